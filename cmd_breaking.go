@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sb/internal/breaking"
+	"sb/internal/diag"
+)
+
+// runBreaking sb breaking --against old.sb [-lang zh|en] new.sb
+// 存在不兼容变更时以非零状态退出 (用于 CI)
+func runBreaking(args []string) error {
+	flags := flag.NewFlagSet("breaking", flag.ExitOnError)
+	against := flags.String("against", "", "已发布的旧版本 .sb 文件")
+	all := flags.Bool("all", false, "同时列出兼容变更")
+	langFlag := flags.String("lang", "zh", "诊断信息语言 (zh|en)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "用法: sb breaking --against old.sb [-all] new.sb")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *against == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("需要 --against 旧版本文件和一个新版本文件")
+	}
+	lang, err := diag.ParseLang(*langFlag)
+	if err != nil {
+		return err
+	}
+
+	from, err := parseSchema(*against, lang)
+	if err != nil {
+		return fmt.Errorf("解析错误:\n%w", err)
+	}
+	to, err := parseSchema(flags.Arg(0), lang)
+	if err != nil {
+		return fmt.Errorf("解析错误:\n%w", err)
+	}
+
+	c := &breaking.Checker{OldFile: *against, NewFile: flags.Arg(0), Lang: lang}
+	changes := c.Check(from, to)
+	count := 0
+	for _, d := range changes {
+		if d.Severity == diag.SeverityError {
+			count++
+		} else if !*all {
+			continue
+		}
+		fmt.Println(d)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("发现 %d 处不兼容变更", count)
+	}
+	return nil
+}
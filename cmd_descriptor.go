@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sb/internal/descriptor"
+	"sb/internal/diag"
+)
+
+// runDescriptor sb descriptor [-o out.json] [-lang zh|en] <file.sb>
+// 导出 JSON 描述符; 未指定 -o 时写到标准输出
+func runDescriptor(args []string) error {
+	flags := flag.NewFlagSet("descriptor", flag.ExitOnError)
+	out := flags.String("o", "", "输出文件 (默认标准输出)")
+	langFlag := flags.String("lang", "zh", "诊断信息语言 (zh|en)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "用法: sb descriptor [-o out.json] <file.sb>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("缺少输入文件")
+	}
+	lang, err := diag.ParseLang(*langFlag)
+	if err != nil {
+		return err
+	}
+
+	schema, err := parseSchema(flags.Arg(0), lang)
+	if err != nil {
+		return fmt.Errorf("解析错误:\n%w", err)
+	}
+	data, err := descriptor.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*out, data, 0644)
+}
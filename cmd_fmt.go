@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"sb/internal/diag"
+	"sb/internal/format"
+)
+
+// runFmt sb fmt [-check] [-lang zh|en] <file.sb>...
+// 默认原地改写文件; -check 只列出未格式化的文件并以非零状态退出 (用于 pre-commit)
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := fs.Bool("check", false, "只检查格式, 不改写文件; 存在未格式化文件时返回非零状态")
+	langFlag := fs.String("lang", "zh", "诊断信息语言 (zh|en)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: sb fmt [-check] <file.sb>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("缺少输入文件")
+	}
+	lang, err := diag.ParseLang(*langFlag)
+	if err != nil {
+		return err
+	}
+
+	var unformatted []string
+	for _, filename := range fs.Args() {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read file: %w", err)
+		}
+		out, err := format.SourceFile(filename, src, lang)
+		if err != nil {
+			return fmt.Errorf("格式化失败:\n%w", err)
+		}
+		if bytes.Equal(src, out) {
+			continue
+		}
+		if *check {
+			unformatted = append(unformatted, filename)
+			fmt.Println(filename)
+			continue
+		}
+		if err := os.WriteFile(filename, out, 0644); err != nil {
+			return err
+		}
+	}
+
+	if len(unformatted) > 0 {
+		return fmt.Errorf("%d 个文件需要格式化 (运行 sb fmt 修复)", len(unformatted))
+	}
+	return nil
+}
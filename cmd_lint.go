@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sb/internal/diag"
+	"sb/internal/lexer"
+	"sb/internal/lint"
+	"sb/internal/parser"
+	"strings"
+)
+
+// runLint sb lint [-config sblint.json] [-disable rule,...] [-lang zh|en] <file.sb>...
+// 存在任何未被抑制的问题时以非零状态退出
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	configPath := flags.String("config", "", "规则配置文件 (默认读取当前目录的 "+lint.DefaultConfigFile+")")
+	disable := flags.String("disable", "", "额外关闭的规则, 逗号分隔")
+	langFlag := flags.String("lang", "zh", "诊断信息语言 (zh|en)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "用法: sb lint [-config file] [-disable rule,...] <file.sb>...")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "规则:", strings.Join(lint.Rules, ", "))
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("缺少输入文件")
+	}
+	lang, err := diag.ParseLang(*langFlag)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadLintConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *disable != "" {
+		cfg.Disable = append(cfg.Disable, strings.Split(*disable, ",")...)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	total := 0
+	for _, filename := range flags.Args() {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read file: %w", err)
+		}
+		p := parser.New(lexer.New(string(src)))
+		p.File = filename
+		p.Lang = lang
+		schema, err := p.ParseSchema()
+		if err != nil {
+			return fmt.Errorf("解析错误:\n%w", err)
+		}
+
+		l := lint.New(cfg)
+		l.File = filename
+		l.Lang = lang
+		for _, d := range l.Lint(schema, src) {
+			fmt.Println(d)
+			total++
+		}
+	}
+
+	if total > 0 {
+		return fmt.Errorf("发现 %d 个问题", total)
+	}
+	return nil
+}
+
+// loadLintConfig 读取指定配置; 未指定时尝试当前目录的默认配置, 不存在则使用默认规则
+func loadLintConfig(path string) (lint.Config, error) {
+	if path != "" {
+		return lint.LoadConfig(path)
+	}
+	cfg, err := lint.LoadConfig(lint.DefaultConfigFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lint.Config{}, nil
+	}
+	return cfg, err
+}
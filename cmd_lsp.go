@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sb/internal/diag"
+	"sb/internal/lsp"
+)
+
+// runLsp sb lsp [-lang zh|en] [-log file]
+// 通过标准输入输出与编辑器通信
+func runLsp(args []string) error {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	langFlag := flags.String("lang", "zh", "诊断信息语言 (zh|en), 可被 initializationOptions.lang 覆盖")
+	logFile := flags.String("log", "", "协议错误日志文件 (标准输出被协议占用)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "用法: sb lsp [-lang zh|en] [-log file]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	lang, err := diag.ParseLang(*langFlag)
+	if err != nil {
+		return err
+	}
+
+	s := lsp.NewServer(os.Stdin, os.Stdout)
+	s.Lang = lang
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		s.Logger = log.New(f, "sb-lsp ", log.LstdFlags)
+	}
+	return s.Run()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sb/internal/generator"
+	"slices"
+)
+
+// runTemplates sb templates [-o dir] [-f] [name...]
+// 列出内置模板, 或导出到目录作为自定义模板 (-templates / sb.yaml templates) 的起点
+func runTemplates(args []string) error {
+	flags := flag.NewFlagSet("templates", flag.ExitOnError)
+	out := flags.String("o", "", "导出目录; 未指定时列出内置模板")
+	force := flags.Bool("f", false, "覆盖导出目录中已存在的文件")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "用法: sb templates [-o dir] [-f] [name...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	names := generator.TemplateNames()
+	if flags.NArg() > 0 {
+		for _, name := range flags.Args() {
+			if !slices.Contains(names, name) {
+				return fmt.Errorf("未知模板 %s", name)
+			}
+		}
+		names = flags.Args()
+	}
+	if *out == "" {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+	for _, name := range names {
+		dst := filepath.Join(*out, name)
+		if !*force {
+			if _, err := os.Stat(dst); err == nil {
+				return fmt.Errorf("%s 已存在, 使用 -f 覆盖", dst)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		data, err := fs.ReadFile(generator.TplFS, path.Join(generator.TemplateDir, name))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("已导出 %d 个模板到 %s\n", len(names), *out)
+	return nil
+}
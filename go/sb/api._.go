@@ -2,58 +2,152 @@ package sb
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
 )
 
+// --- 服务接口 ---
+
+// ApiService api 模块的业务逻辑, 实现后通过 RegisterApi 注册
+//
+// 返回的 error 为 *RpcError 时连同说明与详情返回给客户端, 为 RpcErrCode 时作为响应状态码, 其他非 nil 错误按 500 处理
+type ApiService interface {
+	// GetCount 获取数量
+	GetCount(ctx context.Context, page uint8) (uint8, error)
+	// GetBin 获取bin
+	GetBin(ctx context.Context, page uint8) ([]byte, error)
+}
+
+// UserService user 模块的业务逻辑, 实现后通过 RegisterUser 注册
+//
+// 返回的 error 为 *RpcError 时连同说明与详情返回给客户端, 为 RpcErrCode 时作为响应状态码, 其他非 nil 错误按 500 处理
+type UserService interface {
+	// GetAbc 获取用户的id
+	//
+	// Deprecated: 请使用 user.get_abcd
+	GetAbc(ctx context.Context) (OrderStatus, error)
+	// GetAbcd 获取abcd
+	GetAbcd(ctx context.Context, page uint8, size uint8) (OrderStatus, error)
+	// SetSimInfo 设置sim信息
+	//
+	// 业务错误返回 SimError 的值, 客户端收到状态码 RpcBizErr 及对应的错误码; 其他错误经 InternalError 包装
+	SetSimInfo(ctx context.Context, info *SimInfo) SimErrorFault
+}
+
 // --- API Handlers ---
 
-func UserGetAbcHandler(w http.ResponseWriter, r *http.Request) {
+// UserGetAbcHandler 处理 user.get_abc 请求, 业务逻辑由 impl.GetAbc 实现
+//
+// Deprecated: 请使用 user.get_abcd
+func UserGetAbcHandler(impl UserService, opts *ServerOptions) http.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := opts.begin(w, r, "user.get_abc")
+		defer c.finish()
+		c.deprecated("请使用 user.get_abcd")
 
-	if !parseRequest(w, r) { return }
+		if !c.parse() { return }
 
-	result, status := user_get_abc(r.Context())
-	if !checkStatus(w, status) { return }
-	sendResponse(w, U8(result))
+		req := &UserGetAbcRequest{}
+		result, err := intercept[OrderStatus](r.Context(), opts.Interceptors, &UnaryInfo{Api: "user.get_abc", Request: r}, req, func(ctx context.Context, req any) (any, error) {
+			result, err := impl.GetAbc(ctx)
+			return result, err
+		})
+		if !c.check(declaredError[noBizError](err)) { return }
+		c.send(U8(result))
+	}
 }
-func UserGetAbcdHandler(w http.ResponseWriter, r *http.Request) {
-	var page U8
-	var size U8
 
-	if !parseRequest(w, r, &page, &size) { return }
+// UserGetAbcdHandler 处理 user.get_abcd 请求, 业务逻辑由 impl.GetAbcd 实现
+func UserGetAbcdHandler(impl UserService, opts *ServerOptions) http.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := opts.begin(w, r, "user.get_abcd")
+		defer c.finish()
+		var argPage U8
+		var argSize U8
+
+		if !c.parse(&argPage, &argSize) { return }
 
-	result, status := user_get_abcd(r.Context(), uint8(page), uint8(size))
-	if !checkStatus(w, status) { return }
-	sendResponse(w, U8(result))
+		req := &UserGetAbcdRequest{Page: uint8(argPage), Size: uint8(argSize)}
+		result, err := intercept[OrderStatus](r.Context(), opts.Interceptors, &UnaryInfo{Api: "user.get_abcd", Request: r}, req, func(ctx context.Context, req any) (any, error) {
+			in := req.(*UserGetAbcdRequest)
+			result, err := impl.GetAbcd(ctx, in.Page, in.Size)
+			return result, err
+		})
+		if !c.check(declaredError[noBizError](err)) { return }
+		c.send(U8(result))
+	}
 }
-func UserSetSimInfoHandler(w http.ResponseWriter, r *http.Request) {
-	var info SimInfo
 
-	if !parseRequest(w, r, &info) { return }
+// UserSetSimInfoHandler 处理 user.set_sim_info 请求, 业务逻辑由 impl.SetSimInfo 实现
+func UserSetSimInfoHandler(impl UserService, opts *ServerOptions) http.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := opts.begin(w, r, "user.set_sim_info")
+		defer c.finish()
+		var argInfo SimInfo
+
+		if !c.parse(&argInfo) { return }
 
-	status := user_set_sim_info(r.Context(), &info)
-	if !checkStatus(w, status) { return }
-	w.WriteHeader(http.StatusOK)
+		req := &UserSetSimInfoRequest{Info: &argInfo}
+		_, err := intercept[any](r.Context(), opts.Interceptors, &UnaryInfo{Api: "user.set_sim_info", Request: r}, req, func(ctx context.Context, req any) (any, error) {
+			in := req.(*UserSetSimInfoRequest)
+			return nil, impl.SetSimInfo(ctx, in.Info)
+		})
+		if !c.check(declaredError[SimError](err)) { return }
+		c.ok()
+	}
 }
-func GetCountHandler(w http.ResponseWriter, r *http.Request) {
-	var page U8
 
-	if !parseRequest(w, r, &page) { return }
+// GetCountHandler 处理 get_count 请求, 业务逻辑由 impl.GetCount 实现
+func GetCountHandler(impl ApiService, opts *ServerOptions) http.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := opts.begin(w, r, "get_count")
+		defer c.finish()
+		var argPage U8
+
+		if !c.parse(&argPage) { return }
 
-	result, status := get_count(r.Context(), uint8(page))
-	if !checkStatus(w, status) { return }
-	sendResponse(w, U8(result))
+		req := &GetCountRequest{Page: uint8(argPage)}
+		result, err := intercept[uint8](r.Context(), opts.Interceptors, &UnaryInfo{Api: "get_count", Request: r}, req, func(ctx context.Context, req any) (any, error) {
+			in := req.(*GetCountRequest)
+			result, err := impl.GetCount(ctx, in.Page)
+			return result, err
+		})
+		if !c.check(declaredError[noBizError](err)) { return }
+		c.send(U8(result))
+	}
 }
-func GetBinHandler(w http.ResponseWriter, r *http.Request) {
-	var page U8
 
-	if !parseRequest(w, r, &page) { return }
+// GetBinHandler 处理 get_bin 请求, 业务逻辑由 impl.GetBin 实现
+func GetBinHandler(impl ApiService, opts *ServerOptions) http.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := opts.begin(w, r, "get_bin")
+		defer c.finish()
+		var argPage U8
 
-	result, status := get_bin(r.Context(), uint8(page))
-	if !checkStatus(w, status) { return }
-	sendResponse(w, Bin(result))
-}
+		if !c.parse(&argPage) { return }
 
+		req := &GetBinRequest{Page: uint8(argPage)}
+		result, err := intercept[[]byte](r.Context(), opts.Interceptors, &UnaryInfo{Api: "get_bin", Request: r}, req, func(ctx context.Context, req any) (any, error) {
+			in := req.(*GetBinRequest)
+			result, err := impl.GetBin(ctx, in.Page)
+			return result, err
+		})
+		if !c.check(declaredError[noBizError](err)) { return }
+		c.send(Bin(result))
+	}
+}
 
 // --- 路由注册 ---
 
@@ -66,37 +160,250 @@ func composeMiddleware(mws ...Middleware) func(http.HandlerFunc) http.HandlerFun
 	}
 }
 
+// Router 注册路由的目标, *http.ServeMux 及 chi 等路由器均满足; 处理函数自行校验请求方法 (POST)
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// DefaultMaxBodySize 默认的请求体上限
+const DefaultMaxBodySize = 4 << 20
 
-func RegisterApi(mux *http.ServeMux, mws ...Middleware) {
-	mw := composeMiddleware(mws...)
-	mux.HandleFunc("POST /get_count", mw(GetCountHandler))
-	mux.HandleFunc("POST /get_bin", mw(GetBinHandler))
+// ServerOptions 路由注册选项, nil 或零值字段使用默认行为
+type ServerOptions struct {
+	// Prefix 路径前缀, 如 "/rpc/v2"; 客户端的 BaseURL 需包含相同前缀
+	Prefix string
+	// MaxBodySize 请求体上限 (字节), 超过时响应 413; 0 表示 DefaultMaxBodySize, 负数表示不限制
+	MaxBodySize int64
+	// ErrorEncoder 自定义错误响应, 默认以 e.Status 响应并将错误编码在响应体中 (客户端据此解析 RpcError)
+	ErrorEncoder func(w http.ResponseWriter, r *http.Request, e *RpcError)
+	// OnRequest 请求开始时调用
+	OnRequest func(r *http.Request, api string)
+	// OnResponse 响应完成后调用, status 为响应状态码
+	OnResponse func(r *http.Request, api string, status int, elapsed time.Duration)
+	// OnDeprecatedCall 已弃用 API 被调用时调用, 可用于记录日志或上报指标; reason 为弃用说明
+	OnDeprecatedCall func(r *http.Request, api, reason string)
+	// ErrorReporter 内部错误 (panic 及按 500 处理的错误) 的上报, 默认以 Logger 记录
+	ErrorReporter ErrorReporter
+	// Logger 默认错误上报使用的日志, 默认为 slog.Default()
+	Logger *slog.Logger
+	// Middlewares 依次包裹每个 API 的处理函数
+	Middlewares []Middleware
+	// Interceptors 依次包裹每个 API 的业务逻辑, 收到解码后的参数与返回的结果, 见 UnaryInterceptor
+	Interceptors []UnaryInterceptor
 }
 
-func RegisterUser(mux *http.ServeMux, mws ...Middleware) {
-	mw := composeMiddleware(mws...)
-	mux.HandleFunc("POST /user.get_abc", mw(UserGetAbcHandler))
-	mux.HandleFunc("POST /user.get_abcd", mw(UserGetAbcdHandler))
-	mux.HandleFunc("POST /user.set_sim_info", mw(UserSetSimInfoHandler))
+// withDefaults 填充默认值, 返回新的选项
+func (o *ServerOptions) withDefaults() *ServerOptions {
+	var c ServerOptions
+	if o != nil { c = *o }
+	c.Prefix = strings.TrimSuffix(c.Prefix, "/")
+	if c.MaxBodySize == 0 { c.MaxBodySize = DefaultMaxBodySize }
+	if c.Logger == nil { c.Logger = slog.Default() }
+	return &c
 }
 
+func (o *ServerOptions) handle(mux Router, api string, h http.HandlerFunc) {
+	mux.Handle(o.Prefix+"/"+api, composeMiddleware(o.Middlewares...)(h))
+}
+
+// Services 实现全部模块服务接口的类型, 用于 RegisterAll
+type Services interface {
+	ApiService
+	UserService
+}
+
+// RegisterAll 注册全部模块的路由, 请求交由 impl 处理; opts 可为 nil
+func RegisterAll(mux Router, impl Services, opts *ServerOptions) {
+	RegisterApi(mux, impl, opts)
+	RegisterUser(mux, impl, opts)
+}
+
+// RegisterApi 注册 api 模块的路由, 请求交由 impl 处理; opts 可为 nil
+func RegisterApi(mux Router, impl ApiService, opts *ServerOptions) {
+	opts = opts.withDefaults()
+	opts.handle(mux, "get_count", GetCountHandler(impl, opts))
+	opts.handle(mux, "get_bin", GetBinHandler(impl, opts))
+}
+
+// RegisterUser 注册 user 模块的路由, 请求交由 impl 处理; opts 可为 nil
+func RegisterUser(mux Router, impl UserService, opts *ServerOptions) {
+	opts = opts.withDefaults()
+	opts.handle(mux, "user.get_abc", UserGetAbcHandler(impl, opts))
+	opts.handle(mux, "user.get_abcd", UserGetAbcdHandler(impl, opts))
+	opts.handle(mux, "user.set_sim_info", UserSetSimInfoHandler(impl, opts))
+}
+
+// --- 进程内调用 ---
+
+// LocalTransport 客户端的进程内 Transport: 不经过网络, 直接调用注册的处理函数,
+// 请求同样经过中间件, 拦截器与错误编码。可用于不启动服务端的单元测试, 或单体部署中的模块间调用:
+//
+//	c := NewClient("")
+//	c.Transport = NewLocalTransport(impl, nil)
+type LocalTransport struct {
+	// Header 附加到每个请求的请求头, 如 Authorization
+	Header   http.Header
+	prefix   string
+	handlers map[string]http.Handler
+}
+
+// NewLocalTransport 注册全部模块的路由, 请求交由 impl 处理; opts 同 RegisterAll, 可为 nil
+func NewLocalTransport(impl Services, opts *ServerOptions) *LocalTransport {
+	t := &LocalTransport{Header: make(http.Header), prefix: opts.withDefaults().Prefix, handlers: make(map[string]http.Handler)}
+	RegisterAll(t, impl, opts)
+	return t
+}
+
+// Handle 实现 Router, 记录路由对应的处理函数
+func (t *LocalTransport) Handle(pattern string, handler http.Handler) { t.handlers[pattern] = handler }
+
+// Call 实现 Transport
+func (t *LocalTransport) Call(ctx context.Context, api string, req []byte) ([]byte, error) {
+	path := t.prefix + "/" + api
+	h, ok := t.handlers[path]
+	if !ok { return nil, NewRpcError(RpcNotExist, 0, "API 不存在: "+api) }
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(req))
+	if err != nil { return nil, rpcError(RpcReqErr, err) }
+	for k, vs := range t.Header {
+		for _, v := range vs { r.Header.Add(k, v) }
+	}
+	w := &localResponse{header: make(http.Header), status: http.StatusOK}
+	h.ServeHTTP(w, r)
+	if w.status != http.StatusOK { return nil, decodeRpcError(RpcErrCode(w.status), w.body.Bytes()) }
+	return w.body.Bytes(), nil
+}
+
+// localResponse 进程内调用的响应
+type localResponse struct {
+	header http.Header
+	status int
+	wrote  bool
+	body   bytes.Buffer
+}
+
+func (w *localResponse) Header() http.Header { return w.header }
+
+func (w *localResponse) WriteHeader(status int) {
+	if !w.wrote { w.status, w.wrote = status, true }
+}
+
+func (w *localResponse) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.body.Write(b)
+}
+
+// --- 错误上报 ---
+
+// ErrorReport 服务端内部错误: 业务逻辑 panic, 或返回了按 500 处理的错误
+type ErrorReport struct {
+	Api   string // API 名称, 如 user.set_sim_info
+	Err   error  // 返回的错误; panic 时为包装了 panic 值的错误
+	Stack []byte // panic 时的调用栈, 否则为 nil
+}
+
+// ErrorReporter 接收服务端内部错误, 通过 ServerOptions 指定
+type ErrorReporter func(r *http.Request, report ErrorReport)
 
 // --- 内部辅助函数 ---
 
-func checkStatus(w http.ResponseWriter, status RpcErrCode) bool {
-	if status == RpcOk { return true }
-	w.WriteHeader(int(status)); return false
+// rpcCall 单次请求的处理上下文
+type rpcCall struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	api    string
+	opts   *ServerOptions
+	start  time.Time
+	status int
+}
+
+func (o *ServerOptions) begin(w http.ResponseWriter, r *http.Request, api string) *rpcCall {
+	if o.OnRequest != nil { o.OnRequest(r, api) }
+	return &rpcCall{w: w, r: r, api: api, opts: o, start: time.Now()}
+}
+
+// finish 捕获业务逻辑的 panic, 上报后以 500 及标准错误响应体响应; 最后调用 OnResponse
+func (c *rpcCall) finish() {
+	if v := recover(); v != nil {
+		if v == http.ErrAbortHandler { panic(v) }
+		err, ok := v.(error)
+		if ok { err = fmt.Errorf("panic: %w", err) } else { err = fmt.Errorf("panic: %v", v) }
+		c.reportError(err, debug.Stack())
+		c.fail(NewRpcError(RpcRespErr, 0, "服务内部错误"))
+	}
+	if c.opts.OnResponse != nil { c.opts.OnResponse(c.r, c.api, c.status, time.Since(c.start)) }
 }
 
-func parseRequest(w http.ResponseWriter, r *http.Request, args ...Deserializable) bool {
+// deprecated 通知已弃用 API 被调用
+func (c *rpcCall) deprecated(reason string) {
+	if c.opts.OnDeprecatedCall != nil { c.opts.OnDeprecatedCall(c.r, c.api, reason) }
+}
+
+func (c *rpcCall) reportError(err error, stack []byte) {
+	if c.opts.ErrorReporter != nil {
+		c.opts.ErrorReporter(c.r, ErrorReport{Api: c.api, Err: err, Stack: stack})
+		return
+	}
+	args := []any{"api", c.api, "err", err}
+	if stack != nil { args = append(args, "stack", string(stack)) }
+	c.opts.Logger.ErrorContext(c.r.Context(), "rpc 请求处理失败", args...)
+}
+
+// noBizError 未声明错误枚举的 API, 不能返回任何业务错误
+type noBizError struct{}
+
+// declaredError 业务错误须为 API 声明的错误枚举 E; 其他错误枚举的值 (如经拦截器返回) 按内部错误处理,
+// 避免客户端按 E 解读错误码
+func declaredError[E any](err error) error {
+	var biz interface{ rpcError() *RpcError }
+	if !errors.As(err, &biz) { return err }
+	if _, ok := biz.(E); ok { return err }
+	return fmt.Errorf("未声明的业务错误 %T: %v", biz, err)
+}
+
+func (c *rpcCall) check(err error) bool {
+	if err == nil { return true }
+	e := AsRpcError(err)
+	if e.Status == RpcRespErr { c.reportError(err, nil) }
+	c.fail(e); return false
+}
+
+// fail 以错误的状态码响应, 响应体为编码后的错误
+func (c *rpcCall) fail(e *RpcError) {
+	c.status = int(e.Status)
+	if c.opts.ErrorEncoder != nil { c.opts.ErrorEncoder(c.w, c.r, e); return }
+	body, err := e.encode()
+	if err != nil { c.status = http.StatusInternalServerError; c.w.WriteHeader(c.status); return }
+	c.w.WriteHeader(c.status)
+	c.w.Write(body)
+}
+
+// parse 校验请求方法并解码参数
+func (c *rpcCall) parse(args ...Deserializable) bool {
+	if c.r.Method != http.MethodPost {
+		c.w.Header().Set("Allow", http.MethodPost)
+		c.fail(NewRpcError(RpcErrCode(http.StatusMethodNotAllowed), 0, "只支持 POST 请求")); return false
+	}
 	if len(args) == 0 { return true }
-	body, err := io.ReadAll(r.Body); if err != nil { w.WriteHeader(http.StatusBadRequest); return false }
-	if err := GetAll(bytes.NewBuffer(body), args...); err != nil { w.WriteHeader(http.StatusBadRequest); return false }
+	body := c.r.Body
+	if c.opts.MaxBodySize > 0 { body = http.MaxBytesReader(c.w, body, c.opts.MaxBodySize) }
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) { c.fail(NewRpcError(RpcTooLarge, 0, "请求体过大")); return false }
+		c.fail(NewRpcError(RpcReqErr, 0, "读取请求失败")); return false
+	}
+	if err := GetAll(bytes.NewBuffer(data), args...); err != nil { c.fail(NewRpcError(RpcReqErr, 0, "请求参数无法解析")); return false }
 	return true
 }
 
-func sendResponse(w http.ResponseWriter, result Serializable) {
+func (c *rpcCall) send(result Serializable) {
 	var buf bytes.Buffer
-	if err := SetAll(&buf, result); err != nil { w.WriteHeader(http.StatusInternalServerError); return }
-	w.Write(buf.Bytes())
+	if err := SetAll(&buf, result); err != nil { c.reportError(err, nil); c.fail(NewRpcError(RpcRespErr, 0, "响应序列化失败")); return }
+	c.status = http.StatusOK
+	c.w.Write(buf.Bytes())
+}
+
+func (c *rpcCall) ok() {
+	c.status = http.StatusOK
+	c.w.WriteHeader(c.status)
 }
@@ -1,9 +0,0 @@
-package sb
-
-import (
-	"context"
-)
-
-func get_bin(ctx context.Context, page uint8) (result []byte, errCode RpcErrCode) {
-	return nil, RpcRespErr
-}
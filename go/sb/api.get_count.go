@@ -1,10 +0,0 @@
-package sb
-
-import (
-	"context"
-)
-
-func get_count(ctx context.Context, page uint8) (result uint8, errCode RpcErrCode) {
-	recover()
-	panic("aaa")
-}
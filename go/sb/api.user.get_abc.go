@@ -1,9 +0,0 @@
-package sb
-
-import (
-	"context"
-)
-
-func user_get_abc(ctx context.Context) (result OrderStatus, errCode RpcErrCode) {
-	return 0, RpcRespErr
-}
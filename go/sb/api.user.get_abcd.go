@@ -1,9 +0,0 @@
-package sb
-
-import (
-	"context"
-)
-
-func user_get_abcd(ctx context.Context, page uint8, size uint8) (result OrderStatus, errCode RpcErrCode) {
-	return 0, RpcRespErr
-}
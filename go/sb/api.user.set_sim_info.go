@@ -1,9 +0,0 @@
-package sb
-
-import (
-	"context"
-)
-
-func user_set_sim_info(ctx context.Context, info *SimInfo) (errCode RpcErrCode) {
-	return RpcRespErr
-}
@@ -125,6 +125,50 @@ func (v *SimPickPhoneList) Get(buf *bytes.Buffer) error {
 }
 func (v SimPickPhoneList) Eq(other SimPickPhoneList) bool { return slices.Equal(v, other) }
 
+// SimError sim信息错误
+type SimError uint8
+
+const (
+	SimErrorNotFound SimError = 1 // 卡不存在
+	SimErrorExpired SimError = 2 // 已过期
+	SimErrorLocked SimError = 3 // 已锁定
+)
+
+type SimErrorList []SimError
+func (v SimErrorList) Set(buf *bytes.Buffer) error { return SetU8List(buf, *(*[]uint8)(unsafe.Pointer(&v))) }
+func (v *SimErrorList) Get(buf *bytes.Buffer) error {
+	val, err := GetU8List(buf)
+	if err == nil { *v = *(*SimErrorList)(unsafe.Pointer(&val)) }
+	return err
+}
+func (v SimErrorList) Eq(other SimErrorList) bool { return slices.Equal(v, other) }
+
+// Error 使 SimError 可作为 API 的业务错误返回
+func (v SimError) Error() string {
+	switch v {
+	case SimErrorNotFound: return "卡不存在"
+	case SimErrorExpired: return "已过期"
+	case SimErrorLocked: return "已锁定"
+	}
+	return unknownVariant("SimError", uint8(v))
+}
+
+// rpcError 业务错误以状态码 RpcBizErr 返回, 错误码为枚举值
+func (v SimError) rpcError() *RpcError {
+	return &RpcError{Status: RpcBizErr, Code: int32(v), Message: v.Error()}
+}
+
+// SimErrorFault 声明 ! SimError 的 API 在服务接口中返回的错误: SimError 的值 (业务错误), *RpcError 或 RpcErrCode,
+// 其他错误经 InternalError 包装; 其他错误枚举的值无法返回, 由编译器检查
+type SimErrorFault interface {
+	error
+	simErrorFault()
+}
+
+func (SimError) simErrorFault() {}
+func (*RpcError) simErrorFault() {}
+func (RpcErrCode) simErrorFault() {}
+
 // SimOperator 运营商
 type SimOperator uint8
 
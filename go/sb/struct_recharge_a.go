@@ -9,10 +9,7 @@ import (
 )
 
 type RechargeA struct {
-	Id uint32 `bson:"_id" json:"_id"` // abcd
-	Type []OrderStatus `bson:"type" json:"type"` 
-	Phone []string `bson:"phone" json:"phone"` 
-	Si *SimInfo `bson:"si" json:"si"` 
+	Recharge `bson:",inline"`
 	Aid uint32 `bson:"aid" json:"aid"` 
 }
 
@@ -24,21 +21,21 @@ func (s *RechargeA) Get(buf *bytes.Buffer) error {
 	if GetBit(bits, uint8(0)) {
 		val, err := GetU32(buf)
 		if err != nil { return fmt.Errorf("GetRechargeA Id: %w", err) }
-		s.Id = val
+		s.Recharge.Id = val
 	}
 	if GetBit(bits, uint8(1)) {
 		val, err := GetU8List(buf)
 		if err != nil { return fmt.Errorf("GetRechargeA Type: %w", err) }
-		s.Type = *(*[]OrderStatus)(unsafe.Pointer(&val))
+		s.Recharge.Type = *(*[]OrderStatus)(unsafe.Pointer(&val))
 	}
 	if GetBit(bits, uint8(2)) {
 		val, err := GetTextList(buf)
 		if err != nil { return fmt.Errorf("GetRechargeA Phone: %w", err) }
-		s.Phone = val
+		s.Recharge.Phone = val
 	}
 	if GetBit(bits, uint8(3)) {
-		if s.Si == nil { s.Si = new(SimInfo) }
-		if err := s.Si.Get(buf); err != nil { return fmt.Errorf("GetRechargeA Si: %w", err) }
+		if s.Recharge.Si == nil { s.Recharge.Si = new(SimInfo) }
+		if err := s.Recharge.Si.Get(buf); err != nil { return fmt.Errorf("GetRechargeA Si: %w", err) }
 	}
 	if GetBit(bits, uint8(4)) {
 		val, err := GetU32(buf)
@@ -52,20 +49,20 @@ func (s *RechargeA) Set(buf *bytes.Buffer) error {
 	if s == nil { return nil }
 	bits := make([]byte, uint8(math.Ceil(float64(5)/8.0)))
 	body := bytes.NewBuffer(nil)
-	if s.Id != 0 {
-		if err := SetU32(body, s.Id); err != nil { return fmt.Errorf("SetRechargeA Id: %w", err) }
+	if s.Recharge.Id != 0 {
+		if err := SetU32(body, s.Recharge.Id); err != nil { return fmt.Errorf("SetRechargeA Id: %w", err) }
 		SetBit(bits, uint8(0), true)
 	}
-	if len(s.Type) > 0 {
-		if err := SetU8List(body, *(*[]uint8)(unsafe.Pointer(&s.Type))); err != nil { return fmt.Errorf("SetRechargeA Type: %w", err) }
+	if len(s.Recharge.Type) > 0 {
+		if err := SetU8List(body, *(*[]uint8)(unsafe.Pointer(&s.Recharge.Type))); err != nil { return fmt.Errorf("SetRechargeA Type: %w", err) }
 		SetBit(bits, uint8(1), true)
 	}
-	if len(s.Phone) > 0 {
-		if err := SetTextList(body, s.Phone); err != nil { return fmt.Errorf("SetRechargeA Phone: %w", err) }
+	if len(s.Recharge.Phone) > 0 {
+		if err := SetTextList(body, s.Recharge.Phone); err != nil { return fmt.Errorf("SetRechargeA Phone: %w", err) }
 		SetBit(bits, uint8(2), true)
 	}
-	if s.Si != nil {
-		if err := s.Si.Set(body); err != nil { return fmt.Errorf("SetRechargeA Si: %w", err) }
+	if s.Recharge.Si != nil {
+		if err := s.Recharge.Si.Set(body); err != nil { return fmt.Errorf("SetRechargeA Si: %w", err) }
 		SetBit(bits, uint8(3), true)
 	}
 	if s.Aid != 0 {
@@ -80,10 +77,10 @@ func (s *RechargeA) Set(buf *bytes.Buffer) error {
 func (s *RechargeA) Eq(other *RechargeA) bool {
 	if s == other { return true }
 	if s == nil || other == nil { return false }
-	if !EqU32(s.Id, other.Id) { return false }
-	if !slices.Equal(s.Type, other.Type) { return false }
-	if !EqTextList(s.Phone, other.Phone) { return false }
-	if !s.Si.Eq(other.Si) { return false }
+	if !EqU32(s.Recharge.Id, other.Recharge.Id) { return false }
+	if !slices.Equal(s.Recharge.Type, other.Recharge.Type) { return false }
+	if !EqTextList(s.Recharge.Phone, other.Recharge.Phone) { return false }
+	if !s.Recharge.Si.Eq(other.Recharge.Si) { return false }
 	if !EqU32(s.Aid, other.Aid) { return false }
 	return true
 }
@@ -1,5 +1,11 @@
 package ast
 
+// Pos 源码位置 (行列均从 1 开始, 零值表示未知)
+type Pos struct {
+	Line int
+	Col  int
+}
+
 // TypeKind 类型分类: 基础类型, 结构体, 枚举
 type TypeKind int
 
@@ -15,28 +21,65 @@ type Type struct {
 	Name   string
 	Kind   TypeKind
 	IsList bool // 是否为数组/切片 ([T])
+	Pos    Pos  // 类型名所在位置
+}
+
+// IsBaseType 是否为内置基础类型
+func IsBaseType(name string) bool {
+	switch name {
+	case "i8", "u8", "i16", "u16", "i32", "u32", "i64", "u64",
+		"f32", "f64", "bool", "text", "bin":
+		return true
+	}
+	return false
+}
+
+// Deprecation 弃用标记 (来自 @deprecated 注解)
+type Deprecation struct {
+	Reason string // 弃用原因或替代方案, 可为空
 }
 
 // StructField 结构体字段定义
 type StructField struct {
-	Name string
-	Type Type
-	Tag  string // Go struct tag (如 `json:"id"`)
-	Note string // 字段注释
+	Name       string
+	Type       Type
+	Tag        string       // Go struct tag (如 `json:"id"`)
+	Note       string       // 字段注释
+	Deprecated *Deprecation // 非 nil 表示字段已弃用
+	Embedded   string       // 经嵌入展开而来时为直接嵌入的结构体名, 自身声明的字段为空
+	Pos        Pos
 }
 
+// MaxStructFields 结构体字段数上限 (编码时位图索引为 u8)
+const MaxStructFields = 255
+
 // Struct 结构体定义
 type Struct struct {
 	Name   string
-	Fields []StructField
+	Fields []StructField // 已展开嵌入结构体的完整字段列表
+	Embeds []Type        // 直接嵌入的结构体 (按声明顺序, 保留引用位置)
 	Note   string
+	Pos    Pos
+}
+
+// OwnFields 结构体自身声明的字段 (排除嵌入展开而来的字段)
+func (s Struct) OwnFields() []StructField {
+	var own []StructField
+	for _, f := range s.Fields {
+		if f.Embedded == "" {
+			own = append(own, f)
+		}
+	}
+	return own
 }
 
 // EnumChild 枚举成员定义
 type EnumChild struct {
-	ID   uint8  // 枚举数值 (0-255)
-	Name string
-	Note string
+	ID         uint8 // 枚举数值 (0-255)
+	Name       string
+	Note       string
+	Deprecated *Deprecation // 非 nil 表示成员已弃用
+	Pos        Pos
 }
 
 // Enum 枚举定义 (支持 u8 范围内的数值映射)
@@ -44,26 +87,33 @@ type Enum struct {
 	Name     string
 	Children []EnumChild
 	Note     string
+	Pos      Pos
 }
 
 // ApiArg API 参数定义
 type ApiArg struct {
 	Name string
 	Type Type
+	Pos  Pos
 }
 
 // Api 远程调用接口定义
 type Api struct {
-	Name   string
-	Args   []ApiArg
-	Result Type // 返回类型 (nil 表示 void/无返回值)
-	Note   string
+	Name       string
+	Args       []ApiArg
+	Result     Type  // 返回类型 (nil 表示 void/无返回值)
+	Error      *Type // 业务错误枚举 (=> R ! E), nil 表示未声明
+	Note       string
+	Deprecated *Deprecation // 非 nil 表示接口已弃用
+	Idempotent bool         // @idempotent: 重复调用没有副作用, 客户端可自动重试
+	Pos        Pos
 }
 
 // Schema 完整的协议描述文件 (AST 根节点)
 type Schema struct {
-	Structs []Struct
-	Enums   []Enum
-	Apis    []Api
-	Note    string
+	Structs   []Struct
+	Enums     []Enum
+	Apis      []Api
+	Note      string
+	GoPackage string // 文件指令 sb:go-package 指定的 Go 导入路径, 可带 ";包名" 后缀
 }
@@ -0,0 +1,344 @@
+// Package breaking 比较两个版本的 Schema, 找出破坏线上编码兼容性的变更
+//
+// 编码规则决定了兼容性:
+//   - 结构体按字段声明顺序 (含嵌入展开) 编码, 位图索引即字段位置, 位图长度为 ceil(字段数/8) 字节
+//   - 嵌套的结构体与列表元素没有长度前缀, 解码器无法跳过未知字段
+//   - 枚举按 u8 数值编码; API 参数按顺序依次编码
+//
+// 因此删除, 移动字段或修改类型都是不兼容变更; 重命名只影响生成代码, 编码不变。
+package breaking
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+)
+
+// Checker 比较旧版本 (已发布) 与新版本 Schema
+type Checker struct {
+	OldFile string    // 旧版本文件名, 用于报告被删除的定义
+	NewFile string    // 新版本文件名
+	Lang    diag.Lang // 诊断信息语言
+
+	diags diag.List
+}
+
+// Check 返回全部变更, 按文件与位置排序; 不兼容变更为 error 级别, 兼容变更为 info 级别
+func (c *Checker) Check(from, to *ast.Schema) diag.List {
+	c.diags = nil
+	c.checkTypeKinds(from, to)
+	c.checkStructs(from, to)
+	c.checkEnums(from, to)
+	c.checkApis(from, to)
+
+	slices.SortStableFunc(c.diags, func(a, b diag.Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Col, b.Col),
+		)
+	})
+	return c.diags
+}
+
+func (c *Checker) breaking(file string, pos ast.Pos, code string, args ...any) {
+	c.add(file, pos, diag.SeverityError, code, args...)
+}
+
+func (c *Checker) compatible(file string, pos ast.Pos, code string, args ...any) {
+	c.add(file, pos, diag.SeverityInfo, code, args...)
+}
+
+func (c *Checker) add(file string, pos ast.Pos, sev diag.Severity, code string, args ...any) {
+	c.diags = append(c.diags, diag.Diagnostic{
+		File:     file,
+		Line:     pos.Line,
+		Col:      pos.Col,
+		Severity: sev,
+		Code:     code,
+		Message:  messages[code].Format(c.Lang, args...),
+	})
+}
+
+// typeString 类型的源码写法, 如 u8, [User]
+func typeString(t ast.Type) string {
+	if t.IsList {
+		return "[" + t.Name + "]"
+	}
+	return t.Name
+}
+
+func sameType(a, b ast.Type) bool {
+	return a.Name == b.Name && a.IsList == b.IsList
+}
+
+func bitmaskBytes(fields int) int {
+	return (fields + 7) / 8
+}
+
+// --- 类型分类 ---
+
+// checkTypeKinds 同名定义在结构体与枚举之间互换
+func (c *Checker) checkTypeKinds(from, to *ast.Schema) {
+	for _, s := range from.Structs {
+		if e, ok := findEnum(to, s.Name); ok {
+			c.breaking(c.NewFile, e.Pos, CodeTypeKindChanged, s.Name, nounStruct.Format(c.Lang), nounEnum.Format(c.Lang))
+		}
+	}
+	for _, e := range from.Enums {
+		if s, ok := findStruct(to, e.Name); ok {
+			c.breaking(c.NewFile, s.Pos, CodeTypeKindChanged, e.Name, nounEnum.Format(c.Lang), nounStruct.Format(c.Lang))
+		}
+	}
+}
+
+func findStruct(s *ast.Schema, name string) (ast.Struct, bool) {
+	i := slices.IndexFunc(s.Structs, func(st ast.Struct) bool { return st.Name == name })
+	if i < 0 {
+		return ast.Struct{}, false
+	}
+	return s.Structs[i], true
+}
+
+func findEnum(s *ast.Schema, name string) (ast.Enum, bool) {
+	i := slices.IndexFunc(s.Enums, func(e ast.Enum) bool { return e.Name == name })
+	if i < 0 {
+		return ast.Enum{}, false
+	}
+	return s.Enums[i], true
+}
+
+func findApi(s *ast.Schema, name string) (ast.Api, bool) {
+	i := slices.IndexFunc(s.Apis, func(a ast.Api) bool { return a.Name == name })
+	if i < 0 {
+		return ast.Api{}, false
+	}
+	return s.Apis[i], true
+}
+
+// --- 结构体 ---
+
+func (c *Checker) checkStructs(from, to *ast.Schema) {
+	tailOnly := tailOnlyStructs(to)
+	for _, prev := range from.Structs {
+		cur, ok := findStruct(to, prev.Name)
+		if !ok {
+			if _, isEnum := findEnum(to, prev.Name); !isEnum {
+				c.breaking(c.OldFile, prev.Pos, CodeStructRemoved, prev.Name)
+			}
+			continue
+		}
+		c.checkFields(prev, cur, tailOnly[cur.Name])
+	}
+	for _, cur := range to.Structs {
+		_, wasStruct := findStruct(from, cur.Name)
+		_, wasEnum := findEnum(from, cur.Name)
+		if !wasStruct && !wasEnum {
+			c.compatible(c.NewFile, cur.Pos, CodeStructAdded, cur.Name)
+		}
+	}
+}
+
+// checkFields 按位置比较展开后的字段列表
+func (c *Checker) checkFields(prev, cur ast.Struct, tailOnly bool) {
+	indexOf := func(fields []ast.StructField, name string) int {
+		return slices.IndexFunc(fields, func(f ast.StructField) bool { return f.Name == name })
+	}
+	renamed := make(map[int]bool) // 新结构体中被识别为重命名的位置
+
+	for i, of := range prev.Fields {
+		j := indexOf(cur.Fields, of.Name)
+		switch {
+		case j < 0:
+			// 同一位置类型相同且新名称在旧版本中不存在, 视为重命名
+			if i < len(cur.Fields) && sameType(of.Type, cur.Fields[i].Type) && indexOf(prev.Fields, cur.Fields[i].Name) < 0 {
+				renamed[i] = true
+				c.compatible(c.NewFile, cur.Fields[i].Pos, CodeFieldRenamed, prev.Name, of.Name, cur.Fields[i].Name)
+				continue
+			}
+			c.breaking(c.OldFile, of.Pos, CodeFieldRemoved, prev.Name, of.Name, i)
+		case j != i:
+			c.breaking(c.NewFile, cur.Fields[j].Pos, CodeFieldMoved, prev.Name, of.Name, i, j)
+		case !sameType(of.Type, cur.Fields[j].Type):
+			c.breaking(c.NewFile, cur.Fields[j].Type.Pos, CodeFieldTypeChanged, prev.Name, of.Name, typeString(of.Type), typeString(cur.Fields[j].Type))
+		}
+	}
+
+	// 只有追加在末尾的新字段需要单独报告; 插入在中间的字段已导致原字段错位
+	for j := len(prev.Fields); j < len(cur.Fields); j++ {
+		nf := cur.Fields[j]
+		if indexOf(prev.Fields, nf.Name) >= 0 || renamed[j] {
+			continue
+		}
+		if tailOnly {
+			c.compatible(c.NewFile, nf.Pos, CodeFieldAdded, cur.Name, nf.Name, noteTailOnly.Format(c.Lang))
+		} else {
+			c.breaking(c.NewFile, nf.Pos, CodeFieldAdded, cur.Name, nf.Name, noteNested.Format(c.Lang))
+		}
+	}
+
+	if ob, nb := bitmaskBytes(len(prev.Fields)), bitmaskBytes(len(cur.Fields)); ob != nb {
+		c.breaking(c.NewFile, cur.Pos, CodeBitmaskResized, cur.Name, len(prev.Fields), len(cur.Fields), ob, nb)
+	}
+}
+
+// tailOnlyStructs 只出现在编码末尾的结构体: 仅作为非列表的 API 返回值或最后一个参数
+// 这类结构体后面没有其他数据, 旧版本解码器遗留的未知字段数据不会影响后续解码
+func tailOnlyStructs(s *ast.Schema) map[string]bool {
+	result := make(map[string]bool)
+	nested := make(map[string]bool)
+	mark := func(t ast.Type, tail bool) {
+		if t.Kind != ast.KindStruct {
+			return
+		}
+		if tail && !t.IsList {
+			result[t.Name] = true
+		} else {
+			nested[t.Name] = true
+		}
+	}
+	for _, st := range s.Structs {
+		for _, f := range st.Fields {
+			mark(f.Type, false)
+		}
+	}
+	for _, api := range s.Apis {
+		for i, arg := range api.Args {
+			mark(arg.Type, i == len(api.Args)-1)
+		}
+		mark(api.Result, true)
+	}
+	for name := range nested {
+		delete(result, name)
+	}
+	return result
+}
+
+// --- 枚举 ---
+
+func (c *Checker) checkEnums(from, to *ast.Schema) {
+	for _, oe := range from.Enums {
+		ne, ok := findEnum(to, oe.Name)
+		if !ok {
+			if _, isStruct := findStruct(to, oe.Name); !isStruct {
+				c.breaking(c.OldFile, oe.Pos, CodeEnumRemoved, oe.Name)
+			}
+			continue
+		}
+		c.checkVariants(oe, ne)
+	}
+	for _, ne := range to.Enums {
+		_, wasEnum := findEnum(from, ne.Name)
+		_, wasStruct := findStruct(from, ne.Name)
+		if !wasEnum && !wasStruct {
+			c.compatible(c.NewFile, ne.Pos, CodeEnumAdded, ne.Name)
+		}
+	}
+}
+
+// checkVariants 枚举按数值编码: 数值变化或删除成员不兼容, 同值改名兼容
+func (c *Checker) checkVariants(oe, ne ast.Enum) {
+	byName := func(children []ast.EnumChild, name string) (ast.EnumChild, bool) {
+		i := slices.IndexFunc(children, func(ch ast.EnumChild) bool { return ch.Name == name })
+		if i < 0 {
+			return ast.EnumChild{}, false
+		}
+		return children[i], true
+	}
+	byID := func(children []ast.EnumChild, id uint8) (ast.EnumChild, bool) {
+		i := slices.IndexFunc(children, func(ch ast.EnumChild) bool { return ch.ID == id })
+		if i < 0 {
+			return ast.EnumChild{}, false
+		}
+		return children[i], true
+	}
+
+	for _, ov := range oe.Children {
+		if nv, ok := byName(ne.Children, ov.Name); ok {
+			if nv.ID != ov.ID {
+				c.breaking(c.NewFile, nv.Pos, CodeVariantValue, oe.Name, ov.Name, ov.ID, nv.ID)
+			}
+			continue
+		}
+		if nv, ok := byID(ne.Children, ov.ID); ok {
+			if _, existed := byName(oe.Children, nv.Name); !existed {
+				c.compatible(c.NewFile, nv.Pos, CodeVariantRenamed, oe.Name, ov.Name, nv.Name, ov.ID)
+				continue
+			}
+		}
+		c.breaking(c.OldFile, ov.Pos, CodeVariantRemoved, oe.Name, ov.Name, ov.ID)
+	}
+	for _, nv := range ne.Children {
+		if _, ok := byName(oe.Children, nv.Name); ok {
+			continue
+		}
+		if _, ok := byID(oe.Children, nv.ID); ok {
+			continue // 已作为重命名或数值冲突报告
+		}
+		c.compatible(c.NewFile, nv.Pos, CodeVariantAdded, ne.Name, nv.Name, nv.ID)
+	}
+}
+
+// --- API ---
+
+func (c *Checker) checkApis(from, to *ast.Schema) {
+	for _, oa := range from.Apis {
+		na, ok := findApi(to, oa.Name)
+		if !ok {
+			c.breaking(c.OldFile, oa.Pos, CodeApiRemoved, oa.Name)
+			continue
+		}
+		c.checkApi(oa, na)
+	}
+	for _, na := range to.Apis {
+		if _, ok := findApi(from, na.Name); !ok {
+			c.compatible(c.NewFile, na.Pos, CodeApiAdded, na.Name)
+		}
+	}
+}
+
+// checkApi 参数按顺序编码, 参数名不参与编码
+func (c *Checker) checkApi(oa, na ast.Api) {
+	argsEqual := slices.EqualFunc(oa.Args, na.Args, func(a, b ast.ApiArg) bool {
+		return sameType(a.Type, b.Type)
+	})
+	if !argsEqual {
+		c.breaking(c.NewFile, na.Pos, CodeApiArgsChanged, na.Name, argList(oa.Args), argList(na.Args))
+	} else {
+		for i, arg := range na.Args {
+			if arg.Name != oa.Args[i].Name {
+				c.compatible(c.NewFile, arg.Pos, CodeApiArgRenamed, na.Name, oa.Args[i].Name, arg.Name)
+			}
+		}
+	}
+	if !sameType(oa.Result, na.Result) {
+		c.breaking(c.NewFile, na.Result.Pos, CodeApiResultChanged, na.Name, typeString(oa.Result), typeString(na.Result))
+	}
+	// 业务错误以枚举值编码; 新增或删除错误类型不影响旧版本解码
+	switch {
+	case oa.Error == nil && na.Error != nil:
+		c.compatible(c.NewFile, na.Error.Pos, CodeApiErrorAdded, na.Name, typeString(*na.Error))
+	case oa.Error != nil && na.Error == nil:
+		c.compatible(c.NewFile, na.Pos, CodeApiErrorRemoved, na.Name, typeString(*oa.Error))
+	case oa.Error != nil && !sameType(*oa.Error, *na.Error):
+		c.breaking(c.NewFile, na.Error.Pos, CodeApiErrorChanged, na.Name, typeString(*oa.Error), typeString(*na.Error))
+	}
+	// 幂等性不影响编码, 但决定客户端是否自动重试
+	switch {
+	case oa.Idempotent && !na.Idempotent:
+		c.breaking(c.NewFile, na.Pos, CodeApiNotIdempotent, na.Name)
+	case !oa.Idempotent && na.Idempotent:
+		c.compatible(c.NewFile, na.Pos, CodeApiIdempotent, na.Name)
+	}
+}
+
+func argList(args []ast.ApiArg) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = a.Name + " " + typeString(a.Type)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,166 @@
+package breaking
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/lexer"
+	"sb/internal/parser"
+)
+
+func mustParse(t *testing.T, src string) *ast.Schema {
+	t.Helper()
+	schema, err := parser.New(lexer.New(src)).ParseSchema()
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	return schema
+}
+
+// summary 变更列表简化为 "级别 代码"
+func summary(diags diag.List) []string {
+	var out []string
+	for _, d := range diags {
+		out = append(out, fmt.Sprintf("%s %s", d.Severity, d.Code))
+	}
+	return out
+}
+
+// TestChecker 典型变更的兼容性分类
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{
+			name: "Unchanged",
+			old:  "User { id u32\n name text }\nuser.get(id u32) => User",
+			new:  "// 用户\nUser {\n id u32 // 编号\n name text \"n\"\n}\nuser.get(id u32) => User // 查询",
+			want: nil,
+		},
+		{
+			name: "Field Removed",
+			old:  "User { id u32\n name text\n age u8 }\nuser.get(id u32) => User",
+			new:  "User { id u32\n age u8 }\nuser.get(id u32) => User",
+			want: []string{"error field-moved", "error field-removed"},
+		},
+		{
+			name: "Field Reordered",
+			old:  "User { id u32\n name text }\nuser.get(id u32) => User",
+			new:  "User { name text\n id u32 }\nuser.get(id u32) => User",
+			want: []string{"error field-moved", "error field-moved"},
+		},
+		{
+			name: "Field Type Changed",
+			old:  "User { id u32\n tags [text] }",
+			new:  "User { id u64\n tags text }",
+			want: []string{"error field-type-changed", "error field-type-changed"},
+		},
+		{
+			name: "Field Renamed",
+			old:  "User { id u32\n name text }",
+			new:  "User { id u32\n nick text }",
+			want: []string{"info field-renamed"},
+		},
+		{
+			name: "Field Appended To Result",
+			old:  "User { id u32 }\nuser.get(id u32) => User",
+			new:  "User { id u32\n name text }\nuser.get(id u32) => User",
+			want: []string{"info field-added"},
+		},
+		{
+			name: "Field Appended To Nested Struct",
+			old:  "Info { id u32 }\nUser { info Info\n id u32 }",
+			new:  "Info { id u32\n zip text }\nUser { info Info\n id u32 }",
+			want: []string{"error field-added"},
+		},
+		{
+			name: "Bitmask Resized",
+			old:  "A { f0 u8\n f1 u8\n f2 u8\n f3 u8\n f4 u8\n f5 u8\n f6 u8\n f7 u8 }\nget() => A",
+			new:  "A { f0 u8\n f1 u8\n f2 u8\n f3 u8\n f4 u8\n f5 u8\n f6 u8\n f7 u8\n f8 u8 }\nget() => A",
+			want: []string{"error bitmask-resized", "info field-added"},
+		},
+		{
+			name: "Embedded Base Changed",
+			old:  "Base { id u32 }\nAdmin { Base\n role text }",
+			new:  "Base { id u32\n name text }\nAdmin { Base\n role text }",
+			want: []string{"error field-added", "error field-moved"},
+		},
+		{
+			name: "Enum Changes",
+			old:  "S = Ok | Err | Fail",
+			new:  "S = Ok | Error | Timeout(5) | Fail(9)",
+			want: []string{"info enum-variant-renamed", "info enum-variant-added", "error enum-value-changed"},
+		},
+		{
+			name: "Enum Variant Removed",
+			old:  "S = Ok | Err",
+			new:  "S = Ok",
+			want: []string{"error enum-variant-removed"},
+		},
+		{
+			name: "Api Changes",
+			old:  "a.get(id u32) => u8\na.del(id u32) => nil\na.set(id u32, v text) => nil",
+			new:  "a.get(key u32) => u16\na.set(id u32) => nil\na.new() => nil",
+			want: []string{"info api-arg-renamed", "error api-result-changed", "error api-args-changed", "info api-added", "error api-removed"},
+		},
+		{
+			name: "Api Error Changes",
+			old:  "E = A | B\nF = A\na.get() => nil\na.set() => nil ! E\na.del() => nil ! E",
+			new:  "E = A | B\nF = A\na.get() => nil ! E\na.set() => nil ! F\na.del() => nil",
+			want: []string{"info api-error-added", "error api-error-changed", "info api-error-removed"},
+		},
+		{
+			name: "Api Idempotency Changes",
+			old:  "@idempotent\na.get() => nil\na.set() => nil",
+			new:  "a.get() => nil\n@idempotent\na.set() => nil",
+			want: []string{"error api-idempotent-removed", "info api-idempotent-added"},
+		},
+		{
+			name: "Type Kind Changed",
+			old:  "Kind { id u8 }",
+			new:  "Kind = A | B",
+			want: []string{"error type-kind-changed"},
+		},
+		{
+			name: "Definitions Added And Removed",
+			old:  "Old { id u8 }\nGone = A",
+			new:  "New { id u8 }\nFresh = A",
+			want: []string{"info struct-added", "info enum-added", "error struct-removed", "error enum-removed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Checker{OldFile: "old.sb", NewFile: "new.sb"}
+			got := summary(c.Check(mustParse(t, tt.old), mustParse(t, tt.new)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestChecker_Positions 删除的定义指向旧文件, 其余变更指向新文件
+func TestChecker_Positions(t *testing.T) {
+	c := &Checker{OldFile: "old.sb", NewFile: "new.sb", Lang: diag.LangEN}
+	got := c.Check(
+		mustParse(t, "User {\n    id   u32\n    name text\n}\nuser.get() => User"),
+		mustParse(t, "User {\n    id u64\n}"),
+	)
+	want := []string{
+		"new.sb:2:8: error[field-type-changed]: field User.id changed type from u32 to u64",
+		"old.sb:3:5: error[field-removed]: field User.name (position 1) was removed",
+		"old.sb:5:1: error[api-removed]: API user.get was removed",
+	}
+	var lines []string
+	for _, d := range got {
+		lines = append(lines, d.String())
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("got:\n%v\nwant:\n%v", lines, want)
+	}
+}
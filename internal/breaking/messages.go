@@ -0,0 +1,78 @@
+package breaking
+
+import "sb/internal/diag"
+
+// 变更代码; 不兼容变更为 error 级别, 兼容变更为 info 级别
+const (
+	CodeStructRemoved    = "struct-removed"
+	CodeStructAdded      = "struct-added"
+	CodeFieldRemoved     = "field-removed"
+	CodeFieldMoved       = "field-moved"
+	CodeFieldTypeChanged = "field-type-changed"
+	CodeFieldRenamed     = "field-renamed"
+	CodeFieldAdded       = "field-added"
+	CodeBitmaskResized   = "bitmask-resized"
+	CodeEnumRemoved      = "enum-removed"
+	CodeEnumAdded        = "enum-added"
+	CodeVariantRemoved   = "enum-variant-removed"
+	CodeVariantValue     = "enum-value-changed"
+	CodeVariantRenamed   = "enum-variant-renamed"
+	CodeVariantAdded     = "enum-variant-added"
+	CodeApiRemoved       = "api-removed"
+	CodeApiAdded         = "api-added"
+	CodeApiArgsChanged   = "api-args-changed"
+	CodeApiArgRenamed    = "api-arg-renamed"
+	CodeApiResultChanged = "api-result-changed"
+	CodeApiErrorChanged  = "api-error-changed"
+	CodeApiErrorAdded    = "api-error-added"
+	CodeApiErrorRemoved  = "api-error-removed"
+	CodeApiNotIdempotent = "api-idempotent-removed"
+	CodeApiIdempotent    = "api-idempotent-added"
+	CodeTypeKindChanged  = "type-kind-changed"
+)
+
+var messages = map[string]diag.Message{
+	CodeStructRemoved:    {ZH: "删除了结构体 %s", EN: "struct %s was removed"},
+	CodeStructAdded:      {ZH: "新增结构体 %s", EN: "struct %s was added"},
+	CodeFieldRemoved:     {ZH: "删除了字段 %s.%s (位置 %d)", EN: "field %s.%s (position %d) was removed"},
+	CodeFieldMoved:       {ZH: "字段 %s.%s 的位置由 %d 变为 %d, 位图索引错位", EN: "field %s.%s moved from position %d to %d, shifting its bitmask index"},
+	CodeFieldTypeChanged: {ZH: "字段 %s.%s 的类型由 %s 变为 %s", EN: "field %s.%s changed type from %s to %s"},
+	CodeFieldRenamed:     {ZH: "字段 %s.%s 重命名为 %s (编码不变, 生成代码的字段名会变化)", EN: "field %s.%s renamed to %s (wire-compatible, generated field names change)"},
+	CodeFieldAdded:       {ZH: "在 %s 末尾新增字段 %s: %s", EN: "field %s.%s was appended: %s"},
+	CodeBitmaskResized:   {ZH: "结构体 %s 的字段数由 %d 变为 %d, 位图长度由 %d 字节变为 %d 字节", EN: "struct %s went from %d to %d fields, resizing its bitmask from %d to %d bytes"},
+	CodeEnumRemoved:      {ZH: "删除了枚举 %s", EN: "enum %s was removed"},
+	CodeEnumAdded:        {ZH: "新增枚举 %s", EN: "enum %s was added"},
+	CodeVariantRemoved:   {ZH: "删除了枚举成员 %s.%s (值 %d)", EN: "enum variant %s.%s (value %d) was removed"},
+	CodeVariantValue:     {ZH: "枚举成员 %s.%s 的值由 %d 变为 %d", EN: "enum variant %s.%s changed value from %d to %d"},
+	CodeVariantRenamed:   {ZH: "枚举成员 %s.%s 重命名为 %s (值 %d 不变)", EN: "enum variant %s.%s renamed to %s (value %d unchanged)"},
+	CodeVariantAdded:     {ZH: "新增枚举成员 %s.%s (值 %d)", EN: "enum variant %s.%s (value %d) was added"},
+	CodeApiRemoved:       {ZH: "删除了 API %s", EN: "API %s was removed"},
+	CodeApiAdded:         {ZH: "新增 API %s", EN: "API %s was added"},
+	CodeApiArgsChanged:   {ZH: "API %s 的参数由 (%s) 变为 (%s)", EN: "API %s changed arguments from (%s) to (%s)"},
+	CodeApiArgRenamed:    {ZH: "API %s 的参数 %s 重命名为 %s (编码不变)", EN: "API %s renamed argument %s to %s (wire-compatible)"},
+	CodeApiResultChanged: {ZH: "API %s 的返回类型由 %s 变为 %s", EN: "API %s changed result type from %s to %s"},
+	CodeApiErrorChanged:  {ZH: "API %s 的错误类型由 %s 变为 %s, 旧版本会按原枚举解读错误码", EN: "API %s changed error type from %s to %s; old clients decode error codes as the old enum"},
+	CodeApiErrorAdded:    {ZH: "API %s 声明了错误类型 %s", EN: "API %s declared error type %s"},
+	CodeApiErrorRemoved:  {ZH: "API %s 不再声明错误类型 %s", EN: "API %s no longer declares error type %s"},
+	CodeApiNotIdempotent: {ZH: "API %s 不再是幂等的, 旧版本客户端仍会自动重试, 可能重复执行", EN: "API %s is no longer idempotent; old clients still retry it automatically and may repeat the call"},
+	CodeApiIdempotent:    {ZH: "API %s 标记为幂等, 客户端将自动重试", EN: "API %s was marked idempotent; clients will retry it automatically"},
+	CodeTypeKindChanged:  {ZH: "类型 %s 由%s变为%s", EN: "type %s changed from %s to %s"},
+}
+
+// 消息中引用的名词
+var (
+	nounStruct = diag.Message{ZH: "结构体", EN: "a struct"}
+	nounEnum   = diag.Message{ZH: "枚举", EN: "an enum"}
+)
+
+// 追加字段的补充说明
+var (
+	noteTailOnly = diag.Message{
+		ZH: "仅作为 API 返回值或最后一个参数使用, 新旧版本可互相解码",
+		EN: "only used as an API result or last argument, old and new readers stay compatible",
+	}
+	noteNested = diag.Message{
+		ZH: "该结构体被嵌套编码 (字段, 列表或非末尾参数), 旧版本解码器不会跳过新字段的数据",
+		EN: "the struct is encoded nested (field, list or non-last argument); old readers cannot skip the new field's bytes",
+	}
+)
@@ -0,0 +1,424 @@
+// Package descriptor 以 JSON 描述符形式导出已解析的 Schema, 并可从描述符还原 ast.Schema
+//
+// 描述符面向 .sb 解析器之外的工具 (其他语言的数据处理, Schema 注册中心等),
+// 格式独立于 ast 包的内部结构, 通过 version 字段演进:
+//   - 新增可选字段不改变版本号, 读取方应忽略未知字段
+//   - 删除字段或改变字段含义时递增版本号, Load 拒绝高于 Version 的描述符
+//
+// 版本 1 的结构:
+//
+//	{
+//	  "version": 1,
+//	  "go_package": "example.com/app/proto/billing", // sb:go-package 指令, 未指定时省略
+//	  "structs": [{
+//	    "name": "RechargeA",
+//	    "embeds": ["Recharge"],              // 直接嵌入的结构体 (声明顺序)
+//	    "fields": [{                         // 展开嵌入后的完整字段, 顺序即编码顺序
+//	      "index": 0,                        // 位图索引
+//	      "name": "id",
+//	      "type": {"name": "u32", "kind": "base", "list": false},
+//	      "tag": "_id",
+//	      "note": "...",
+//	      "embedded_from": "Recharge",       // 经嵌入展开而来时为直接嵌入的结构体, 自身字段省略
+//	      "deprecated": {"reason": "..."}    // 仅已弃用时出现
+//	    }]
+//	  }],
+//	  "enums": [{"name": "Status", "variants": [{"name": "Ok", "id": 0}]}],
+//	  "apis": [{
+//	    "name": "user.get",
+//	    "args": [{"name": "id", "type": {...}}],
+//	    "result": {...},                     // 无返回值 (nil) 时为 null
+//	    "idempotent": true                   // @idempotent, 否则省略
+//	  }]
+//	}
+//
+// type.kind 取值: base (基础类型), struct, enum。
+package descriptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"sb/internal/ast"
+)
+
+// Version 当前描述符格式版本
+const Version = 1
+
+// File 描述符根节点
+type File struct {
+	Version   int      `json:"version"`
+	Note      string   `json:"note,omitempty"`
+	GoPackage string   `json:"go_package,omitempty"`
+	Structs   []Struct `json:"structs"`
+	Enums     []Enum   `json:"enums"`
+	Apis      []Api    `json:"apis"`
+}
+
+type Type struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // base | struct | enum
+	List bool   `json:"list"`
+}
+
+type Deprecation struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type Field struct {
+	Index        int          `json:"index"`
+	Name         string       `json:"name"`
+	Type         Type         `json:"type"`
+	Tag          string       `json:"tag,omitempty"`
+	Note         string       `json:"note,omitempty"`
+	EmbeddedFrom string       `json:"embedded_from,omitempty"`
+	Deprecated   *Deprecation `json:"deprecated,omitempty"`
+}
+
+type Struct struct {
+	Name   string   `json:"name"`
+	Note   string   `json:"note,omitempty"`
+	Embeds []string `json:"embeds,omitempty"`
+	Fields []Field  `json:"fields"`
+}
+
+type Variant struct {
+	Name       string       `json:"name"`
+	ID         uint8        `json:"id"`
+	Note       string       `json:"note,omitempty"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+}
+
+type Enum struct {
+	Name     string    `json:"name"`
+	Note     string    `json:"note,omitempty"`
+	Variants []Variant `json:"variants"`
+}
+
+type Arg struct {
+	Name string `json:"name"`
+	Type Type   `json:"type"`
+}
+
+type Api struct {
+	Name       string       `json:"name"`
+	Note       string       `json:"note,omitempty"`
+	Args       []Arg        `json:"args"`
+	Result     *Type        `json:"result"`          // nil 表示无返回值
+	Error      *Type        `json:"error,omitempty"` // 业务错误枚举
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	Idempotent bool         `json:"idempotent,omitempty"`
+}
+
+var kindNames = map[ast.TypeKind]string{
+	ast.KindBase:   "base",
+	ast.KindStruct: "struct",
+	ast.KindEnum:   "enum",
+}
+
+// --- 导出 ---
+
+// Marshal 导出为带缩进的 JSON
+func Marshal(s *ast.Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(FromSchema(s), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// FromSchema 由已通过语义分析的 Schema 构建描述符
+func FromSchema(s *ast.Schema) *File {
+	f := &File{
+		Version:   Version,
+		Note:      s.Note,
+		GoPackage: s.GoPackage,
+		Structs:   make([]Struct, 0, len(s.Structs)),
+		Enums:     make([]Enum, 0, len(s.Enums)),
+		Apis:      make([]Api, 0, len(s.Apis)),
+	}
+
+	for _, st := range s.Structs {
+		ds := Struct{Name: st.Name, Note: st.Note, Fields: make([]Field, 0, len(st.Fields))}
+		for _, e := range st.Embeds {
+			ds.Embeds = append(ds.Embeds, e.Name)
+		}
+		for i, fd := range st.Fields {
+			ds.Fields = append(ds.Fields, Field{
+				Index:        i,
+				Name:         fd.Name,
+				Type:         typeOf(fd.Type),
+				Tag:          fd.Tag,
+				Note:         fd.Note,
+				EmbeddedFrom: fd.Embedded,
+				Deprecated:   deprecationOf(fd.Deprecated),
+			})
+		}
+		f.Structs = append(f.Structs, ds)
+	}
+
+	for _, e := range s.Enums {
+		de := Enum{Name: e.Name, Note: e.Note, Variants: make([]Variant, 0, len(e.Children))}
+		for _, c := range e.Children {
+			de.Variants = append(de.Variants, Variant{Name: c.Name, ID: c.ID, Note: c.Note, Deprecated: deprecationOf(c.Deprecated)})
+		}
+		f.Enums = append(f.Enums, de)
+	}
+
+	for _, api := range s.Apis {
+		da := Api{Name: api.Name, Note: api.Note, Args: make([]Arg, 0, len(api.Args)), Deprecated: deprecationOf(api.Deprecated), Idempotent: api.Idempotent}
+		for _, a := range api.Args {
+			da.Args = append(da.Args, Arg{Name: a.Name, Type: typeOf(a.Type)})
+		}
+		if api.Result.Name != "nil" {
+			t := typeOf(api.Result)
+			da.Result = &t
+		}
+		if api.Error != nil {
+			t := typeOf(*api.Error)
+			da.Error = &t
+		}
+		f.Apis = append(f.Apis, da)
+	}
+	return f
+}
+
+func typeOf(t ast.Type) Type {
+	return Type{Name: t.Name, Kind: kindNames[t.Kind], List: t.IsList}
+}
+
+func deprecationOf(d *ast.Deprecation) *Deprecation {
+	if d == nil {
+		return nil
+	}
+	return &Deprecation{Reason: d.Reason}
+}
+
+// --- 加载 ---
+
+// Load 解析 JSON 描述符并还原为 ast.Schema (不含源码位置)
+func Load(data []byte) (*ast.Schema, error) {
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("descriptor: %w", err)
+	}
+	return f.ToSchema()
+}
+
+// ToSchema 校验描述符并还原为 ast.Schema
+// 校验项与解析器的语义分析一致: 版本, 类型引用, 重复定义 (含枚举成员名称与数值, API 参数)
+func (f *File) ToSchema() (*ast.Schema, error) {
+	if f.Version < 1 || f.Version > Version {
+		return nil, fmt.Errorf("descriptor: unsupported version %d (supported: 1-%d)", f.Version, Version)
+	}
+
+	kinds := make(map[string]ast.TypeKind)
+	define := func(name string, kind ast.TypeKind) error {
+		if _, ok := kinds[name]; ok {
+			return fmt.Errorf("descriptor: %s is defined more than once", name)
+		}
+		kinds[name] = kind
+		return nil
+	}
+	for _, st := range f.Structs {
+		if err := define(st.Name, ast.KindStruct); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range f.Enums {
+		if err := define(e.Name, ast.KindEnum); err != nil {
+			return nil, err
+		}
+	}
+	resolve := func(t Type, where string) (ast.Type, error) {
+		kind, ok := kinds[t.Name]
+		if !ok {
+			if t.Kind != "base" || !ast.IsBaseType(t.Name) {
+				return ast.Type{}, fmt.Errorf("descriptor: %s: undefined type %s", where, t.Name)
+			}
+			kind = ast.KindBase
+		}
+		if kindNames[kind] != t.Kind {
+			return ast.Type{}, fmt.Errorf("descriptor: %s: type %s has kind %s, want %s", where, t.Name, t.Kind, kindNames[kind])
+		}
+		return ast.Type{Name: t.Name, Kind: kind, IsList: t.List}, nil
+	}
+
+	s := &ast.Schema{Note: f.Note, GoPackage: f.GoPackage}
+	for _, ds := range f.Structs {
+		st := ast.Struct{Name: ds.Name, Note: ds.Note}
+		for _, name := range ds.Embeds {
+			if kinds[name] != ast.KindStruct || name == ds.Name {
+				return nil, fmt.Errorf("descriptor: struct %s: invalid embed %s", ds.Name, name)
+			}
+			st.Embeds = append(st.Embeds, ast.Type{Name: name, Kind: ast.KindStruct})
+		}
+		for i, df := range ds.Fields {
+			if df.Index != i {
+				return nil, fmt.Errorf("descriptor: field %s.%s: index %d, want %d", ds.Name, df.Name, df.Index, i)
+			}
+			t, err := resolve(df.Type, ds.Name+"."+df.Name)
+			if err != nil {
+				return nil, err
+			}
+			st.Fields = append(st.Fields, ast.StructField{
+				Name:       df.Name,
+				Type:       t,
+				Tag:        df.Tag,
+				Note:       df.Note,
+				Embedded:   df.EmbeddedFrom,
+				Deprecated: deprecationFrom(df.Deprecated),
+			})
+		}
+		if len(st.Fields) > ast.MaxStructFields {
+			return nil, fmt.Errorf("descriptor: struct %s has %d fields (max %d)", ds.Name, len(st.Fields), ast.MaxStructFields)
+		}
+		s.Structs = append(s.Structs, st)
+	}
+	if err := checkEmbeds(f.Structs); err != nil {
+		return nil, err
+	}
+
+	for _, de := range f.Enums {
+		e := ast.Enum{Name: de.Name, Note: de.Note}
+		names, ids := make(map[string]bool), make(map[uint8]string)
+		for _, v := range de.Variants {
+			if names[v.Name] {
+				return nil, fmt.Errorf("descriptor: enum %s: variant %s is defined more than once", de.Name, v.Name)
+			}
+			if prev, ok := ids[v.ID]; ok {
+				return nil, fmt.Errorf("descriptor: enum %s: variants %s and %s have the same id %d", de.Name, prev, v.Name, v.ID)
+			}
+			names[v.Name], ids[v.ID] = true, v.Name
+			e.Children = append(e.Children, ast.EnumChild{ID: v.ID, Name: v.Name, Note: v.Note, Deprecated: deprecationFrom(v.Deprecated)})
+		}
+		s.Enums = append(s.Enums, e)
+	}
+
+	apis := make(map[string]bool)
+	for _, da := range f.Apis {
+		if apis[da.Name] {
+			return nil, fmt.Errorf("descriptor: API %s is defined more than once", da.Name)
+		}
+		apis[da.Name] = true
+		api := ast.Api{Name: da.Name, Note: da.Note, Deprecated: deprecationFrom(da.Deprecated), Idempotent: da.Idempotent}
+		args := make(map[string]bool)
+		for _, a := range da.Args {
+			if args[a.Name] {
+				return nil, fmt.Errorf("descriptor: API %s has duplicate argument %s", da.Name, a.Name)
+			}
+			args[a.Name] = true
+			t, err := resolve(a.Type, da.Name+"("+a.Name+")")
+			if err != nil {
+				return nil, err
+			}
+			api.Args = append(api.Args, ast.ApiArg{Name: a.Name, Type: t})
+		}
+		api.Result = ast.Type{Name: "nil", Kind: ast.KindBase}
+		if da.Result != nil {
+			t, err := resolve(*da.Result, da.Name+" result")
+			if err != nil {
+				return nil, err
+			}
+			api.Result = t
+		}
+		if da.Error != nil {
+			t, err := resolve(*da.Error, da.Name+" error")
+			if err != nil {
+				return nil, err
+			}
+			if t.Kind != ast.KindEnum || t.IsList {
+				return nil, fmt.Errorf("descriptor: %s error: type %s must be an enum", da.Name, t.Name)
+			}
+			api.Error = &t
+		}
+		s.Apis = append(s.Apis, api)
+	}
+	return s, nil
+}
+
+// checkEmbeds 嵌入不能成环, 且展开后的字段必须与被嵌入结构体的字段一致:
+// 描述符同时保存 embeds 与展开后的 fields, 手工修改或过期的描述符可能两者不符
+func checkEmbeds(structs []Struct) error {
+	byName := make(map[string]*Struct, len(structs))
+	for i := range structs {
+		byName[structs[i].Name] = &structs[i]
+	}
+	expanded := make(map[string][]Field) // 已校验结构体的完整字段
+	visiting := make(map[string]bool)
+
+	var check func(ds *Struct) error
+	check = func(ds *Struct) error {
+		if _, ok := expanded[ds.Name]; ok {
+			return nil
+		}
+		if visiting[ds.Name] {
+			return fmt.Errorf("descriptor: struct %s: circular embedding", ds.Name)
+		}
+		visiting[ds.Name] = true
+		defer delete(visiting, ds.Name)
+
+		// 按字段顺序, 每个嵌入对应一段连续且与被嵌入结构体完全一致的字段
+		var embeds []string
+		for i := 0; i < len(ds.Fields); {
+			from := ds.Fields[i].EmbeddedFrom
+			if from == "" {
+				i++
+				continue
+			}
+			base, ok := byName[from]
+			if !ok || !slices.Contains(ds.Embeds, from) {
+				return fmt.Errorf("descriptor: field %s.%s: %s is not embedded", ds.Name, ds.Fields[i].Name, from)
+			}
+			if err := check(base); err != nil {
+				return err
+			}
+			want := expanded[from]
+			for j, wf := range want {
+				if i+j >= len(ds.Fields) || !sameField(ds.Fields[i+j], wf, from) {
+					return fmt.Errorf("descriptor: struct %s: fields embedded from %s do not match struct %s", ds.Name, from, from)
+				}
+			}
+			embeds = append(embeds, from)
+			i += len(want)
+		}
+		// 没有字段的嵌入结构体不出现在 fields 中
+		var declared []string
+		for _, name := range ds.Embeds {
+			if base, ok := byName[name]; ok {
+				if err := check(base); err != nil {
+					return err
+				}
+			}
+			if len(expanded[name]) > 0 {
+				declared = append(declared, name)
+			}
+		}
+		if !slices.Equal(embeds, declared) {
+			return fmt.Errorf("descriptor: struct %s: embeds %v do not match the embedded fields", ds.Name, ds.Embeds)
+		}
+		expanded[ds.Name] = ds.Fields
+		return nil
+	}
+	for i := range structs {
+		if err := check(&structs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameField 展开到嵌入方的字段 got 与被嵌入结构体 from 的字段 want 一致 (索引除外)
+func sameField(got, want Field, from string) bool {
+	return got.Name == want.Name && got.Type == want.Type && got.Tag == want.Tag && got.Note == want.Note &&
+		got.EmbeddedFrom == from && (got.Deprecated == nil) == (want.Deprecated == nil) &&
+		(got.Deprecated == nil || *got.Deprecated == *want.Deprecated)
+}
+
+func deprecationFrom(d *Deprecation) *ast.Deprecation {
+	if d == nil {
+		return nil
+	}
+	return &ast.Deprecation{Reason: d.Reason}
+}
@@ -0,0 +1,165 @@
+package descriptor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sb/internal/ast"
+	"sb/internal/lexer"
+	"sb/internal/parser"
+)
+
+const testSource = `// sb:go-package example.com/app/proto/order;order
+
+// 订单状态
+OrderStatus = Pending | @deprecated("使用 Paid") Done | Paid(5)
+
+CancelError = NotFound | Shipped
+
+// 基础信息
+Info {
+    id  u32  "_id" // 编号
+    tag [text]
+}
+
+Order {
+    Info
+    status OrderStatus
+    items  [Info]
+}
+
+// 查询订单
+@idempotent
+order.get(id u32, tags [text]) => Order
+order.cancel(id u32) => nil ! CancelError
+`
+
+// clearPos 清除源码位置, 描述符不保留位置信息
+func clearPos(s *ast.Schema) {
+	for i := range s.Structs {
+		st := &s.Structs[i]
+		st.Pos = ast.Pos{}
+		for j := range st.Embeds {
+			st.Embeds[j].Pos = ast.Pos{}
+		}
+		for j := range st.Fields {
+			st.Fields[j].Pos, st.Fields[j].Type.Pos = ast.Pos{}, ast.Pos{}
+		}
+	}
+	for i := range s.Enums {
+		s.Enums[i].Pos = ast.Pos{}
+		for j := range s.Enums[i].Children {
+			s.Enums[i].Children[j].Pos = ast.Pos{}
+		}
+	}
+	for i := range s.Apis {
+		api := &s.Apis[i]
+		api.Pos, api.Result.Pos = ast.Pos{}, ast.Pos{}
+		if api.Error != nil {
+			api.Error.Pos = ast.Pos{}
+		}
+		for j := range api.Args {
+			api.Args[j].Pos, api.Args[j].Type.Pos = ast.Pos{}, ast.Pos{}
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	schema, err := parser.New(lexer.New(testSource)).ParseSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"version": 1`, `"embedded_from": "Info"`, `"result": null`, `"go_package": "example.com/app/proto/order;order"`, `"reason": "使用 Paid"`, `"error": {`, `"idempotent": true`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("descriptor missing %s:\n%s", want, data)
+		}
+	}
+
+	got, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearPos(schema)
+	if !reflect.DeepEqual(got, schema) {
+		t.Errorf("round trip mismatch:\n got  %+v\n want %+v", got, schema)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"version", `{"version": 2}`, "unsupported version 2"},
+		{"syntax", `{"version": 1`, "descriptor:"},
+		{"duplicate", `{"version": 1, "structs": [{"name": "A"}], "enums": [{"name": "A"}]}`, "A is defined more than once"},
+		{"undefined", `{"version": 1, "structs": [{"name": "A", "fields": [{"index": 0, "name": "b", "type": {"name": "B", "kind": "struct"}}]}]}`, "undefined type B"},
+		{"kind", `{"version": 1, "enums": [{"name": "E"}], "apis": [{"name": "get", "result": {"name": "E", "kind": "struct"}}]}`, "type E has kind struct, want enum"},
+		{"index", `{"version": 1, "structs": [{"name": "A", "fields": [{"index": 1, "name": "b", "type": {"name": "u8", "kind": "base"}}]}]}`, "index 1, want 0"},
+		{"embed", `{"version": 1, "structs": [{"name": "A", "embeds": ["A"]}]}`, "invalid embed A"},
+		{"embedded_from", `{"version": 1, "structs": [{"name": "A", "fields": [{"index": 0, "name": "b", "type": {"name": "u8", "kind": "base"}, "embedded_from": "B"}]}]}`, "B is not embedded"},
+		{"variant name", `{"version": 1, "enums": [{"name": "E", "variants": [{"name": "A", "id": 0}, {"name": "A", "id": 1}]}]}`, "enum E: variant A is defined more than once"},
+		{"variant id", `{"version": 1, "enums": [{"name": "E", "variants": [{"name": "A", "id": 1}, {"name": "B", "id": 1}]}]}`, "enum E: variants A and B have the same id 1"},
+		{"api", `{"version": 1, "apis": [{"name": "get"}, {"name": "get"}]}`, "API get is defined more than once"},
+		{"argument", `{"version": 1, "apis": [{"name": "get", "args": [{"name": "id", "type": {"name": "u8", "kind": "base"}}, {"name": "id", "type": {"name": "u8", "kind": "base"}}]}]}`, "API get has duplicate argument id"},
+		{"api error", `{"version": 1, "structs": [{"name": "A"}], "apis": [{"name": "get", "error": {"name": "A", "kind": "struct"}}]}`, "type A must be an enum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoad_Embeds 嵌入成环, 或展开后的字段与 embeds 及被嵌入结构体不一致的描述符被拒绝
+func TestLoad_Embeds(t *testing.T) {
+	schema, err := parser.New(lexer.New(testSource)).ParseSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		modify func(info, order *Struct)
+		want   string
+	}{
+		{"cycle", func(info, order *Struct) {
+			info.Embeds = []string{"Order"}
+		}, "circular embedding"},
+		{"stale field", func(info, order *Struct) {
+			info.Fields[0].Type.Name = "u64"
+		}, "struct Order: fields embedded from Info do not match struct Info"},
+		{"missing field", func(info, order *Struct) {
+			order.Fields = order.Fields[1:]
+			for i := range order.Fields {
+				order.Fields[i].Index = i
+			}
+		}, "struct Order: fields embedded from Info do not match struct Info"},
+		{"undeclared embed", func(info, order *Struct) {
+			order.Embeds = nil
+		}, "field Order.id: Info is not embedded"},
+		{"flattened away", func(info, order *Struct) {
+			for i := range order.Fields {
+				order.Fields[i].EmbeddedFrom = ""
+			}
+		}, "struct Order: embeds [Info] do not match the embedded fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := FromSchema(schema)
+			tt.modify(&f.Structs[0], &f.Structs[1])
+			_, err := f.ToSchema()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ToSchema error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
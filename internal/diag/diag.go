@@ -0,0 +1,120 @@
+package diag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity 诊断级别
+type Severity int
+
+const (
+	SeverityError   Severity = iota // 错误: 无法继续生成代码
+	SeverityWarning                 // 警告: 不影响生成, 但建议修复
+	SeverityInfo                    // 提示: 仅供参考
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return "error"
+}
+
+// Lang 诊断信息语言
+type Lang string
+
+const (
+	LangZH Lang = "zh" // 中文 (默认)
+	LangEN Lang = "en" // 英文
+)
+
+// ParseLang 解析语言标识, 空字符串返回默认中文
+func ParseLang(s string) (Lang, error) {
+	switch Lang(strings.ToLower(s)) {
+	case "", LangZH:
+		return LangZH, nil
+	case LangEN:
+		return LangEN, nil
+	}
+	return LangZH, fmt.Errorf("unsupported language %q (zh|en)", s)
+}
+
+// Message 双语消息模板, 按 Lang 选择格式串
+type Message struct {
+	ZH string
+	EN string
+}
+
+// Format 按语言格式化消息
+func (m Message) Format(lang Lang, args ...any) string {
+	format := m.ZH
+	if lang == LangEN && m.EN != "" {
+		format = m.EN
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Diagnostic 带位置的结构化诊断信息
+type Diagnostic struct {
+	File     string
+	Line     int // 从 1 开始, 0 表示未知
+	Col      int // 从 1 开始 (按字符计), 0 表示未知
+	Severity Severity
+	Code     string // 稳定的机器可读代码 (如 undefined-type)
+	Message  string
+}
+
+// String 格式: file:line:col: severity[code]: message
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		b.WriteString(d.File)
+		b.WriteString(":")
+	}
+	if d.Line > 0 {
+		fmt.Fprintf(&b, "%d:", d.Line)
+		if d.Col > 0 {
+			fmt.Fprintf(&b, "%d:", d.Col)
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	fmt.Fprintf(&b, "%s[%s]: %s", d.Severity, d.Code, d.Message)
+	return b.String()
+}
+
+func (d Diagnostic) Error() string { return d.String() }
+
+// List 诊断列表, 可直接作为 error 返回
+type List []Diagnostic
+
+// HasErrors 是否包含错误级别的诊断
+func (l List) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err 包含错误时返回自身, 否则返回 nil
+func (l List) Err() error {
+	if l.HasErrors() {
+		return l
+	}
+	return nil
+}
+
+func (l List) Error() string {
+	lines := make([]string, len(l))
+	for i, d := range l {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
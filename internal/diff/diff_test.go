@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func lines(from, to int) string {
+	var sb strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+	return sb.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"new file", "", "a\nb\n",
+			"--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			"change", lines(1, 10), strings.Replace(lines(1, 10), "5\n", "five\n", 1),
+			"--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"two hunks", lines(1, 20), strings.Replace(strings.Replace(lines(1, 20), "2\n", "", 1), "19\n", "19\nx\n", 1),
+			"--- a\n+++ b\n@@ -1,5 +1,4 @@\n 1\n-2\n 3\n 4\n 5\n@@ -17,4 +16,5 @@\n 17\n 18\n 19\n+x\n 20\n",
+		},
+		{
+			"merged hunk", lines(1, 9), strings.Replace(strings.Replace(lines(1, 9), "1\n", "one\n", 1), "8\n", "eight\n", 1),
+			"--- a\n+++ b\n@@ -1,9 +1,9 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n",
+		},
+		{
+			"no newline", "a\nb", "a\nc",
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{
+			"delete all", "a\n", "",
+			"--- a\n+++ b\n@@ -1 +0,0 @@\n-a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", []byte(tt.a), []byte(tt.b)); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnified_Apply 对随机改动应用差异, 结果应与目标一致
+func TestUnified_Apply(t *testing.T) {
+	a := strings.Split("a b c d e f g h i j k l m n o p", " ")
+	b := strings.Split("x b c e f g y h i j z l n o p q", " ")
+	ops := lineOps(a, b)
+	var gotA, gotB []string
+	for _, o := range ops {
+		if o.kind != opInsert {
+			gotA = append(gotA, o.line)
+		}
+		if o.kind != opDelete {
+			gotB = append(gotB, o.line)
+		}
+	}
+	if strings.Join(gotA, " ") != strings.Join(a, " ") || strings.Join(gotB, " ") != strings.Join(b, " ") {
+		t.Errorf("ops do not reproduce inputs: %v / %v", gotA, gotB)
+	}
+}
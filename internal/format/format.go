@@ -0,0 +1,573 @@
+// Package format 输出 .sb 文件的规范格式
+//
+// 格式化基于 Token 流而不是 ast.Schema: AST 会丢弃独立注释, 空行和嵌入关系,
+// 而格式化必须原样保留这些信息, 只调整空白与对齐。
+package format
+
+import (
+	"bytes"
+	"sb/internal/diag"
+	"sb/internal/lexer"
+	"sb/internal/parser"
+	"strings"
+	"unicode/utf8"
+)
+
+// indent 结构体字段与多行参数的缩进
+const indent = "    "
+
+// Source 格式化 .sb 源码, 存在语法错误时返回 diag.List
+// 语义错误 (如未定义类型) 不影响格式化
+func Source(src []byte) ([]byte, error) {
+	return SourceFile("", src, diag.LangZH)
+}
+
+// SourceFile 同 Source, filename 与 lang 用于诊断信息
+func SourceFile(filename string, src []byte, lang diag.Lang) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	p.File = filename
+	p.Lang = lang
+	_, diags := p.Parse()
+
+	var syntax diag.List
+	for _, d := range diags {
+		if parser.IsSyntaxError(d.Code) {
+			syntax = append(syntax, d)
+		}
+	}
+	if err := syntax.Err(); err != nil {
+		return nil, err
+	}
+
+	f := &formatter{toks: tokenize(string(src))}
+	f.parseFile()
+	return f.print(), nil
+}
+
+func tokenize(src string) []lexer.Token {
+	l := lexer.New(src)
+	var toks []lexer.Token
+	for {
+		tok := l.NextToken()
+		toks = append(toks, tok)
+		if tok.Type == lexer.TokenEOF {
+			return toks
+		}
+	}
+}
+
+// --- 轻量语法树 (仅供格式化使用) ---
+
+type nodeKind int
+
+const (
+	nodeBlank nodeKind = iota
+	nodeComment
+	nodeAnnotation
+	nodeStruct
+	nodeEnum
+	nodeApi
+)
+
+type node struct {
+	kind    nodeKind
+	text    string // 注释 / 注解文本
+	comment string // 同行尾注释
+
+	st  *structNode
+	en  *enumNode
+	api *apiNode
+}
+
+type structNode struct {
+	name        string
+	openComment string // { 同行的注释
+	items       []structItem
+}
+
+type structItem struct {
+	kind    nodeKind // nodeBlank, nodeComment, nodeAnnotation 或 nodeStruct (表示字段)
+	text    string
+	name    string
+	typ     string // 为空表示嵌入
+	tag     string
+	comment string
+}
+
+type enumNode struct {
+	name      string
+	variants  []variant
+	multiline bool
+}
+
+type variant struct {
+	annos   []string
+	text    string // 名称及可选的显式数值, 如 Active(3)
+	comment string
+}
+
+type apiNode struct {
+	name      string
+	items     []apiItem
+	result    string
+	multiline bool
+}
+
+type apiItem struct {
+	isComment bool
+	name      string
+	typ       string
+	comment   string
+}
+
+// --- Token 解析 ---
+
+type formatter struct {
+	toks     []lexer.Token
+	i        int
+	lastLine int // 最近消费的 Token 所在行
+	nodes    []node
+}
+
+func (f *formatter) cur() lexer.Token  { return f.toks[f.i] }
+func (f *formatter) peek() lexer.Token { return f.toks[min(f.i+1, len(f.toks)-1)] }
+
+func (f *formatter) next() lexer.Token {
+	tok := f.toks[f.i]
+	if tok.Type != lexer.TokenEOF {
+		f.i++
+	}
+	f.lastLine = tok.Line
+	return tok
+}
+
+// blankBefore 当前 Token 与上一个 Token 之间是否存在空行
+func (f *formatter) blankBefore() bool {
+	return f.lastLine > 0 && f.cur().Line > f.lastLine+1
+}
+
+// trailingComment 若当前 Token 是与上一个 Token 同行的注释则消费并返回
+func (f *formatter) trailingComment() string {
+	if f.cur().Type == lexer.TokenComment && f.cur().Line == f.lastLine {
+		return f.next().Value
+	}
+	return ""
+}
+
+func (f *formatter) parseFile() {
+	for f.cur().Type != lexer.TokenEOF {
+		if f.blankBefore() && len(f.nodes) > 0 {
+			f.nodes = append(f.nodes, node{kind: nodeBlank})
+		}
+
+		tok := f.cur()
+		switch {
+		case tok.Type == lexer.TokenComment:
+			// } 之后的同行注释归属于上一个定义
+			if n := len(f.nodes); n > 0 && tok.Line == f.lastLine && f.nodes[n-1].kind == nodeStruct {
+				f.nodes[n-1].comment = f.next().Value
+				continue
+			}
+			f.nodes = append(f.nodes, node{kind: nodeComment, text: f.next().Value})
+		case tok.Type == lexer.TokenAt:
+			f.nodes = append(f.nodes, node{kind: nodeAnnotation, text: f.parseAnnotation()})
+		case f.peek().Type == lexer.TokenLBrace:
+			f.nodes = append(f.nodes, node{kind: nodeStruct, st: f.parseStruct()})
+		case f.peek().Type == lexer.TokenAssign || f.peek().Type == lexer.TokenPipe:
+			f.nodes = append(f.nodes, node{kind: nodeEnum, en: f.parseEnum()})
+		default:
+			api, comment := f.parseApi()
+			f.nodes = append(f.nodes, node{kind: nodeApi, api: api, comment: comment})
+		}
+	}
+}
+
+// parseAnnotation 读取 @name 或 @name("value"), 返回规范文本
+func (f *formatter) parseAnnotation() string {
+	at := f.next() // @
+	name := f.next()
+	text := "@" + name.Value
+	if f.cur().Type == lexer.TokenLParen && f.cur().Line == at.Line {
+		f.next() // (
+		text += "(" + f.next().Value + ")"
+		f.next() // )
+	}
+	return text
+}
+
+func (f *formatter) parseType() string {
+	if f.cur().Type != lexer.TokenLBracket {
+		return f.next().Value
+	}
+	f.next() // [
+	name := f.next().Value
+	f.next() // ]
+	return "[" + name + "]"
+}
+
+func (f *formatter) parseStruct() *structNode {
+	s := &structNode{name: f.next().Value}
+	f.next() // {
+	s.openComment = f.trailingComment()
+
+	for f.cur().Type != lexer.TokenRBrace && f.cur().Type != lexer.TokenEOF {
+		if f.blankBefore() && len(s.items) > 0 {
+			s.items = append(s.items, structItem{kind: nodeBlank})
+		}
+		switch f.cur().Type {
+		case lexer.TokenComma:
+			f.next()
+		case lexer.TokenComment:
+			s.items = append(s.items, structItem{kind: nodeComment, text: f.next().Value})
+		case lexer.TokenAt:
+			s.items = append(s.items, structItem{kind: nodeAnnotation, text: f.parseAnnotation()})
+		default:
+			s.items = append(s.items, f.parseField())
+		}
+	}
+	f.next() // }
+	return s
+}
+
+func (f *formatter) parseField() structItem {
+	name := f.next()
+	item := structItem{kind: nodeStruct, name: name.Value}
+	next := f.cur()
+	if next.Line == name.Line && (next.Type == lexer.TokenIdent || next.Type == lexer.TokenLBracket) {
+		item.typ = f.parseType()
+		if tok := f.cur(); tok.Type == lexer.TokenIdent && tok.Line == name.Line && isQuoted(tok.Value) {
+			item.tag = f.next().Value
+		}
+	}
+	item.comment = f.trailingComment()
+	return item
+}
+
+func (f *formatter) parseEnum() *enumNode {
+	name := f.next()
+	e := &enumNode{name: name.Value}
+	if f.cur().Type == lexer.TokenAssign {
+		f.next()
+	}
+
+	var annos []string
+	for f.cur().Type != lexer.TokenEOF {
+		switch f.cur().Type {
+		case lexer.TokenPipe:
+			f.next()
+			continue
+		case lexer.TokenAt:
+			annos = append(annos, f.parseAnnotation())
+			continue
+		}
+
+		tok := f.next()
+		if tok.Line != name.Line {
+			e.multiline = true
+		}
+		v := variant{annos: annos, text: tok.Value}
+		annos = nil
+		if f.cur().Type == lexer.TokenLParen {
+			f.next() // (
+			v.text += "(" + f.next().Value + ")"
+			f.next() // )
+		}
+		v.comment = f.trailingComment()
+		e.variants = append(e.variants, v)
+
+		if f.cur().Type != lexer.TokenPipe {
+			break
+		}
+	}
+
+	// 尾注释只能出现在行末, 非最后一个成员带注释时必须换行
+	for _, v := range e.variants[:max(len(e.variants)-1, 0)] {
+		if v.comment != "" {
+			e.multiline = true
+		}
+	}
+	return e
+}
+
+func (f *formatter) parseApi() (*apiNode, string) {
+	first := f.next()
+	api := &apiNode{name: first.Value}
+	for f.cur().Type == lexer.TokenDot {
+		f.next()
+		api.name += "." + f.next().Value
+	}
+	f.next() // (
+
+	for f.cur().Type != lexer.TokenRParen && f.cur().Type != lexer.TokenEOF {
+		tok := f.cur()
+		if tok.Line != first.Line {
+			api.multiline = true
+		}
+		switch tok.Type {
+		case lexer.TokenComma:
+			f.next()
+			// 逗号后的同行注释属于前一个参数
+			if c := f.trailingComment(); c != "" && len(api.items) > 0 {
+				api.items[len(api.items)-1].comment = c
+			}
+		case lexer.TokenComment:
+			if n := len(api.items); n > 0 && tok.Line == f.lastLine && !api.items[n-1].isComment {
+				api.items[n-1].comment = f.next().Value
+				continue
+			}
+			api.items = append(api.items, apiItem{isComment: true, comment: f.next().Value})
+		default:
+			item := apiItem{name: f.next().Value}
+			item.typ = f.parseType()
+			api.items = append(api.items, item)
+		}
+	}
+	if f.cur().Line != first.Line {
+		api.multiline = true
+	}
+	f.next() // )
+	f.next() // =>
+	api.result = f.parseType()
+	if f.cur().Type == lexer.TokenBang {
+		f.next() // !
+		api.result += " ! " + f.parseType()
+	}
+	return api, f.trailingComment()
+}
+
+// --- 输出 ---
+
+func (f *formatter) print() []byte {
+	var b bytes.Buffer
+	for i := 0; i < len(f.nodes); i++ {
+		n := f.nodes[i]
+		switch n.kind {
+		case nodeBlank:
+			b.WriteString("\n")
+		case nodeComment:
+			b.WriteString(comment(n.text) + "\n")
+		case nodeAnnotation:
+			b.WriteString(n.text + "\n")
+		case nodeStruct:
+			printStruct(&b, n)
+		case nodeEnum:
+			printEnum(&b, n.en)
+		case nodeApi:
+			// 连续的单行 API 对齐尾注释
+			j := i
+			var rows [][]string
+			for j < len(f.nodes) && f.nodes[j].kind == nodeApi && !f.nodes[j].api.multiline {
+				rows = append(rows, []string{apiSignature(f.nodes[j].api), comment(f.nodes[j].comment)})
+				j++
+			}
+			if len(rows) > 0 {
+				for _, line := range alignRows(rows) {
+					b.WriteString(line + "\n")
+				}
+				i = j - 1
+				continue
+			}
+			printMultilineApi(&b, n)
+		}
+	}
+	return b.Bytes()
+}
+
+func printStruct(b *bytes.Buffer, n node) {
+	s := n.st
+	b.WriteString(s.name + " {")
+	if len(s.items) == 0 {
+		b.WriteString("}" + withComment("", n.comment) + "\n")
+		return
+	}
+	b.WriteString(withComment("", s.openComment) + "\n")
+
+	// 按空行分块对齐, 注释与注解行不参与对齐但也不打断分块
+	var block []structItem
+	flush := func() {
+		var rows [][]string
+		for _, it := range block {
+			if it.kind == nodeStruct {
+				rows = append(rows, []string{it.name, it.typ, it.tag, comment(it.comment)})
+			}
+		}
+		lines := alignRows(rows)
+		for _, it := range block {
+			switch it.kind {
+			case nodeComment:
+				b.WriteString(indent + comment(it.text) + "\n")
+			case nodeAnnotation:
+				b.WriteString(indent + it.text + "\n")
+			default:
+				b.WriteString(indent + lines[0] + "\n")
+				lines = lines[1:]
+			}
+		}
+		block = block[:0]
+	}
+	for _, it := range s.items {
+		if it.kind == nodeBlank {
+			flush()
+			b.WriteString("\n")
+			continue
+		}
+		block = append(block, it)
+	}
+	flush()
+	b.WriteString("}" + withComment("", n.comment) + "\n")
+}
+
+func printEnum(b *bytes.Buffer, e *enumNode) {
+	if !e.multiline {
+		parts := make([]string, len(e.variants))
+		for i, v := range e.variants {
+			parts[i] = joinAnnotations(v.annos, v.text)
+		}
+		last := ""
+		if len(e.variants) > 0 {
+			last = e.variants[len(e.variants)-1].comment
+		}
+		b.WriteString(withComment(e.name+" = "+strings.Join(parts, " | "), last) + "\n")
+		return
+	}
+
+	// 多行: 后续成员的 | 与 = 对齐
+	pad := strings.Repeat(" ", utf8.RuneCountInString(e.name)+1)
+	rows := make([][]string, len(e.variants))
+	for i, v := range e.variants {
+		prefix := e.name + " = "
+		if i > 0 {
+			prefix = pad + "| "
+		}
+		rows[i] = []string{prefix + joinAnnotations(v.annos, v.text), comment(v.comment)}
+	}
+	for _, line := range alignRows(rows) {
+		b.WriteString(line + "\n")
+	}
+}
+
+func printMultilineApi(b *bytes.Buffer, n node) {
+	api := n.api
+	b.WriteString(api.name + "(\n")
+	var rows [][]string
+	for _, it := range api.items {
+		if !it.isComment {
+			rows = append(rows, []string{it.name, it.typ + ",", comment(it.comment)})
+		}
+	}
+	lines := alignRows(rows)
+	for _, it := range api.items {
+		if it.isComment {
+			b.WriteString(indent + comment(it.comment) + "\n")
+			continue
+		}
+		b.WriteString(indent + lines[0] + "\n")
+		lines = lines[1:]
+	}
+	b.WriteString(withComment(") => "+api.result, n.comment) + "\n")
+}
+
+// apiSignature 单行 API 签名: name(a T, b U) => R (! E)
+func apiSignature(api *apiNode) string {
+	var args []string
+	for _, it := range api.items {
+		if !it.isComment {
+			args = append(args, it.name+" "+it.typ)
+		}
+	}
+	return api.name + "(" + strings.Join(args, ", ") + ") => " + api.result
+}
+
+func joinAnnotations(annos []string, text string) string {
+	if len(annos) == 0 {
+		return text
+	}
+	return strings.Join(annos, " ") + " " + text
+}
+
+// comment 规范化注释文本, 空文本返回空串
+func comment(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "// " + text
+}
+
+func withComment(line, c string) string {
+	if c == "" {
+		return line
+	}
+	if line == "" {
+		return " // " + c
+	}
+	return line + " // " + c
+}
+
+// alignRows 按列对齐: 每列宽度取 "该列之后仍有内容" 的行中的最大宽度, 行尾不留空白
+// 所有行均为空的列被丢弃 (如整个结构体都没有 Tag)
+func alignRows(rows [][]string) []string {
+	rows = discardEmptyColumns(rows)
+	var widths []int
+	for _, row := range rows {
+		last := lastNonEmpty(row)
+		for c := 0; c < last; c++ {
+			if c >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[c] = max(widths[c], utf8.RuneCountInString(row[c]))
+		}
+	}
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		last := lastNonEmpty(row)
+		var sb strings.Builder
+		for c := 0; c <= last; c++ {
+			cell := row[c]
+			sb.WriteString(cell)
+			if c < last {
+				sb.WriteString(strings.Repeat(" ", widths[c]-utf8.RuneCountInString(cell)+1))
+			}
+		}
+		lines[i] = strings.TrimRight(sb.String(), " ")
+	}
+	return lines
+}
+
+func discardEmptyColumns(rows [][]string) [][]string {
+	if len(rows) == 0 {
+		return rows
+	}
+	var keep []int
+	for c := range rows[0] {
+		for _, row := range rows {
+			if row[c] != "" {
+				keep = append(keep, c)
+				break
+			}
+		}
+	}
+	out := make([][]string, len(rows))
+	for i, row := range rows {
+		for _, c := range keep {
+			out[i] = append(out[i], row[c])
+		}
+		if out[i] == nil {
+			out[i] = []string{""}
+		}
+	}
+	return out
+}
+
+func lastNonEmpty(row []string) int {
+	for c := len(row) - 1; c >= 0; c-- {
+		if row[c] != "" {
+			return c
+		}
+	}
+	return 0
+}
+
+func isQuoted(v string) bool {
+	return strings.HasPrefix(v, "\"") || strings.HasPrefix(v, "`")
+}
@@ -0,0 +1,145 @@
+package format
+
+import (
+	"os"
+	"reflect"
+	"sb/internal/ast"
+	"sb/internal/lexer"
+	"sb/internal/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	input := `// header
+
+
+User { id u32, name text }
+Admin{ // 管理员
+  User
+
+
+  // 角色
+  role   text "r"   // 角色
+  @deprecated("x") old u8
+} // end
+St=A|@deprecated B|C(5) // last
+Order = Pending //待处理
+  | Closed // 已关闭
+user.search(
+  keyword text, // 关键字
+  // 分页
+  page u8
+) => [User] // 搜索
+@deprecated
+user.get( id u32 ,name text, ) => User
+get_count(page u8) => u8 //数量
+set_count(page u8) => nil!St // 设置
+Empty {}
+`
+	want := `// header
+
+User {
+    id   u32
+    name text
+}
+Admin { // 管理员
+    User
+
+    // 角色
+    role text "r" // 角色
+    @deprecated("x")
+    old  u8
+} // end
+St = A | @deprecated B | C(5) // last
+Order = Pending // 待处理
+      | Closed  // 已关闭
+user.search(
+    keyword text, // 关键字
+    // 分页
+    page    u8,
+) => [User] // 搜索
+@deprecated
+user.get(id u32, name text) => User
+get_count(page u8) => u8       // 数量
+set_count(page u8) => nil ! St // 设置
+Empty {}
+`
+	got, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Source() mismatch\n--- got ---\n%s\n--- want ---\n%s", got, want)
+	}
+
+	again, err := Source(got)
+	if err != nil || string(again) != string(got) {
+		t.Errorf("Source() is not idempotent:\n%s", again)
+	}
+}
+
+func TestSource_SyntaxError(t *testing.T) {
+	if _, err := Source([]byte("user.get(id u32 => nil")); err == nil {
+		t.Error("Expected syntax error")
+	}
+	// 语义错误不阻止格式化
+	if _, err := Source([]byte("User { info Missing }")); err != nil {
+		t.Errorf("Unexpected error for semantic problem: %v", err)
+	}
+}
+
+// TestSource_PreservesSchema 格式化前后解析得到的 Schema 一致
+func TestSource_PreservesSchema(t *testing.T) {
+	src, err := os.ReadFile("../../aaa.sb")
+	if err != nil {
+		t.Skip("aaa.sb not found")
+	}
+	out, err := Source(src)
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+
+	before := parse(t, src)
+	after := parse(t, out)
+	if !reflect.DeepEqual(before, after) {
+		t.Error("Formatted schema differs from original")
+	}
+}
+
+func parse(t *testing.T, src []byte) *ast.Schema {
+	t.Helper()
+	schema, err := parser.New(lexer.New(string(src))).ParseSchema()
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	clearPos(schema)
+	return schema
+}
+
+// clearPos 清除位置信息, 只比较语义内容
+func clearPos(s *ast.Schema) {
+	clearType := func(t *ast.Type) { t.Pos = ast.Pos{} }
+	for i := range s.Structs {
+		st := &s.Structs[i]
+		st.Pos = ast.Pos{}
+		for j := range st.Fields {
+			st.Fields[j].Pos = ast.Pos{}
+			clearType(&st.Fields[j].Type)
+		}
+	}
+	for i := range s.Enums {
+		s.Enums[i].Pos = ast.Pos{}
+		for j := range s.Enums[i].Children {
+			s.Enums[i].Children[j].Pos = ast.Pos{}
+		}
+	}
+	for i := range s.Apis {
+		api := &s.Apis[i]
+		api.Pos = ast.Pos{}
+		clearType(&api.Result)
+		for j := range api.Args {
+			api.Args[j].Pos = ast.Pos{}
+			clearType(&api.Args[j].Type)
+		}
+	}
+}
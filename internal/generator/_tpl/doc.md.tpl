@@ -5,10 +5,10 @@
 
 ## API List
 
-| Name | Arguments | Returns | Description |
-| :--- | :--- | :--- | :--- |
+| Name | Arguments | Returns | Errors | Description |
+| :--- | :--- | :--- | :--- | :--- |
 {{- range .Apis}}
-| {{.Name | SnakeCase}} | {{range .Args}}{{.Name}} {{if .Type.IsList}}[{{end}}{{.Type.Name}}{{if .Type.IsList}}]{{end}}<br>{{end}} | {{if ne .Result.Name "nil"}}{{if .Result.IsList}}[{{end}}{{.Result.Name}}{{if .Result.IsList}}]{{end}}{{else}}Void{{end}} | {{.Note}} |
+| {{if .Deprecated}}~~{{.Name | SnakeCase}}~~{{else}}{{.Name | SnakeCase}}{{end}} | {{range .Args}}{{.Name}} {{if .Type.IsList}}[{{end}}{{.Type.Name}}{{if .Type.IsList}}]{{end}}<br>{{end}} | {{if ne .Result.Name "nil"}}{{if .Result.IsList}}[{{end}}{{.Result.Name}}{{if .Result.IsList}}]{{end}}{{else}}Void{{end}} | {{if .Error}}{{.Error.Name}}{{end}} | {{.Note}}{{if .Idempotent}}<br>**幂等**: 失败时自动重试{{end}}{{if .Deprecated}}<br>**已弃用**: {{DeprecatedReason .Deprecated}}{{end}} |
 {{- end}}
 
 ## RPC Error Codes (HTTP Status)
@@ -21,30 +21,36 @@
 | 401 | NotAuth | 未授权 (登录失效) |
 | 404 | NotExist | 资源不存在 |
 | 408 | Timeout | 请求超时 (含重试耗尽) |
+| 413 | TooLarge | 请求体超过服务端上限 (ServerOptions.MaxBodySize) |
+| 422 | BizErr | 业务错误 (错误码为 API 声明的错误枚举值, 见 Errors 列) |
 | 500 | RespErr | 响应处理错误 (反序列化失败) |
 
+非 200 响应的响应体为结构化错误 `RpcError`，依次为业务错误码 (`i32`, 0 表示未指定)、说明 (`text`)、详情 (`bin`, Schema 中声明的结构体序列化后的内容, 可为空)。
+
 ## Usage Demos
 
 ### Go Client
 ```go
 import (
     "context"
+    "errors"
     "fmt"
-    "your_project/sb"
+    "{{if .GoImport}}{{.GoImport}}{{else}}your_project/{{.GoPackage}}{{end}}"
 )
 
 func main() {
-    client := sb.NewClient("http://localhost:8080")
-    client.Retries = 3 // 默认已是 3 次
+    client := {{.GoPackage}}.NewClient("http://localhost:8080")
+    client.Retries = 3 // 幂等 API 失败后的重试次数, 默认已是 3 次 (指数退避, 见 RetryDelay, RetryCodes)
     
     // Example call
     {{- if .Apis}}
     {{- $api := index .Apis 0}}
     {{- $hasRet := ne $api.Result.Name "nil"}}
-    {{if $hasRet}}res, status{{else}}status{{end}} := client.{{$api.Name | PascalCase}}(context.Background() {{range $api.Args}}, {{GoValue .Type.Name}}{{end}})
+    {{if $hasRet}}res, err{{else}}err{{end}} := client.{{$api.Name | PascalCase}}(context.Background() {{range $api.Args}}, {{GoValue .Type.Name}}{{end}})
     
-    if status != sb.RpcOk {
-        fmt.Printf("Request failed with status: %d\n", status)
+    var rpcErr *{{.GoPackage}}.RpcError
+    if errors.As(err, &rpcErr) {
+        fmt.Printf("Request failed: status %d, code %d, %s\n", rpcErr.Status, rpcErr.Code, rpcErr.Message)
         return
     }
     {{if $hasRet}}fmt.Printf("Result: %+v\n", res){{end}}
@@ -58,18 +64,22 @@ func main() {
 ```go
 import (
     "net/http"
-    "your_project/sb"
+    "{{if .GoImport}}{{.GoImport}}{{else}}your_project/{{.GoPackage}}{{end}}"
 )
+{{- if not .GoFunctions}}
+{{range $module, $pkgApis := .Groups}}
+// {{$module | CamelCase}}Service 实现 {{$.GoPackage}}.{{$module | PascalCase}}Service, 可持有数据库连接等依赖
+type {{$module | CamelCase}}Service struct{}
 
+var _ {{$.GoPackage}}.{{$module | PascalCase}}Service = (*{{$module | CamelCase}}Service)(nil)
+{{end}}
+{{- end}}
 func main() {
     mux := http.NewServeMux()
     
-    // Register API handlers (default middleware is optional)
+    // Register API handlers (nil 使用默认选项; 路径前缀, 请求体上限, 钩子等见 ServerOptions)
     {{- range $module, $pkgApis := .Groups}}
-    sb.Register{{$module | PascalCase}}(mux)
-    {{- end}}
-    {{- if not .Groups}}
-    sb.RegisterApi(mux) 
+    {{$.GoPackage}}.Register{{$module | PascalCase}}(mux{{if not $.GoFunctions}}, &{{$module | CamelCase}}Service{}{{end}}, nil)
     {{- end}}
 
     fmt.Println("Server starting on :8080")
@@ -85,17 +95,17 @@ async function demo() {
     const client = new sb.RpcClient({
         host: "http://localhost:8080",
         timeout: 5000,
-        retries: 3 // 默认已是 3 次
+        retries: 3 // 幂等 API 失败后的重试次数, 默认已是 3 次 (指数退避, 见 retryDelay, retryCodes)
     });
 
     {{- if .Apis}}
     {{- $api := index .Apis 0}}
     {{- $hasRet := ne $api.Result.Name "nil"}}
     // Example: {{$api.Note}}
-    {{if $hasRet}}const [res, status]{{else}}const status{{end}} = await client.{{$api.Name | CamelCase}}({{range $i, $arg := $api.Args}}{{if $i}}, {{end}}{{TsValue .Type.Name}}{{end}});
+    {{if $hasRet}}const [res, err]{{else}}const err{{end}} = await client.{{$api.Name | CamelCase}}({{range $i, $arg := $api.Args}}{{if $i}}, {{end}}{{TsValue .Type.Name}}{{end}});
     
-    if (status !== sb.RpcErrCode.Ok) {
-        console.error("Request failed with status:", status);
+    if (err !== null) {
+        console.error("Request failed:", err.status, err.code, err.message);
         return;
     }
     {{if $hasRet}}console.log("Data:", res);{{end}}
@@ -116,7 +126,7 @@ async function demo() {
 | ID | Name | Description |
 | :--- | :--- | :--- |
 {{- range .Children}}
-| {{.ID}} | {{.Name}} | {{.Note}} |
+| {{.ID}} | {{if .Deprecated}}~~{{.Name}}~~{{else}}{{.Name}}{{end}} | {{.Note}}{{if .Deprecated}}<br>**已弃用**: {{DeprecatedReason .Deprecated}}{{end}} |
 {{- end}}
 
 {{- end}}
@@ -127,11 +137,15 @@ async function demo() {
 {{- range .Structs}}
 #### {{.Name}}
 {{if .Note}}> {{.Note}}{{end}}
+{{- if .Embeds}}
+
+Embeds: {{range $i, $e := .Embeds}}{{if $i}}, {{end}}{{$e.Name}}{{end}}
+{{- end}}
 
 | Field | Type | Description |
 | :--- | :--- | :--- |
 {{- range .Fields}}
-| {{.Name}} | {{if .Type.IsList}}[{{end}}{{.Type.Name}}{{if .Type.IsList}}]{{end}} | {{.Note}} |
+| {{if .Deprecated}}~~{{.Name}}~~{{else}}{{.Name}}{{end}} | {{if .Type.IsList}}[{{end}}{{.Type.Name}}{{if .Type.IsList}}]{{end}} | {{.Note}}{{if .Embedded}}{{if .Note}}<br>{{end}}来自 {{.Embedded}}{{end}}{{if .Deprecated}}<br>**已弃用**: {{DeprecatedReason .Deprecated}}{{end}} |
 {{- end}}
 
 {{- end}}
\ No newline at end of file
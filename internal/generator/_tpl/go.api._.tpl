@@ -2,42 +2,115 @@ package {{.Package}}
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
 )
 
+// --- 服务接口 ---
+{{range $module, $apis := .Groups}}
+// {{$module | PascalCase}}Service {{$module}} 模块的业务逻辑, 实现后通过 Register{{$module | PascalCase}} 注册
+//
+// 返回的 error 为 *RpcError 时连同说明与详情返回给客户端, 为 RpcErrCode 时作为响应状态码, 其他非 nil 错误按 500 处理
+type {{$module | PascalCase}}Service interface {
+{{- range $apis}}
+	// {{GoMethod .}} {{if .Note}}{{.Note}}{{else}}处理 {{.Name}} 请求{{end}}
+	{{- if .Error}}
+	//
+	// 业务错误返回 {{.Error.Name | PascalCase}} 的值, 客户端收到状态码 RpcBizErr 及对应的错误码; 其他错误经 InternalError 包装
+	{{- end}}
+	{{- if .Deprecated}}
+	//
+	// Deprecated: {{DeprecatedReason .Deprecated}}
+	{{- end}}
+	{{GoMethod .}}(ctx context.Context{{range .Args}}, {{.Name | CamelCase | GoParam}} {{GoLogicType .Type}}{{end}}) {{if ne .Result.Name "nil"}}({{GoLogicType .Result}}, {{GoErrorType .}}){{else}}{{GoErrorType .}}{{end}}
+{{- end}}
+}
+{{end}}
 // --- API Handlers ---
 
 {{range .Apis}}
 {{- $resData := .Result -}}
 {{- $handlerName := .Name | PascalCase -}}
-func {{$handlerName}}Handler(w http.ResponseWriter, r *http.Request) {
-	{{- range .Args}}
-	var {{.Name}} {{GoRpcType .Type}}
-	{{- end}}
+// {{$handlerName}}Handler 处理 {{.Name}} 请求, 业务逻辑由 impl.{{GoMethod .}} 实现
+{{- if .Deprecated}}
+//
+// Deprecated: {{DeprecatedReason .Deprecated}}
+{{- end}}
+func {{$handlerName}}Handler(impl {{GoModule .}}Service, opts *ServerOptions) http.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := opts.begin(w, r, {{printf "%q" .Name}})
+		defer c.finish()
+		{{- if .Deprecated}}
+		c.deprecated({{printf "%q" (DeprecatedReason .Deprecated)}})
+		{{- end}}
+		{{- range .Args}}
+		var arg{{.Name | PascalCase}} {{GoRpcType .Type}}
+		{{- end}}
 
-	if !parseRequest(w, r{{range .Args}}, &{{.Name}}{{end}}) { return }
-
-	{{if ne $resData.Name "nil" -}}
-	result, status := {{.Name | SnakeCase}}(r.Context()
-		{{- range $i, $arg := .Args}}, {{if IsBaseType .Type}}{{GoLogicType .Type}}({{.Name}}){{else if IsEnum .Type}}{{if .Type.IsList}}{{.Name}}{{else}}{{PascalCase .Type.Name}}({{.Name}}){{end}}{{else if IsStruct .Type}}&{{.Name}}{{else}}{{.Name}}{{end}}{{end}})
-	if !checkStatus(w, status) { return }
-	{{if or (IsStruct $resData) (IsList $resData) -}}
-	sendResponse(w, result)
-	{{- else if IsEnum $resData -}}
-	sendResponse(w, U8(result))
-	{{- else -}}
-	sendResponse(w, {{PascalCase $resData.Name}}(result))
-	{{- end}}
-	{{- else -}}
-	status := {{.Name | SnakeCase}}(r.Context()
-		{{- range $i, $arg := .Args}}, {{if IsBaseType .Type}}{{GoLogicType .Type}}({{.Name}}){{else if IsEnum .Type}}{{if .Type.IsList}}{{.Name}}{{else}}{{PascalCase .Type.Name}}({{.Name}}){{end}}{{else if IsStruct .Type}}&{{.Name}}{{else}}{{.Name}}{{end}}{{end}})
-	if !checkStatus(w, status) { return }
-	w.WriteHeader(http.StatusOK)
+		if !c.parse({{range $i, $arg := .Args}}{{if $i}}, {{end}}&arg{{.Name | PascalCase}}{{end}}) { return }
+
+		req := &{{$handlerName}}Request{ {{- range $i, $arg := .Args}}{{if $i}}, {{end}}{{.Name | PascalCase}}: {{if IsBaseType .Type}}{{GoLogicType .Type}}(arg{{.Name | PascalCase}}){{else if IsEnum .Type}}{{if .Type.IsList}}arg{{.Name | PascalCase}}{{else}}{{PascalCase .Type.Name}}(arg{{.Name | PascalCase}}){{end}}{{else if IsStruct .Type}}&arg{{.Name | PascalCase}}{{else}}arg{{.Name | PascalCase}}{{end}}{{end -}} }
+		{{if ne $resData.Name "nil"}}result, err := intercept[{{GoLogicType $resData}}]{{else}}_, err := intercept[any]{{end}}(r.Context(), opts.Interceptors, &UnaryInfo{Api: {{printf "%q" .Name}}, Request: r}, req, func(ctx context.Context, req any) (any, error) {
+			{{- if .Args}}
+			in := req.(*{{$handlerName}}Request)
+			{{- end}}
+			{{- if ne $resData.Name "nil"}}
+			result, err := impl.{{GoMethod .}}(ctx{{range .Args}}, in.{{.Name | PascalCase}}{{end}})
+			return result, err
+			{{- else}}
+			return nil, impl.{{GoMethod .}}(ctx{{range .Args}}, in.{{.Name | PascalCase}}{{end}})
+			{{- end}}
+		})
+		if !c.check(declaredError[{{if .Error}}{{.Error.Name | PascalCase}}{{else}}noBizError{{end}}](err)) { return }
+		{{if ne $resData.Name "nil" -}}
+		{{if IsList $resData -}}
+		c.send({{GoRpcType $resData}}(result))
+		{{- else if IsStruct $resData -}}
+		c.send(result)
+		{{- else if IsEnum $resData -}}
+		c.send(U8(result))
+		{{- else -}}
+		c.send({{PascalCase $resData.Name}}(result))
+		{{- end}}
+		{{- else -}}
+		c.ok()
+		{{- end}}
+	}
+}
+
+{{end}}
+{{- if .Functions -}}
+// --- 包内处理函数 (api.<name>.go) 到服务接口的适配 ---
+{{range $module, $apis := .Groups}}
+// {{$module | CamelCase}}Funcs 以包内的处理函数实现 {{$module | PascalCase}}Service
+type {{$module | CamelCase}}Funcs struct{}
+{{range $apis}}
+func ({{$module | CamelCase}}Funcs) {{GoMethod .}}(ctx context.Context{{range .Args}}, {{GoParam .Name}} {{GoLogicType .Type}}{{end}}) {{if ne .Result.Name "nil"}}({{GoLogicType .Result}}, {{GoErrorType .}}){{else}}{{GoErrorType .}}{{end}} {
+	{{- if ne .Result.Name "nil"}}
+	result, code := {{.Name | SnakeCase}}(ctx{{range .Args}}, {{GoParam .Name}}{{end}})
+	return result, codeError[{{GoErrorType .}}](code)
+	{{- else}}
+	return codeError[{{GoErrorType .}}]({{.Name | SnakeCase}}(ctx{{range .Args}}, {{GoParam .Name}}{{end}}))
 	{{- end}}
 }
 {{end}}
+{{- end}}
+// codeError 处理函数返回的状态码转为服务接口的错误, RpcErrCode 满足 error 及各错误枚举的 Fault 接口
+func codeError[F error](code RpcErrCode) F {
+	var f F
+	if code != RpcOk { f = any(code).(F) }
+	return f
+}
 
+{{end -}}
 // --- 路由注册 ---
 
 type Middleware func(http.HandlerFunc) http.HandlerFunc
@@ -49,31 +122,253 @@ func composeMiddleware(mws ...Middleware) func(http.HandlerFunc) http.HandlerFun
 	}
 }
 
+// Router 注册路由的目标, *http.ServeMux 及 chi 等路由器均满足; 处理函数自行校验请求方法 (POST)
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// DefaultMaxBodySize 默认的请求体上限
+const DefaultMaxBodySize = 4 << 20
+
+// ServerOptions 路由注册选项, nil 或零值字段使用默认行为
+type ServerOptions struct {
+	// Prefix 路径前缀, 如 "/rpc/v2"; 客户端的 BaseURL 需包含相同前缀
+	Prefix string
+	// MaxBodySize 请求体上限 (字节), 超过时响应 413; 0 表示 DefaultMaxBodySize, 负数表示不限制
+	MaxBodySize int64
+	// ErrorEncoder 自定义错误响应, 默认以 e.Status 响应并将错误编码在响应体中 (客户端据此解析 RpcError)
+	ErrorEncoder func(w http.ResponseWriter, r *http.Request, e *RpcError)
+	// OnRequest 请求开始时调用
+	OnRequest func(r *http.Request, api string)
+	// OnResponse 响应完成后调用, status 为响应状态码
+	OnResponse func(r *http.Request, api string, status int, elapsed time.Duration)
+	// OnDeprecatedCall 已弃用 API 被调用时调用, 可用于记录日志或上报指标; reason 为弃用说明
+	OnDeprecatedCall func(r *http.Request, api, reason string)
+	// ErrorReporter 内部错误 (panic 及按 500 处理的错误) 的上报, 默认以 Logger 记录
+	ErrorReporter ErrorReporter
+	// Logger 默认错误上报使用的日志, 默认为 slog.Default()
+	Logger *slog.Logger
+	// Middlewares 依次包裹每个 API 的处理函数
+	Middlewares []Middleware
+	// Interceptors 依次包裹每个 API 的业务逻辑, 收到解码后的参数与返回的结果, 见 UnaryInterceptor
+	Interceptors []UnaryInterceptor
+}
+
+// withDefaults 填充默认值, 返回新的选项
+func (o *ServerOptions) withDefaults() *ServerOptions {
+	var c ServerOptions
+	if o != nil { c = *o }
+	c.Prefix = strings.TrimSuffix(c.Prefix, "/")
+	if c.MaxBodySize == 0 { c.MaxBodySize = DefaultMaxBodySize }
+	if c.Logger == nil { c.Logger = slog.Default() }
+	return &c
+}
+
+func (o *ServerOptions) handle(mux Router, api string, h http.HandlerFunc) {
+	mux.Handle(o.Prefix+"/"+api, composeMiddleware(o.Middlewares...)(h))
+}
+{{- if not .Functions}}
+
+// Services 实现全部模块服务接口的类型, 用于 RegisterAll
+type Services interface {
+{{- range $module, $pkgApis := .Groups}}
+	{{$module | PascalCase}}Service
+{{- end}}
+}
+{{- end}}
+
+// RegisterAll 注册全部模块的路由{{if .Functions}}, 业务逻辑为包内的处理函数 (api.<name>.go){{else}}, 请求交由 impl 处理{{end}}; opts 可为 nil
+func RegisterAll(mux Router{{if not .Functions}}, impl Services{{end}}, opts *ServerOptions) {
+{{- range $module, $pkgApis := .Groups}}
+	Register{{$module | PascalCase}}(mux{{if not $.Functions}}, impl{{end}}, opts)
+{{- end}}
+}
 {{range $module, $pkgApis := .Groups}}
-func Register{{$module | PascalCase}}(mux *http.ServeMux, mws ...Middleware) {
-	mw := composeMiddleware(mws...)
+{{- if $.Functions}}
+// Register{{$module | PascalCase}} 注册 {{$module}} 模块的路由, 业务逻辑为包内的处理函数 (api.<name>.go); opts 可为 nil
+func Register{{$module | PascalCase}}(mux Router, opts *ServerOptions) {
+	impl := {{$module | CamelCase}}Funcs{}
+{{- else}}
+// Register{{$module | PascalCase}} 注册 {{$module}} 模块的路由, 请求交由 impl 处理; opts 可为 nil
+func Register{{$module | PascalCase}}(mux Router, impl {{$module | PascalCase}}Service, opts *ServerOptions) {
+{{- end}}
+	opts = opts.withDefaults()
 {{- range $pkgApis}}
-	mux.HandleFunc("POST /{{.Name}}", mw({{.Name | PascalCase}}Handler))
+	opts.handle(mux, "{{.Name}}", {{.Name | PascalCase}}Handler(impl, opts))
 {{- end}}
 }
 {{end}}
+// --- 进程内调用 ---
+
+// LocalTransport 客户端的进程内 Transport: 不经过网络, 直接调用注册的处理函数,
+// 请求同样经过中间件, 拦截器与错误编码。可用于不启动服务端的单元测试, 或单体部署中的模块间调用:
+//
+//	c := NewClient("")
+//	c.Transport = NewLocalTransport({{if not .Functions}}impl, {{end}}nil)
+type LocalTransport struct {
+	// Header 附加到每个请求的请求头, 如 Authorization
+	Header   http.Header
+	prefix   string
+	handlers map[string]http.Handler
+}
+
+// NewLocalTransport 注册全部模块的路由{{if not .Functions}}, 请求交由 impl 处理{{end}}; opts 同 RegisterAll, 可为 nil
+func NewLocalTransport({{if not .Functions}}impl Services, {{end}}opts *ServerOptions) *LocalTransport {
+	t := &LocalTransport{Header: make(http.Header), prefix: opts.withDefaults().Prefix, handlers: make(map[string]http.Handler)}
+	RegisterAll(t{{if not .Functions}}, impl{{end}}, opts)
+	return t
+}
+
+// Handle 实现 Router, 记录路由对应的处理函数
+func (t *LocalTransport) Handle(pattern string, handler http.Handler) { t.handlers[pattern] = handler }
+
+// Call 实现 Transport
+func (t *LocalTransport) Call(ctx context.Context, api string, req []byte) ([]byte, error) {
+	path := t.prefix + "/" + api
+	h, ok := t.handlers[path]
+	if !ok { return nil, NewRpcError(RpcNotExist, 0, "API 不存在: "+api) }
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(req))
+	if err != nil { return nil, rpcError(RpcReqErr, err) }
+	for k, vs := range t.Header {
+		for _, v := range vs { r.Header.Add(k, v) }
+	}
+	w := &localResponse{header: make(http.Header), status: http.StatusOK}
+	h.ServeHTTP(w, r)
+	if w.status != http.StatusOK { return nil, decodeRpcError(RpcErrCode(w.status), w.body.Bytes()) }
+	return w.body.Bytes(), nil
+}
+
+// localResponse 进程内调用的响应
+type localResponse struct {
+	header http.Header
+	status int
+	wrote  bool
+	body   bytes.Buffer
+}
+
+func (w *localResponse) Header() http.Header { return w.header }
+
+func (w *localResponse) WriteHeader(status int) {
+	if !w.wrote { w.status, w.wrote = status, true }
+}
+
+func (w *localResponse) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.body.Write(b)
+}
+
+// --- 错误上报 ---
+
+// ErrorReport 服务端内部错误: 业务逻辑 panic, 或返回了按 500 处理的错误
+type ErrorReport struct {
+	Api   string // API 名称, 如 user.set_sim_info
+	Err   error  // 返回的错误; panic 时为包装了 panic 值的错误
+	Stack []byte // panic 时的调用栈, 否则为 nil
+}
+
+// ErrorReporter 接收服务端内部错误, 通过 ServerOptions 指定
+type ErrorReporter func(r *http.Request, report ErrorReport)
 
 // --- 内部辅助函数 ---
 
-func checkStatus(w http.ResponseWriter, status RpcErrCode) bool {
-	if status == RpcOk { return true }
-	w.WriteHeader(int(status)); return false
+// rpcCall 单次请求的处理上下文
+type rpcCall struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	api    string
+	opts   *ServerOptions
+	start  time.Time
+	status int
 }
 
-func parseRequest(w http.ResponseWriter, r *http.Request, args ...Deserializable) bool {
+func (o *ServerOptions) begin(w http.ResponseWriter, r *http.Request, api string) *rpcCall {
+	if o.OnRequest != nil { o.OnRequest(r, api) }
+	return &rpcCall{w: w, r: r, api: api, opts: o, start: time.Now()}
+}
+
+// finish 捕获业务逻辑的 panic, 上报后以 500 及标准错误响应体响应; 最后调用 OnResponse
+func (c *rpcCall) finish() {
+	if v := recover(); v != nil {
+		if v == http.ErrAbortHandler { panic(v) }
+		err, ok := v.(error)
+		if ok { err = fmt.Errorf("panic: %w", err) } else { err = fmt.Errorf("panic: %v", v) }
+		c.reportError(err, debug.Stack())
+		c.fail(NewRpcError(RpcRespErr, 0, "服务内部错误"))
+	}
+	if c.opts.OnResponse != nil { c.opts.OnResponse(c.r, c.api, c.status, time.Since(c.start)) }
+}
+
+// deprecated 通知已弃用 API 被调用
+func (c *rpcCall) deprecated(reason string) {
+	if c.opts.OnDeprecatedCall != nil { c.opts.OnDeprecatedCall(c.r, c.api, reason) }
+}
+
+func (c *rpcCall) reportError(err error, stack []byte) {
+	if c.opts.ErrorReporter != nil {
+		c.opts.ErrorReporter(c.r, ErrorReport{Api: c.api, Err: err, Stack: stack})
+		return
+	}
+	args := []any{"api", c.api, "err", err}
+	if stack != nil { args = append(args, "stack", string(stack)) }
+	c.opts.Logger.ErrorContext(c.r.Context(), "rpc 请求处理失败", args...)
+}
+
+// noBizError 未声明错误枚举的 API, 不能返回任何业务错误
+type noBizError struct{}
+
+// declaredError 业务错误须为 API 声明的错误枚举 E; 其他错误枚举的值 (如经拦截器返回) 按内部错误处理,
+// 避免客户端按 E 解读错误码
+func declaredError[E any](err error) error {
+	var biz interface{ rpcError() *RpcError }
+	if !errors.As(err, &biz) { return err }
+	if _, ok := biz.(E); ok { return err }
+	return fmt.Errorf("未声明的业务错误 %T: %v", biz, err)
+}
+
+func (c *rpcCall) check(err error) bool {
+	if err == nil { return true }
+	e := AsRpcError(err)
+	if e.Status == RpcRespErr { c.reportError(err, nil) }
+	c.fail(e); return false
+}
+
+// fail 以错误的状态码响应, 响应体为编码后的错误
+func (c *rpcCall) fail(e *RpcError) {
+	c.status = int(e.Status)
+	if c.opts.ErrorEncoder != nil { c.opts.ErrorEncoder(c.w, c.r, e); return }
+	body, err := e.encode()
+	if err != nil { c.status = http.StatusInternalServerError; c.w.WriteHeader(c.status); return }
+	c.w.WriteHeader(c.status)
+	c.w.Write(body)
+}
+
+// parse 校验请求方法并解码参数
+func (c *rpcCall) parse(args ...Deserializable) bool {
+	if c.r.Method != http.MethodPost {
+		c.w.Header().Set("Allow", http.MethodPost)
+		c.fail(NewRpcError(RpcErrCode(http.StatusMethodNotAllowed), 0, "只支持 POST 请求")); return false
+	}
 	if len(args) == 0 { return true }
-	body, err := io.ReadAll(r.Body); if err != nil { w.WriteHeader(http.StatusBadRequest); return false }
-	if err := GetAll(bytes.NewBuffer(body), args...); err != nil { w.WriteHeader(http.StatusBadRequest); return false }
+	body := c.r.Body
+	if c.opts.MaxBodySize > 0 { body = http.MaxBytesReader(c.w, body, c.opts.MaxBodySize) }
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) { c.fail(NewRpcError(RpcTooLarge, 0, "请求体过大")); return false }
+		c.fail(NewRpcError(RpcReqErr, 0, "读取请求失败")); return false
+	}
+	if err := GetAll(bytes.NewBuffer(data), args...); err != nil { c.fail(NewRpcError(RpcReqErr, 0, "请求参数无法解析")); return false }
 	return true
 }
 
-func sendResponse(w http.ResponseWriter, result Serializable) {
+func (c *rpcCall) send(result Serializable) {
 	var buf bytes.Buffer
-	if err := SetAll(&buf, result); err != nil { w.WriteHeader(http.StatusInternalServerError); return }
-	w.Write(buf.Bytes())
+	if err := SetAll(&buf, result); err != nil { c.reportError(err, nil); c.fail(NewRpcError(RpcRespErr, 0, "响应序列化失败")); return }
+	c.status = http.StatusOK
+	c.w.Write(buf.Bytes())
+}
+
+func (c *rpcCall) ok() {
+	c.status = http.StatusOK
+	c.w.WriteHeader(c.status)
 }
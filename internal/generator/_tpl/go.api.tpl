@@ -9,6 +9,8 @@ import (
 	"context"
 )
 
-func {{$innerFuncName}}(ctx context.Context{{range .Api.Args}}, {{.Name}} {{GoLogicType .Type}}{{end}}) ({{if $hasRet}}result {{$retType}}, {{end}}errCode RpcErrCode) {
-	return {{if $hasRet}}{{GoValue .Api.Result.Name}}, {{end}}RpcRespErr
+{{if .Api.Deprecated}}// Deprecated: {{DeprecatedReason .Api.Deprecated}}
+{{end -}}
+func {{$innerFuncName}}(ctx context.Context{{range .Api.Args}}, {{GoParam .Name}} {{GoLogicType .Type}}{{end}}) ({{if $hasRet}}result {{$retType}}, {{end}}errCode RpcErrCode) {
+	return {{if $hasRet}}{{if $resultData.IsList}}nil{{else if IsStruct $resultData}}&{{PascalCase $resultData.Name}}{}{{else}}{{GoValue $resultData.Name}}{{end}}, {{end}}RpcRespErr
 }
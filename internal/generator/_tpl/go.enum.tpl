@@ -13,6 +13,9 @@ type {{$enumName}} uint8
 
 const (
 {{- range .Children}}
+	{{- if .Deprecated}}
+	// Deprecated: {{DeprecatedReason .Deprecated}}
+	{{- end}}
 	{{$enumName}}{{.Name | PascalCase}} {{$enumName}} = {{.ID}} {{if .Note}}// {{.Note}}{{end}}
 {{- end}}
 )
@@ -25,4 +28,32 @@ func (v *{{$enumName}}List) Get(buf *bytes.Buffer) error {
 	return err
 }
 func (v {{$enumName}}List) Eq(other {{$enumName}}List) bool { return slices.Equal(v, other) }
+{{- if index $.ErrorEnums .Name}}
+
+// Error 使 {{$enumName}} 可作为 API 的业务错误返回
+func (v {{$enumName}}) Error() string {
+	switch v {
+	{{- range .Children}}
+	case {{$enumName}}{{.Name | PascalCase}}: return {{if .Note}}{{printf "%q" .Note}}{{else}}"{{$enumName}}.{{.Name | PascalCase}}"{{end}}
+	{{- end}}
+	}
+	return unknownVariant("{{$enumName}}", uint8(v))
+}
+
+// rpcError 业务错误以状态码 RpcBizErr 返回, 错误码为枚举值
+func (v {{$enumName}}) rpcError() *RpcError {
+	return &RpcError{Status: RpcBizErr, Code: int32(v), Message: v.Error()}
+}
+
+// {{$enumName}}Fault 声明 ! {{$enumName}} 的 API 在服务接口中返回的错误: {{$enumName}} 的值 (业务错误), *RpcError 或 RpcErrCode,
+// 其他错误经 InternalError 包装; 其他错误枚举的值无法返回, 由编译器检查
+type {{$enumName}}Fault interface {
+	error
+	{{$enumName | CamelCase}}Fault()
+}
+
+func ({{$enumName}}) {{$enumName | CamelCase}}Fault() {}
+func (*RpcError) {{$enumName | CamelCase}}Fault() {}
+func (RpcErrCode) {{$enumName | CamelCase}}Fault() {}
+{{- end}}
 {{end}}
\ No newline at end of file
@@ -2,9 +2,15 @@ package {{.Package}}
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 )
 
@@ -18,14 +24,190 @@ const (
 	RpcRespErr   RpcErrCode = 500
 	RpcNotAuth   RpcErrCode = 401
 	RpcNotExist  RpcErrCode = 404
+	RpcTooLarge  RpcErrCode = 413 // 请求体超过服务端上限
+	RpcBizErr    RpcErrCode = 422 // 业务错误, 错误码为 API 声明的错误枚举值
 )
 
+// Error 使 RpcErrCode 可直接作为服务接口的错误返回
+func (c RpcErrCode) Error() string { return "rpc status " + strconv.Itoa(int(c)) }
+
+// RpcError 结构化的 RPC 错误。服务接口返回后编码在非 200 响应的响应体中:
+// 业务错误码 (i32), 说明 (text), 详情 (bin, 为 Schema 中声明的结构体序列化后的内容)
+//
+// 客户端方法返回的非 nil 错误均为 *RpcError, 可用 errors.Is(err, RpcNotAuth) 判断状态码
+type RpcError struct {
+	Status  RpcErrCode   // HTTP 状态码, 为 0 或 RpcOk 时按 RpcRespErr 处理
+	Code    int32        // 业务错误码, 0 表示未指定
+	Message string       // 错误说明, 如 "余额不足"
+	Details Serializable // 服务端设置的详情, 可为 nil; 客户端收到的详情使用 DecodeDetails 读取
+	details []byte
+	cause   error // 客户端收到的业务错误对应的错误枚举值, 或 InternalError 包装的原错误
+}
+
+// NewRpcError 创建业务错误, 详情可通过 WithDetails 附加
+func NewRpcError(status RpcErrCode, code int32, message string) *RpcError {
+	return &RpcError{Status: status, Code: code, Message: message}
+}
+
+// InternalError 将错误包装为内部错误 (RpcRespErr), 用于返回类型为 <错误枚举>Fault 的服务接口方法;
+// 原错误交给 ErrorReporter, 不会返回给客户端
+func InternalError(err error) *RpcError { return &RpcError{Status: RpcRespErr, cause: err} }
+
+// WithDetails 附加详情, 返回 e 本身
+func (e *RpcError) WithDetails(details Serializable) *RpcError { e.Details = details; return e }
+
+func (e *RpcError) Error() string {
+	msg := e.Status.Error()
+	if e.Code != 0 { msg += " code " + strconv.Itoa(int(e.Code)) }
+	if e.Message != "" { msg += ": " + e.Message } else if e.cause != nil { msg += ": " + e.cause.Error() }
+	return msg
+}
+
+// Unwrap 返回状态码及业务错误的枚举值, 使 errors.Is(err, RpcNotAuth) 和 errors.As(err, &simErr) 成立
+func (e *RpcError) Unwrap() []error {
+	if e.cause != nil { return []error{e.Status, e.cause} }
+	return []error{e.Status}
+}
+
+// HasDetails 响应中是否带有详情
+func (e *RpcError) HasDetails() bool { return len(e.details) > 0 || e.Details != nil }
+
+// DecodeDetails 将客户端收到的详情解码到 v (Schema 中声明的结构体)
+func (e *RpcError) DecodeDetails(v Deserializable) error {
+	return GetAll(bytes.NewBuffer(e.details), v)
+}
+
+// AsRpcError 将服务接口返回的错误转换为 *RpcError: RpcError 原样返回, RpcErrCode 转为对应状态码,
+// 其他错误按 RpcRespErr 处理且不向客户端暴露其说明
+func AsRpcError(err error) *RpcError {
+	var biz interface{ rpcError() *RpcError }
+	if errors.As(err, &biz) {
+		return biz.rpcError()
+	}
+	var e *RpcError
+	if errors.As(err, &e) {
+		if e.Status == 0 || e.Status == RpcOk {
+			c := *e
+			c.Status = RpcRespErr
+			return &c
+		}
+		return e
+	}
+	var code RpcErrCode
+	if !errors.As(err, &code) || code == RpcNoConn || code == RpcOk { code = RpcRespErr }
+	return &RpcError{Status: code}
+}
+
+// encode 编码为响应体
+func (e *RpcError) encode() ([]byte, error) {
+	details := e.details
+	if e.Details != nil {
+		var buf bytes.Buffer
+		if err := SetAll(&buf, e.Details); err != nil { return nil, err }
+		details = buf.Bytes()
+	}
+	var buf bytes.Buffer
+	if err := SetAll(&buf, I32(e.Code), Text(e.Message), Bin(details)); err != nil { return nil, err }
+	return buf.Bytes(), nil
+}
+
+// decodeRpcError 解析非 200 响应; 响应体为空或无法解析时 (如代理返回的错误) 只保留状态码
+func decodeRpcError(status RpcErrCode, body []byte) *RpcError {
+	e := &RpcError{Status: status}
+	var code I32
+	var message Text
+	var details Bin
+	if err := GetAll(bytes.NewBuffer(body), &code, &message, &details); err == nil {
+		e.Code, e.Message, e.details = int32(code), string(message), details
+	}
+	return e
+}
+
+func rpcError(status RpcErrCode, err error) error {
+	return &RpcError{Status: status, Message: err.Error()}
+}
+
+// bizError 为业务错误附加 API 声明的错误枚举值
+func bizError[E interface{ ~uint8; error }](err error) error {
+	var e *RpcError
+	if errors.As(err, &e) && e.Status == RpcBizErr { e.cause = E(e.Code) }
+	return err
+}
+
+func unknownVariant(enum string, v uint8) string { return enum + "(" + strconv.Itoa(int(v)) + ")" }
+
+// --- 拦截器 ---
+
+// UnaryInfo 拦截器收到的调用信息
+type UnaryInfo struct {
+	Api     string        // API 名称, 如 user.set_sim_info
+	Request *http.Request // 服务端收到的 HTTP 请求 (可读取请求头等); 客户端为 nil
+}
+
+// UnaryHandler 调用下一个拦截器, 最终执行业务逻辑 (服务端) 或发送请求 (客户端)。
+// req 为 API 对应的 *<Api>Request, 返回值为 API 的结果, 无返回值的 API 为 nil
+type UnaryHandler func(ctx context.Context, req any) (any, error)
+
+// UnaryInterceptor 一元拦截器, 可用于日志, 鉴权, 指标, 缓存等, 通过 ServerOptions.Interceptors 或 Client.Interceptors 指定。
+// 可修改 ctx 与 req (类型须保持不变) 后调用 next, 也可不调用 next 直接返回结果或错误
+type UnaryInterceptor func(ctx context.Context, info *UnaryInfo, req any, next UnaryHandler) (any, error)
+
+// intercept 依次经过拦截器后调用 h, 并将结果转换为 API 的结果类型 T
+func intercept[T any](ctx context.Context, interceptors []UnaryInterceptor, info *UnaryInfo, req any, h UnaryHandler) (T, error) {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next, ic := h, interceptors[i]
+		h = func(ctx context.Context, req any) (any, error) { return ic(ctx, info, req, next) }
+	}
+	res, err := h(ctx, req)
+	v, ok := res.(T)
+	if !ok && res != nil {
+		return v, fmt.Errorf("%s: 拦截器返回的结果类型 %T 与 API 不符", info.Api, res)
+	}
+	return v, err
+}
+
+// --- 请求参数 ---
+{{range .Apis}}
+// {{.Name | PascalCase}}Request {{.Name}} 的参数, 为拦截器收到的 req
+type {{.Name | PascalCase}}Request struct {
+{{- range .Args}}
+	{{.Name | PascalCase}} {{GoLogicType .Type}}
+{{- end}}
+}
+{{end}}
+// 重试策略的默认值, 见 Client
+const (
+	DefaultRetryDelay    = 500 * time.Millisecond
+	DefaultRetryMaxDelay = 10 * time.Second
+)
+
+// DefaultRetryCodes 默认触发重试的状态码: 无法连接, 超时, 以及网关错误与服务暂不可用 (502, 503, 504)
+var DefaultRetryCodes = []RpcErrCode{RpcNoConn, RpcTimeout, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// Transport 客户端发送请求的方式, api 为 API 名称 (如 user.get_abcd), req 与返回值为编码后的参数与结果。
+// 失败时应返回 *RpcError, Client 据其状态码判断是否重试; 其他错误按 RpcNoConn 处理
+type Transport interface {
+	Call(ctx context.Context, api string, req []byte) ([]byte, error)
+}
+
 type Client struct {
-	BaseURL string
-	HTTP    *http.Client
-	Timeout time.Duration
+	// Transport 发送请求的方式, nil 表示以 HTTP POST 发送到 BaseURL + "/" + api (使用 HTTP 与请求头);
+	// 进程内调用见 LocalTransport
+	Transport Transport
+	BaseURL   string
+	HTTP      *http.Client
+	Timeout   time.Duration
+	// Retries 幂等 API (@idempotent) 失败后的最大重试次数; 其他 API 不自动重试, 避免重复执行
 	Retries int
-	headers map[string]string
+	// RetryDelay 首次重试前的等待, 之后每次翻倍直至 RetryMaxDelay, 实际等待在其一半到全部之间随机;
+	// 0 表示 DefaultRetryDelay 与 DefaultRetryMaxDelay
+	RetryDelay    time.Duration
+	RetryMaxDelay time.Duration
+	// RetryCodes 触发重试的状态码, nil 表示 DefaultRetryCodes
+	RetryCodes []RpcErrCode
+	// Interceptors 依次包裹每次调用, 见 UnaryInterceptor
+	Interceptors []UnaryInterceptor
+	headers      map[string]string
 }
 
 func NewClient(baseURL string) *Client {
@@ -54,81 +236,120 @@ func isTimeout(err error) bool {
 	return false
 }
 
-func (c *Client) do(ctx context.Context, path string, body []byte) ([]byte, RpcErrCode) {
-	var resp *http.Response
-	var err error
-
-	for i := 0; i <= c.Retries; i++ {
+// do 经 Transport 发送请求; idempotent 为 true 时按重试策略重试可重试的错误
+func (c *Client) do(ctx context.Context, api string, body []byte, idempotent bool) ([]byte, error) {
+	var t Transport = httpTransport{c}
+	if c.Transport != nil { t = c.Transport }
+	retries := 0
+	if idempotent { retries = c.Retries }
+	for i := 0; ; i++ {
 		if i > 0 {
-			timer := time.NewTimer(time.Duration(i) * time.Second)
+			timer := time.NewTimer(c.backoff(i))
 			select {
 			case <-ctx.Done():
 				timer.Stop()
-				return nil, RpcTimeout
+				return nil, rpcError(RpcTimeout, ctx.Err())
 			case <-timer.C:
 			}
 		}
+		b, err := t.Call(ctx, api, body)
+		var e *RpcError
+		if err != nil && !errors.As(err, &e) { err = rpcError(RpcNoConn, err) }
+		if err == nil || i >= retries || !c.retryable(err) { return b, err }
+	}
+}
 
-		req, reqErr := http.NewRequestWithContext(ctx, "POST", c.BaseURL+path, bytes.NewReader(body))
-		if reqErr != nil {
-			return nil, RpcNoConn
-		}
-
-		for k, v := range c.headers {
-			req.Header.Set(k, v)
-		}
+// httpTransport 默认的 Transport: 以 Client 的 BaseURL, HTTP 与请求头发送 POST 请求
+type httpTransport struct{ c *Client }
 
-		resp, err = c.HTTP.Do(req)
-		if err != nil {
-			if isTimeout(err) && i < c.Retries {
-				continue
-			}
-			if isTimeout(err) {
-				return nil, RpcTimeout
-			}
-			return nil, RpcNoConn
-		}
+func (t httpTransport) Call(ctx context.Context, api string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", t.c.BaseURL+"/"+api, bytes.NewReader(body))
+	if err != nil {
+		return nil, rpcError(RpcNoConn, err)
+	}
+	for k, v := range t.c.headers {
+		req.Header.Set(k, v)
+	}
 
-		if resp.StatusCode == http.StatusRequestTimeout && i < c.Retries {
-			resp.Body.Close()
-			continue
+	resp, err := t.c.HTTP.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, rpcError(RpcTimeout, err)
 		}
-		break
+		return nil, rpcError(RpcNoConn, err)
 	}
 	defer resp.Body.Close()
 
+	b, err := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return nil, RpcErrCode(resp.StatusCode)
+		return nil, decodeRpcError(RpcErrCode(resp.StatusCode), b)
 	}
+	if err != nil {
+		return nil, rpcError(RpcRespErr, err)
+	}
+	return b, nil
+}
+
+func (c *Client) retryable(err error) bool {
+	codes := c.RetryCodes
+	if codes == nil { codes = DefaultRetryCodes }
+	var e *RpcError
+	return errors.As(err, &e) && slices.Contains(codes, e.Status)
+}
 
-	b, _ := io.ReadAll(resp.Body)
-	return b, RpcOk
+// backoff 第 n 次重试前的等待: 指数增长, 并在 [d/2, d] 内随机抖动, 避免大量客户端同时重试
+func (c *Client) backoff(n int) time.Duration {
+	d, limit := cmp.Or(c.RetryDelay, DefaultRetryDelay), cmp.Or(c.RetryMaxDelay, DefaultRetryMaxDelay)
+	for ; n > 1 && d < limit; n-- { d *= 2 }
+	d = min(d, limit)
+	return d/2 + rand.N(d/2+1)
 }
 
 {{range .Apis}}
 {{- $resData := .Result -}}
 // {{.Name | PascalCase}} {{.Note}}
-func (c *Client) {{.Name | PascalCase}}(ctx context.Context{{range .Args}}, {{.Name | CamelCase}} {{GoLogicType .Type}}{{end}}) ({{if eq $resData.Name "nil"}}errCode RpcErrCode{{else}}result {{GoLogicType .Result}}, errCode RpcErrCode{{end}}) {
-	{{if ne $resData.Name "nil"}}var res {{GoRpcType $resData}}{{end}}
-	var buf bytes.Buffer
-	{{- if .Args}}
-	if err := SetAll(&buf{{range .Args}}, {{if or (IsBaseType .Type) (IsEnum .Type)}}{{GoRpcType .Type}}({{.Name | CamelCase}}){{else if IsStruct .Type}}{{.Name | CamelCase}}{{else}}{{.Name | CamelCase}}{{end}}{{end}}); err != nil {
-		return {{if eq $resData.Name "nil"}}RpcReqErr{{else}}{{if or (IsBaseType $resData) (IsEnum $resData)}}{{GoLogicType $resData}}(res){{else}}res{{end}}, RpcReqErr{{end}}
-	}
-	{{- end}}
+{{- if .Error}}
+//
+// 业务错误可用 errors.As(err, &e) 取出, e 的类型为 {{.Error.Name | PascalCase}}
+{{- end}}
+{{- if .Idempotent}}
+//
+// 幂等 API, 失败时按 Client 的重试策略自动重试
+{{- end}}
+{{- if .Deprecated}}
+//
+// Deprecated: {{DeprecatedReason .Deprecated}}
+{{- end}}
+func (c *Client) {{.Name | PascalCase}}(ctx context.Context{{range .Args}}, {{.Name | CamelCase | GoParam}} {{GoLogicType .Type}}{{end}}) ({{if eq $resData.Name "nil"}}err error{{else}}result {{GoLogicType .Result}}, err error{{end}}) {
+	req := &{{.Name | PascalCase}}Request{ {{- range $i, $arg := .Args}}{{if $i}}, {{end}}{{.Name | PascalCase}}: {{.Name | CamelCase | GoParam}}{{end -}} }
+	{{if eq $resData.Name "nil"}}_, err = intercept[any]{{else}}return intercept[{{GoLogicType $resData}}]{{end}}(ctx, c.Interceptors, &UnaryInfo{Api: "{{.Name}}"}, req, func(ctx context.Context, req any) (any, error) {
+		{{- if .Args}}
+		in := req.(*{{.Name | PascalCase}}Request)
+		{{- end}}
+		var buf bytes.Buffer
+		{{- if .Args}}
+		if err := SetAll(&buf{{range .Args}}, {{if or (IsBaseType .Type) (IsEnum .Type)}}{{GoRpcType .Type}}(in.{{.Name | PascalCase}}){{else}}in.{{.Name | PascalCase}}{{end}}{{end}}); err != nil {
+			return nil, rpcError(RpcReqErr, err)
+		}
+		{{- end}}
 
-	{{if eq $resData.Name "nil"}}_{{else}}body{{end}}, status := c.do(ctx, "/{{.Name}}", buf.Bytes())
-	if status != RpcOk {
-		return {{if eq $resData.Name "nil"}}status{{else}}{{if or (IsBaseType $resData) (IsEnum $resData)}}{{GoLogicType $resData}}(res){{else}}res{{end}}, status{{end}}
-	}
+		{{if eq $resData.Name "nil"}}_, err := {{else}}body, err := {{end}}c.do(ctx, "{{.Name}}", buf.Bytes(), {{.Idempotent}})
+		if err != nil {
+			return nil, {{if .Error}}bizError[{{.Error.Name | PascalCase}}](err){{else}}err{{end}}
+		}
+		{{- if ne $resData.Name "nil"}}
 
-	{{if ne $resData.Name "nil" -}}
-	if err := GetAll(bytes.NewBuffer(body), {{if or (IsStruct $resData) (IsList $resData)}}&res{{else if IsEnum $resData}}(*U8)(&res){{else}}&res{{end}}); err != nil {
-		return {{if or (IsBaseType $resData) (IsEnum $resData)}}{{GoLogicType $resData}}(res){{else}}res{{end}}, RpcRespErr
-	}
-	return {{if or (IsBaseType $resData) (IsEnum $resData)}}{{GoLogicType $resData}}(res){{else}}res{{end}}, status
-	{{- else -}}
-	return status
+		var res {{GoRpcType $resData}}
+		if err := GetAll(bytes.NewBuffer(body), &res); err != nil {
+			return nil, rpcError(RpcRespErr, err)
+		}
+		return {{if and (IsStruct $resData) (not $resData.IsList)}}&res{{else}}{{GoLogicType $resData}}(res){{end}}, nil
+		{{- else}}
+		return nil, nil
+		{{- end}}
+	})
+	{{- if eq $resData.Name "nil"}}
+	return err
 	{{- end}}
 }
 {{end}}
\ No newline at end of file
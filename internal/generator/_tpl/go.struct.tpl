@@ -4,7 +4,7 @@
 		{{- $needUnsafe = true -}}
 	{{- end -}}
 {{- end -}}
-package sb
+package {{.Package}}
 
 import (
 	"bytes"
@@ -15,8 +15,16 @@ import (
 )
 
 type {{.Name | PascalCase}} struct {
+	{{- range .Embeds}}
+	{{.Name | PascalCase}} {{GoEmbedTag}}
+	{{- end}}
 	{{- range .Fields}}
-	{{.Name | PascalCase}} {{GoLogicType .Type}} {{GoTag .}} {{if .Note}}// {{.Note}}{{end}}
+	{{- if not .Embedded}}
+	{{- if .Deprecated}}
+	// Deprecated: {{DeprecatedReason .Deprecated}}
+	{{- end}}
+	{{.Name | PascalCase}} {{GoLogicType .Type}} {{GoTag .StructField}} {{if .Note}}// {{.Note}}{{end}}
+	{{- end}}
 	{{- end}}
 }
 
@@ -28,32 +36,32 @@ func (s *{{.Name | PascalCase}}) Get(buf *bytes.Buffer) error {
 
 	{{- range $i, $field := .Fields}}
 	{{- if eq .Type.Name "bool"}}
-	s.{{$field.Name | PascalCase}} = GetBit(bits, uint8({{$i}}))
+	s.{{$field.Path}} = GetBit(bits, uint8({{$i}}))
 	{{- else}}
 	if GetBit(bits, uint8({{$i}})) {
 		{{- if IsBaseType .Type}}
 		val, err := Get{{.Type.Name | PascalCase}}{{if .Type.IsList}}List{{end}}(buf)
 		if err != nil { return fmt.Errorf("Get{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
-		s.{{$field.Name | PascalCase}} = val
+		s.{{$field.Path}} = val
 		{{- else}}
 		{{- if IsEnum .Type}}
 		{{- if .Type.IsList}}
 		val, err := GetU8List(buf)
 		if err != nil { return fmt.Errorf("Get{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
-		s.{{$field.Name | PascalCase}} = *(*[]{{.Type.Name | PascalCase}})(unsafe.Pointer(&val))
+		s.{{$field.Path}} = *(*[]{{.Type.Name | PascalCase}})(unsafe.Pointer(&val))
 		{{- else}}
 		val, err := GetU8(buf)
 		if err != nil { return fmt.Errorf("Get{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
-		s.{{$field.Name | PascalCase}} = {{.Type.Name | PascalCase}}(val)
+		s.{{$field.Path}} = {{.Type.Name | PascalCase}}(val)
 		{{- end}}
 		{{- else}}
 		{{- if .Type.IsList}}
 		var val {{.Type.Name | PascalCase}}List
 		if err := val.Get(buf); err != nil { return fmt.Errorf("Get{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
-		s.{{$field.Name | PascalCase}} = val
+		s.{{$field.Path}} = val
 		{{- else}}
-		if s.{{$field.Name | PascalCase}} == nil { s.{{$field.Name | PascalCase}} = new({{.Type.Name | PascalCase}}) }
-		if err := s.{{$field.Name | PascalCase}}.Get(buf); err != nil { return fmt.Errorf("Get{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
+		if s.{{$field.Path}} == nil { s.{{$field.Path}} = new({{.Type.Name | PascalCase}}) }
+		if err := s.{{$field.Path}}.Get(buf); err != nil { return fmt.Errorf("Get{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
 		{{- end}}
 		{{- end}}
 		{{- end}}
@@ -70,42 +78,42 @@ func (s *{{.Name | PascalCase}}) Set(buf *bytes.Buffer) error {
 
 	{{- range $i, $field := .Fields}}
 	{{- if eq .Type.Name "bool"}}
-	SetBit(bits, uint8({{$i}}), s.{{$field.Name | PascalCase}})
+	SetBit(bits, uint8({{$i}}), s.{{$field.Path}})
 	{{- else}}
 	{{- if IsBaseType .Type}}
 	{{- if .Type.IsList}}
-	if len(s.{{$field.Name | PascalCase}}) > 0 {
-		if err := Set{{.Type.Name | PascalCase}}List(body, s.{{$field.Name | PascalCase}}); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
+	if len(s.{{$field.Path}}) > 0 {
+		if err := Set{{.Type.Name | PascalCase}}List(body, s.{{$field.Path}}); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
 		SetBit(bits, uint8({{$i}}), true)
 	}
 	{{- else}}
-	if s.{{$field.Name | PascalCase}} != {{GoValue .Type.Name}} {
-		if err := Set{{.Type.Name | PascalCase}}(body, s.{{$field.Name | PascalCase}}); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
+	if s.{{$field.Path}} != {{GoValue .Type.Name}} {
+		if err := Set{{.Type.Name | PascalCase}}(body, s.{{$field.Path}}); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
 		SetBit(bits, uint8({{$i}}), true)
 	}
 	{{- end}}
 	{{- else}}
 	{{- if IsEnum .Type}}
 	{{- if .Type.IsList}}
-	if len(s.{{$field.Name | PascalCase}}) > 0 {
-		if err := SetU8List(body, *(*[]uint8)(unsafe.Pointer(&s.{{$field.Name | PascalCase}}))); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
+	if len(s.{{$field.Path}}) > 0 {
+		if err := SetU8List(body, *(*[]uint8)(unsafe.Pointer(&s.{{$field.Path}}))); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
 		SetBit(bits, uint8({{$i}}), true)
 	}
 	{{- else}}
-	if s.{{$field.Name | PascalCase}} != 0 {
-		if err := SetU8(body, uint8(s.{{$field.Name | PascalCase}})); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
+	if s.{{$field.Path}} != 0 {
+		if err := SetU8(body, uint8(s.{{$field.Path}})); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
 		SetBit(bits, uint8({{$i}}), true)
 	}
 	{{- end}}
 	{{- else}}
 	{{- if .Type.IsList}}
-	if len(s.{{$field.Name | PascalCase}}) > 0 {
-		if err := ({{.Type.Name | PascalCase}}List)(s.{{$field.Name | PascalCase}}).Set(body); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
+	if len(s.{{$field.Path}}) > 0 {
+		if err := ({{.Type.Name | PascalCase}}List)(s.{{$field.Path}}).Set(body); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
 		SetBit(bits, uint8({{$i}}), true)
 	}
 	{{- else}}
-	if s.{{$field.Name | PascalCase}} != nil {
-		if err := s.{{$field.Name | PascalCase}}.Set(body); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
+	if s.{{$field.Path}} != nil {
+		if err := s.{{$field.Path}}.Set(body); err != nil { return fmt.Errorf("Set{{$.Name | PascalCase}} {{.Name | PascalCase}}: %w", err) }
 		SetBit(bits, uint8({{$i}}), true)
 	}
 	{{- end}}
@@ -123,19 +131,19 @@ func (s *{{.Name | PascalCase}}) Eq(other *{{.Name | PascalCase}}) bool {
 	if s == nil || other == nil { return false }
 	{{- range .Fields}}
 	{{- if IsBaseType .Type}}
-	if !Eq{{.Type.Name | PascalCase}}{{if .Type.IsList}}List{{end}}(s.{{.Name | PascalCase}}, other.{{.Name | PascalCase}}) { return false }
+	if !Eq{{.Type.Name | PascalCase}}{{if .Type.IsList}}List{{end}}(s.{{.Path}}, other.{{.Path}}) { return false }
 	{{- else}}
 	{{- if IsEnum .Type}}
 	{{- if .Type.IsList}}
-	if !slices.Equal(s.{{.Name | PascalCase}}, other.{{.Name | PascalCase}}) { return false }
+	if !slices.Equal(s.{{.Path}}, other.{{.Path}}) { return false }
 	{{- else}}
-	if s.{{.Name | PascalCase}} != other.{{.Name | PascalCase}} { return false }
+	if s.{{.Path}} != other.{{.Path}} { return false }
 	{{- end}}
 	{{- else}}
 	{{- if .Type.IsList}}
-	if !({{.Type.Name | PascalCase}}List)(s.{{.Name | PascalCase}}).Eq(({{.Type.Name | PascalCase}}List)(other.{{.Name | PascalCase}})) { return false }
+	if !({{.Type.Name | PascalCase}}List)(s.{{.Path}}).Eq(({{.Type.Name | PascalCase}}List)(other.{{.Path}})) { return false }
 	{{- else}}
-	if !s.{{.Name | PascalCase}}.Eq(other.{{.Name | PascalCase}}) { return false }
+	if !s.{{.Path}}.Eq(other.{{.Path}}) { return false }
 	{{- end}}
 	{{- end}}
 	{{- end}}
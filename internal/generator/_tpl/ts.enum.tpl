@@ -2,6 +2,9 @@
 {{if .Note}}// {{.Note}}{{end}}
 export enum {{.Name | PascalCase}} {
 {{- range .Children}}
+{{- if .Deprecated}}
+    /** @deprecated {{DeprecatedReason .Deprecated}} */
+{{- end}}
     {{.Name | PascalCase}} = {{.ID}}, {{if .Note}}// {{.Note}}{{end}}
 {{- end}}
 }
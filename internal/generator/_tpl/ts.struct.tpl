@@ -1,10 +1,15 @@
 import * as _ from "./_.ts"
 import * as Enum from "./enum"
 
-export interface {{.Name | PascalCase}} extends _.Serializable, _.Deserializable {
+export interface {{.Name | PascalCase}} extends {{range .Embeds}}_.{{.Name | PascalCase}}, {{end}}_.Serializable, _.Deserializable {
     {{- range .Fields}}
+    {{- if not .Embedded}}
+    {{- if .Deprecated}}
+    /** @deprecated {{DeprecatedReason .Deprecated}} */
+    {{- end}}
     {{.Name | CamelCase}}: {{if .Type.IsList}}{{if IsEnum .Type}}Enum.{{end}}{{if not (IsBaseType .Type)}}{{if not (IsEnum .Type)}}_.{{end}}{{end}}{{TsType .Type}}[]{{else}}{{if IsEnum .Type}}Enum.{{end}}{{if not (IsBaseType .Type)}}{{if not (IsEnum .Type)}}_.{{end}}{{end}}{{TsType .Type}}{{end}};
     {{- end}}
+    {{- end}}
 }
 
 export const new{{.Name | PascalCase}} = (): {{.Name | PascalCase}} => {
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"sb/internal/ast"
+	"sb/internal/util"
+	"text/template"
+)
+
+// DocGenerator 生成 DOC.md
+type DocGenerator struct {
+	Config  Config
+	Out     string                  // 输出目录; 为空时写入各代码目标的目录
+	Targets map[string]TargetConfig // 本次启用的全部目标, 用于确定示例中的 Go 包与输出位置
+}
+
+// OutputDir 指定了输出目录时返回该目录; 否则文档与代码放在一起, 不单独占用目录
+func (g *DocGenerator) OutputDir(schema *ast.Schema) (string, error) {
+	return g.Out, nil
+}
+
+func (g *DocGenerator) Generate(schema *ast.Schema) error {
+	dirs := []string{g.Out}
+	if g.Out == "" {
+		var err error
+		if dirs, err = codeDirs(schema, g.Targets); err != nil {
+			return err
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	tplContent, err := fs.ReadFile(g.Config.TplFS, "_tpl/doc.md.tpl")
+	if err != nil {
+		return err
+	}
+
+	pkg, err := ResolveGoPackage(goConfig(g.Targets["go"]), schema)
+	if err != nil {
+		return err
+	}
+
+	tpl, err := template.New("doc").Funcs(g.funcMap()).Parse(string(tplContent))
+	if err != nil {
+		return err
+	}
+
+	data := map[string]any{
+		"Apis":      schema.Apis,
+		"Enums":     schema.Enums,
+		"Structs":   schema.Structs,
+		"Note":      schema.Note,
+		"Groups":    groupApis(schema.Apis),
+		"GoPackage": pkg.Name,
+		"GoImport":  pkg.Import,
+		// 业务逻辑为包内处理函数时, 示例中注册路由不需要传入实现
+		"GoFunctions": g.Targets["go"].Options["server"] == ServerFunctions,
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		if err := g.Config.output().WriteFile(filepath.Join(dir, "DOC.md"), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcMap 文档模板函数; 示例值沿用 Go 与 TypeScript 生成器的写法
+func (g *DocGenerator) funcMap() template.FuncMap {
+	dummyCfg := Config{TplFS: g.Config.TplFS}
+	goGen := NewGoGenerator(dummyCfg)
+	tsGen := NewTsGenerator(dummyCfg)
+
+	return template.FuncMap{
+		"SnakeCase":        util.SnakeCase,
+		"PascalCase":       util.PascalCase,
+		"CamelCase":        util.CamelCase,
+		"GoValue":          goGen.FuncMap["GoValue"],
+		"TsValue":          tsGen.FuncMap["TsValue"],
+		"DeprecatedReason": DeprecatedReason,
+	}
+}
+
+// groupApis 按模块 (名称中第一个点之前的部分) 分组, 见 apiModule
+func groupApis(apis []ast.Api) map[string][]ast.Api {
+	groups := make(map[string][]ast.Api)
+	for _, api := range apis {
+		module, _ := apiModule(api.Name)
+		groups[module] = append(groups[module], api)
+	}
+	return groups
+}
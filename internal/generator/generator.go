@@ -1,19 +1,58 @@
 package generator
 
 import (
+	"io/fs"
 	"sb/internal/ast"
-	"embed"
+	"sb/internal/diag"
 )
 
 // Config 代码生成配置
 type Config struct {
-	GoDir string   // Go 代码输出目录
-	TsDir string   // TypeScript 代码输出目录
-	GoTag string   // 附加的 Go struct tag (如 "bson,json")
-	TplFS embed.FS // 嵌入的模板文件系统
+	GoDir string // Go 代码输出目录
+	TsDir string // TypeScript 代码输出目录
+	GoTag string // 附加的 Go struct tag (如 "bson,json")
+	// 以下三项为空时依次取 Schema 中的 sb:go-package 指令与默认值, 见 ResolveGoPackage
+	GoPackage string // Go 包名
+	GoSubdir  string // Go 代码输出子目录 (相对 GoDir)
+	GoImport  string // 生成包的完整导入路径
+
+	GoServer   string // 服务端业务逻辑的组织方式 (ServerInterface / ServerFunctions), 为空时生成服务接口
+	GoHandlers string // 处理函数签名与 Schema 不一致时的处理方式 (HandlersRewrite / HandlersReport), 为空时改写
+	TplFS      fs.FS  // 模板文件系统 (内置的 TplFS, 或经 Overlay 覆盖)
+	Output     Output // 生成结果的写入目标, 为 nil 时直接写入磁盘
+
+	File string    // Schema 文件路径, 用于诊断
+	Lang diag.Lang // 诊断信息语言, 为空时使用中文
+}
+
+// output 生成结果的写入目标
+func (c Config) output() Output {
+	if c.Output == nil {
+		return Disk
+	}
+	return c.Output
 }
 
 // Generator 代码生成器接口
 type Generator interface {
 	Generate(schema *ast.Schema) error
 }
+
+// DeprecatedReason 返回弃用说明, 未提供原因时使用默认文案
+func DeprecatedReason(d *ast.Deprecation) string {
+	if d == nil || d.Reason == "" {
+		return "该定义已弃用, 请勿在新代码中使用。"
+	}
+	return d.Reason
+}
+
+// errorEnums 作为 API 错误类型 (=> R ! E) 使用的枚举
+func errorEnums(schema *ast.Schema) map[string]bool {
+	enums := make(map[string]bool)
+	for _, api := range schema.Apis {
+		if api.Error != nil {
+			enums[api.Error.Name] = true
+		}
+	}
+	return enums
+}
@@ -3,9 +3,14 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"go/token"
+	"go/types"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sb/internal/ast"
+	"sb/internal/names"
 	"sb/internal/util"
 	"strings"
 	"text/template"
@@ -15,28 +20,58 @@ import (
 type GoGenerator struct {
 	Config  Config
 	FuncMap template.FuncMap
+
+	notices []string
 }
 
 func NewGoGenerator(cfg Config) *GoGenerator {
 	g := &GoGenerator{Config: cfg}
 	g.FuncMap = template.FuncMap{
-		"PascalCase":  util.PascalCase,
-		"SnakeCase":   util.SnakeCase,
-		"CamelCase":   util.CamelCase,
-		"GoType":      g.getGoType,
-		"GoValue":     g.getGoValue,
-		"GoTag":       g.getGoTag,
-		"GoLogicType": g.getGoLogicType,
-		"GoRpcType":   g.getGoRpcType,
-		"IsBaseType":  func(t ast.Type) bool { return t.Kind == ast.KindBase },
-		"IsEnum":      func(t ast.Type) bool { return t.Kind == ast.KindEnum },
-		"IsStruct":    func(t ast.Type) bool { return t.Kind == ast.KindStruct },
-		"IsList":      func(t ast.Type) bool { return t.IsList },
-		"Ceil":        func(n int) int { return int(math.Ceil(float64(n) / 8.0)) },
+		"PascalCase":       util.PascalCase,
+		"SnakeCase":        util.SnakeCase,
+		"CamelCase":        util.CamelCase,
+		"GoType":           g.getGoType,
+		"GoValue":          g.getGoValue,
+		"GoTag":            g.getGoTag,
+		"GoEmbedTag":       g.getGoEmbedTag,
+		"GoLogicType":      g.getGoLogicType,
+		"GoRpcType":        g.getGoRpcType,
+		"GoModule":         func(api ast.Api) string { m, _ := apiModule(api.Name); return util.PascalCase(m) },
+		"GoMethod":         func(api ast.Api) string { _, m := apiModule(api.Name); return util.PascalCase(m) },
+		"GoErrorType":      goErrorType,
+		"GoParam":          goParam,
+		"IsBaseType":       func(t ast.Type) bool { return t.Kind == ast.KindBase },
+		"IsEnum":           func(t ast.Type) bool { return t.Kind == ast.KindEnum },
+		"IsStruct":         func(t ast.Type) bool { return t.Kind == ast.KindStruct },
+		"IsList":           func(t ast.Type) bool { return t.IsList },
+		"Ceil":             func(n int) int { return int(math.Ceil(float64(n) / 8.0)) },
+		"DeprecatedReason": DeprecatedReason,
 	}
 	return g
 }
 
+// goReserved 生成的函数中与参数同处一个作用域的标识符: 接收者, 局部变量, 命名返回值与导入的包名
+var goReserved = map[string]bool{
+	"c": true, "ctx": true, "req": true, "in": true, "buf": true, "body": true, "res": true,
+	"result": true, "err": true, "code": true, "errCode": true,
+	"bytes": true, "cmp": true, "context": true, "debug": true, "errors": true, "fmt": true, "http": true,
+	"io": true, "rand": true, "slices": true, "slog": true, "strconv": true, "strings": true, "time": true,
+}
+
+// goParam 参数在 Go 函数签名中的名称; 与关键字, 预声明标识符或生成代码中的标识符同名时加 arg 前缀
+func goParam(name string) string {
+	if token.IsKeyword(name) || types.Universe.Lookup(name) != nil || goReserved[name] {
+		return "arg" + util.PascalCase(name)
+	}
+	return name
+}
+
+// goErrorType 服务接口方法的错误返回类型: 声明了 ! E 的 API 为 EFault, 只能返回 E 的值或内部错误
+func goErrorType(api ast.Api) string {
+	if api.Error == nil { return "error" }
+	return util.PascalCase(api.Error.Name) + "Fault"
+}
+
 func (g *GoGenerator) getGoRpcType(t ast.Type) string {
 	if t.Name == "nil" { return "" }
 	if t.IsList {
@@ -130,24 +165,162 @@ func (g *GoGenerator) getGoTag(field ast.StructField) string {
 	return "`" + strings.Join(res, " ") + "`"
 }
 
+// getGoEmbedTag 嵌入结构体的 Tag: encoding/json 默认展开匿名字段, 其余 (bson, yaml 等) 需显式 inline
+func (g *GoGenerator) getGoEmbedTag() string {
+	if g.Config.GoTag == "" { return "" }
+
+	var res []string
+	for _, k := range strings.Split(g.Config.GoTag, ",") {
+		if k = strings.TrimSpace(k); k != "json" {
+			res = append(res, fmt.Sprintf("%s:\",inline\"", k))
+		}
+	}
+	if len(res) == 0 { return "" }
+	return "`" + strings.Join(res, " ") + "`"
+}
+
+// goField 模板中的字段: Path 为从结构体访问该字段的完整选择器 (如 Recharge.Id)
+// 经嵌入而来的字段使用完整路径, 避免与自身字段或其他嵌入结构体的同名字段产生遮蔽或歧义
+type goField struct {
+	ast.StructField
+	Path string
+}
+
+// goFields 按编码顺序列出字段及其选择器; 嵌入结构体的字段在展开结果中连续且保持原顺序
+func goFields(s ast.Struct, structs map[string]ast.Struct) []goField {
+	fields := make([]goField, len(s.Fields))
+	embedded := make(map[string][]goField)
+	next := make(map[string]int)
+	for i, f := range s.Fields {
+		fields[i] = goField{StructField: f, Path: util.PascalCase(f.Name)}
+		if f.Embedded == "" {
+			continue
+		}
+		if _, ok := embedded[f.Embedded]; !ok {
+			embedded[f.Embedded] = goFields(structs[f.Embedded], structs)
+		}
+		if base := embedded[f.Embedded]; next[f.Embedded] < len(base) {
+			fields[i].Path = util.PascalCase(f.Embedded) + "." + base[next[f.Embedded]].Path
+			next[f.Embedded]++
+		}
+	}
+	return fields
+}
+
 type baseTypeInfo struct {
 	Name, Go string
 	IsFloat  bool
 	Eps      string
 }
 
+// DefaultGoPackage 未指定包名与导入路径时的包名与输出子目录
+const DefaultGoPackage = "sb"
+
+// GoPackage 生成的 Go 包
+type GoPackage struct {
+	Name   string // 包名
+	Dir    string // 输出目录
+	Import string // 完整导入路径, 输出目录不在任何 Go 模块内时为空
+}
+
+// ResolveGoPackage 确定生成包的名称, 输出目录与导入路径
+// 优先级: Config (命令行参数) > Schema 中的 sb:go-package 指令 > 默认值 (<GoDir>/sb, 包名 sb)
+//   - 指定导入路径时, 输出目录由 GoDir 所在模块 (向上查找 go.mod) 推断; 不在模块内时为 <GoDir>/<路径最后一段>
+//   - 指定子目录时, 导入路径由模块路径加上相对目录推断
+//   - 包名默认取导入路径或子目录的最后一段
+func ResolveGoPackage(cfg Config, schema *ast.Schema) (GoPackage, error) {
+	importPath, name := cfg.GoImport, cfg.GoPackage
+	if importPath == "" && schema.GoPackage != "" {
+		p, n, _ := strings.Cut(schema.GoPackage, ";")
+		importPath = p
+		if name == "" {
+			name = n
+		}
+	}
+	modPath, modDir := findModule(cfg.GoDir)
+
+	var pkg GoPackage
+	switch {
+	case cfg.GoSubdir != "":
+		pkg.Dir = filepath.Join(cfg.GoDir, cfg.GoSubdir)
+	case importPath != "" && modPath != "":
+		rel, ok := strings.CutPrefix(importPath, modPath+"/")
+		if !ok {
+			return pkg, fmt.Errorf("导入路径 %s 不属于输出目录所在的模块 %s", importPath, modPath)
+		}
+		pkg.Dir = filepath.Join(modDir, filepath.FromSlash(rel))
+	case importPath != "":
+		pkg.Dir = filepath.Join(cfg.GoDir, path.Base(importPath))
+	case name != "":
+		pkg.Dir = filepath.Join(cfg.GoDir, name)
+	default:
+		pkg.Dir = filepath.Join(cfg.GoDir, DefaultGoPackage)
+	}
+
+	pkg.Import = importPath
+	if pkg.Import == "" && modPath != "" {
+		if abs, err := filepath.Abs(pkg.Dir); err == nil {
+			if rel, err := filepath.Rel(modDir, abs); err == nil {
+				pkg.Import = path.Join(modPath, filepath.ToSlash(rel))
+			}
+		}
+	}
+
+	pkg.Name = name
+	if pkg.Name == "" {
+		pkg.Name = filepath.Base(pkg.Dir)
+		if importPath != "" {
+			pkg.Name = path.Base(importPath)
+		}
+	}
+	if !token.IsIdentifier(pkg.Name) {
+		return pkg, fmt.Errorf("包名 %q 不是合法的 Go 标识符, 请指定包名", pkg.Name)
+	}
+	return pkg, nil
+}
+
+// findModule 从 dir 向上查找 go.mod, 返回模块路径与模块根目录; 未找到时均为空
+func findModule(dir string) (modPath, modDir string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+	for {
+		if data, err := os.ReadFile(filepath.Join(abs, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if v, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+					return strings.Trim(strings.TrimSpace(v), `"`), abs
+				}
+			}
+			return "", ""
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", ""
+		}
+		abs = parent
+	}
+}
+
 func (g *GoGenerator) Generate(schema *ast.Schema) error {
-	targetDir := filepath.Join(g.Config.GoDir, "sb")
-	os.MkdirAll(targetDir, 0755)
+	pkg, err := ResolveGoPackage(g.Config, schema)
+	if err != nil {
+		return err
+	}
+	targetDir := pkg.Dir
 
-	pkgName := "sb"
+	pkgName := pkg.Name
 
 	// 0. 校验
 	for _, s := range schema.Structs {
-		if len(s.Fields) > 255 {
-			return fmt.Errorf("结构体 %s 拥有 %d 个字段，超过限制 (255)", s.Name, len(s.Fields))
+		if len(s.Fields) > ast.MaxStructFields {
+			return fmt.Errorf("结构体 %s 拥有 %d 个字段，超过限制 (%d)", s.Name, len(s.Fields), ast.MaxStructFields)
 		}
 	}
+	// 生成的标识符同名时无法编译, 在写入任何文件前报告
+	if diags := names.Check(schema, g.Config.File, g.Config.Lang); len(diags) > 0 {
+		return diags
+	}
 
 	// 1. 生成 type.go
 	types := []baseTypeInfo{
@@ -167,18 +340,24 @@ func (g *GoGenerator) Generate(schema *ast.Schema) error {
 
 	// 2. 生成枚举
 	if err := g.executeTemplate("_tpl/go.enum.tpl", filepath.Join(targetDir, "enum.go"), map[string]any{
-		"Enums":   schema.Enums,
-		"Package": pkgName,
+		"Enums":      schema.Enums,
+		"ErrorEnums": errorEnums(schema),
+		"Package":    pkgName,
 	}); err != nil {
 		return err
 	}
 
 	// 3. 生成结构体
+	structs := make(map[string]ast.Struct, len(schema.Structs))
+	for _, s := range schema.Structs {
+		structs[s.Name] = s
+	}
 	for _, s := range schema.Structs {
 		path := filepath.Join(targetDir, "struct_"+util.SnakeCase(s.Name)+".go")
 		if err := g.executeTemplate("_tpl/go.struct.tpl", path, map[string]any{
 			"Name":    s.Name,
-			"Fields":  s.Fields,
+			"Embeds":  s.Embeds,
+			"Fields":  goFields(s, structs),
 			"Note":    s.Note,
 			"Package": pkgName,
 		}); err != nil {
@@ -188,36 +367,63 @@ func (g *GoGenerator) Generate(schema *ast.Schema) error {
 
 	// 4. 生成 API 与 RPC
 	if len(schema.Apis) > 0 {
-		modName := g.getModuleName()
-		
-		// 业务逻辑 Handler
+		// 同一模块的服务接口中方法名不能重复
+		methods := make(map[string]string)
+		for _, api := range schema.Apis {
+			module, method := apiModule(api.Name)
+			key := util.PascalCase(module) + "Service." + util.PascalCase(method)
+			if prev, ok := methods[key]; ok {
+				return fmt.Errorf("API %s 与 %s 生成的方法名同为 %s", prev, api.Name, key)
+			}
+			methods[key] = api.Name
+		}
+
+		// 业务逻辑 Handler (server: functions): 只在首次生成; 已存在时检查函数签名是否与 Schema 一致
+		functions := g.Config.GoServer == ServerFunctions
+		var drifts []handlerDrift
 		for _, api := range schema.Apis {
+			if !functions {
+				break
+			}
 			filename := "api." + api.Name + ".go"
 			logicPath := filepath.Join(targetDir, filename)
-			if _, err := os.Stat(logicPath); os.IsNotExist(err) {
-				if err := g.executeTemplate("_tpl/go.api.tpl", logicPath, map[string]any{
-					"Api":     api,
-					"Mod":     modName,
-					"Package": pkgName,
-				}); err != nil {
+			exists, err := g.Config.output().Exists(logicPath)
+			if err != nil {
+				return err
+			}
+			stub, err := g.renderTemplate("_tpl/go.api.tpl", map[string]any{
+				"Api":     api,
+				"Import":  pkg.Import,
+				"Package": pkgName,
+			})
+			if err != nil {
+				return err
+			}
+			if !exists {
+				if err := g.Config.output().WriteFile(logicPath, stub); err != nil {
 					return err
 				}
+				continue
+			}
+			drift, err := g.syncHandler(logicPath, stub, util.SnakeCase(api.Name))
+			if err != nil {
+				return err
+			}
+			if drift != nil {
+				drifts = append(drifts, *drift)
 			}
 		}
-
 		// API 注册
 		groups := make(map[string][]ast.Api)
 		for _, api := range schema.Apis {
-			module := "api"
-			if parts := strings.Split(api.Name, "."); len(parts) > 1 {
-				module = parts[0]
-			}
+			module, _ := apiModule(api.Name)
 			groups[module] = append(groups[module], api)
 		}
 		if err := g.executeTemplate("_tpl/go.api._.tpl", filepath.Join(targetDir, "api._.go"), map[string]any{
-			"Apis":    schema.Apis,
-			"Groups":  groups,
-			"Mod":     modName,
+			"Apis":      schema.Apis,
+			"Groups":    groups,
+			"Functions": functions,
+			"Import":  pkg.Import,
 			"Package": pkgName,
 		}); err != nil {
 			return err
@@ -226,40 +432,48 @@ func (g *GoGenerator) Generate(schema *ast.Schema) error {
 		// RPC 客户端/服务端
 		if err := g.executeTemplate("_tpl/go.rpc.tpl", filepath.Join(targetDir, "rpc.go"), map[string]any{
 			"Apis":    schema.Apis,
-			"Mod":     modName,
+			"Import":  pkg.Import,
 			"Package": pkgName,
 		}); err != nil {
 			return err
 		}
+		if len(drifts) > 0 {
+			return driftError(drifts)
+		}
 	}
 
 	return nil
 }
 
-func (g *GoGenerator) getModuleName() string {
-	path := filepath.Join(g.Config.GoDir, "go.mod")
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return ""
-	}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "module ") {
-			return strings.TrimSpace(strings.TrimPrefix(line, "module "))
-		}
-	}
-	return ""
+func (g *GoGenerator) executeTemplate(tplPath, destPath string, data any) error {
+	out, err := g.renderTemplate(tplPath, data)
+	if err != nil { return err }
+	return g.Config.output().WriteFile(destPath, out)
 }
 
-func (g *GoGenerator) executeTemplate(tplPath, destPath string, data any) error {
-	tplContent, err := g.Config.TplFS.ReadFile(tplPath)
-	if err != nil { return fmt.Errorf("read embedded template %s: %w", tplPath, err) }
+func (g *GoGenerator) renderTemplate(tplPath string, data any) ([]byte, error) {
+	tplContent, err := fs.ReadFile(g.Config.TplFS, tplPath)
+	if err != nil { return nil, fmt.Errorf("read template %s: %w", tplPath, err) }
 	
 	tpl, err := template.New(filepath.Base(tplPath)).Funcs(g.FuncMap).Parse(string(tplContent))
-	if err != nil { return err }
+	if err != nil { return nil, err }
 	
 	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, data); err != nil { return err }
-	
-	return os.WriteFile(destPath, buf.Bytes(), 0644)
+	if err := tpl.Execute(&buf, data); err != nil { return nil, err }
+	return buf.Bytes(), nil
+}
+
+// apiModule 把 API 名称拆分为模块与方法, 没有模块的 API 归入 api 模块
+// 如 user.get_abc -> (user, get_abc), get_count -> (api, get_count)
+func apiModule(name string) (module, method string) {
+	if m, rest, ok := strings.Cut(name, "."); ok {
+		return m, rest
+	}
+	return "api", name
+}
+
+// OutputDir 生成包所在目录
+func (g *GoGenerator) OutputDir(schema *ast.Schema) (string, error) {
+	pkg, err := ResolveGoPackage(g.Config, schema)
+	return pkg.Dir, err
 }
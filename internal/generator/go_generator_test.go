@@ -0,0 +1,510 @@
+package generator
+
+import (
+	goast "go/ast"
+	goparser "go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/lexer"
+	"sb/internal/names"
+	"sb/internal/parser"
+	"sb/internal/util"
+)
+
+func TestResolveGoPackage(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		directive string
+		want      GoPackage
+		wantErr   bool
+	}{
+		{
+			name: "Default",
+			cfg:  Config{GoDir: root},
+			want: GoPackage{Name: "sb", Dir: filepath.Join(root, "sb"), Import: "example.com/app/sb"},
+		},
+		{
+			name: "Default Outside Module",
+			cfg:  Config{GoDir: outside},
+			want: GoPackage{Name: "sb", Dir: filepath.Join(outside, "sb")},
+		},
+		{
+			name:      "Directive",
+			cfg:       Config{GoDir: filepath.Join(root, "gen")},
+			directive: "example.com/app/internal/proto/billing",
+			want:      GoPackage{Name: "billing", Dir: filepath.Join(root, "internal/proto/billing"), Import: "example.com/app/internal/proto/billing"},
+		},
+		{
+			name:      "Directive With Name",
+			cfg:       Config{GoDir: root},
+			directive: "example.com/app/proto/v2;sim",
+			want:      GoPackage{Name: "sim", Dir: filepath.Join(root, "proto/v2"), Import: "example.com/app/proto/v2"},
+		},
+		{
+			name:      "Flags Override Directive",
+			cfg:       Config{GoDir: root, GoSubdir: "internal/proto/sim", GoPackage: "simpb"},
+			directive: "example.com/app/other",
+			want:      GoPackage{Name: "simpb", Dir: filepath.Join(root, "internal/proto/sim"), Import: "example.com/app/other"},
+		},
+		{
+			name: "Subdir",
+			cfg:  Config{GoDir: root, GoSubdir: "internal/proto/sim"},
+			want: GoPackage{Name: "sim", Dir: filepath.Join(root, "internal/proto/sim"), Import: "example.com/app/internal/proto/sim"},
+		},
+		{
+			name: "Import Outside Module",
+			cfg:  Config{GoDir: outside, GoImport: "example.com/lib/billing"},
+			want: GoPackage{Name: "billing", Dir: filepath.Join(outside, "billing"), Import: "example.com/lib/billing"},
+		},
+		{
+			name:    "Import Not In Module",
+			cfg:     Config{GoDir: root, GoImport: "example.com/other/billing"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Name",
+			cfg:     Config{GoDir: root, GoSubdir: "my-proto"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveGoPackage(tt.cfg, &ast.Schema{GoPackage: tt.directive})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoServer(t *testing.T) {
+	schema := &ast.Schema{
+		Apis: []ast.Api{
+			{Name: "user.get", Args: []ast.ApiArg{{Name: "page", Type: ast.Type{Name: "u8", Kind: ast.KindBase}}}, Result: ast.Type{Name: "u8", Kind: ast.KindBase}},
+			{Name: "ping", Result: ast.Type{Name: "nil"}},
+		},
+	}
+	tests := []struct {
+		server   string
+		want     []string
+		handlers bool
+	}{
+		{
+			server: ServerInterface,
+			want: []string{
+				"type UserService interface {",
+				"Get(ctx context.Context, page uint8) (uint8, error)",
+				"type ApiService interface {",
+				"Ping(ctx context.Context) error",
+				"func RegisterUser(mux Router, impl UserService, opts *ServerOptions)",
+				"func RegisterAll(mux Router, impl Services, opts *ServerOptions)",
+				"result, err := intercept[uint8](r.Context(), opts.Interceptors, &UnaryInfo{Api: \"user.get\", Request: r}, req,",
+				"return nil, impl.Ping(ctx)",
+				"func NewLocalTransport(impl Services, opts *ServerOptions) *LocalTransport",
+			},
+		},
+		{
+			server: ServerFunctions,
+			want: []string{
+				"type userFuncs struct{}",
+				"result, code := user_get(ctx, page)",
+				"func RegisterUser(mux Router, opts *ServerOptions)",
+				"func NewLocalTransport(opts *ServerOptions) *LocalTransport",
+			},
+			handlers: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			dir := t.TempDir()
+			mem := NewMemory()
+			gen := NewGoGenerator(Config{GoDir: dir, TplFS: TplFS, Output: mem, GoServer: tt.server})
+			if err := gen.Generate(schema); err != nil {
+				t.Fatal(err)
+			}
+			src, ok := mem.ReadFile(filepath.Join(dir, "sb", "api._.go"))
+			if !ok {
+				t.Fatalf("api._.go not generated, files: %v", mem.Files())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(src), want) {
+					t.Errorf("api._.go missing %q", want)
+				}
+			}
+			_, ok = mem.ReadFile(filepath.Join(dir, "sb", "api.user.get.go"))
+			if ok != tt.handlers {
+				t.Errorf("api.user.get.go generated = %v, want %v", ok, tt.handlers)
+			}
+		})
+	}
+}
+
+func TestGoErrorEnum(t *testing.T) {
+	simError := ast.Type{Name: "SimError", Kind: ast.KindEnum}
+	schema := &ast.Schema{
+		Enums: []ast.Enum{
+			{Name: "SimError", Children: []ast.EnumChild{{Name: "NotFound", ID: 1, Note: "卡不存在"}, {Name: "Locked", ID: 2}}},
+			{Name: "Status", Children: []ast.EnumChild{{Name: "Ok"}}},
+		},
+		Apis: []ast.Api{{Name: "sim.set", Result: ast.Type{Name: "nil"}, Error: &simError}},
+	}
+	dir := t.TempDir()
+	mem := NewMemory()
+	if err := NewGoGenerator(Config{GoDir: dir, TplFS: TplFS, Output: mem}).Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	enums, _ := mem.ReadFile(filepath.Join(dir, "sb", "enum.go"))
+	for _, want := range []string{"func (v SimError) Error() string", `case SimErrorNotFound: return "卡不存在"`, `case SimErrorLocked: return "SimError.Locked"`} {
+		if !strings.Contains(string(enums), want) {
+			t.Errorf("enum.go missing %q", want)
+		}
+	}
+	if strings.Contains(string(enums), "func (v Status) Error()") {
+		t.Error("enum not used as API error implements error")
+	}
+	if !strings.Contains(string(enums), "type SimErrorFault interface {") {
+		t.Error("enum.go missing SimErrorFault")
+	}
+	api, _ := mem.ReadFile(filepath.Join(dir, "sb", "api._.go"))
+	if !strings.Contains(string(api), "Set(ctx context.Context) SimErrorFault") {
+		t.Error("service method does not return SimErrorFault")
+	}
+	rpc, _ := mem.ReadFile(filepath.Join(dir, "sb", "rpc.go"))
+	if !strings.Contains(string(rpc), "return nil, bizError[SimError](err)") {
+		t.Error("client does not attach SimError")
+	}
+}
+
+func TestGoIdempotent(t *testing.T) {
+	u32 := ast.Type{Name: "u32", Kind: ast.KindBase}
+	schema := &ast.Schema{
+		Apis: []ast.Api{
+			{Name: "user.get", Args: []ast.ApiArg{{Name: "id", Type: u32}}, Result: u32, Idempotent: true},
+			{Name: "user.set", Args: []ast.ApiArg{{Name: "id", Type: u32}}, Result: ast.Type{Name: "nil"}},
+		},
+	}
+	dir := t.TempDir()
+	mem := NewMemory()
+	if err := NewGoGenerator(Config{GoDir: dir, TplFS: TplFS, Output: mem}).Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	rpc, _ := mem.ReadFile(filepath.Join(dir, "sb", "rpc.go"))
+	for _, want := range []string{`c.do(ctx, "user.get", buf.Bytes(), true)`, `c.do(ctx, "user.set", buf.Bytes(), false)`} {
+		if !strings.Contains(string(rpc), want) {
+			t.Errorf("rpc.go missing %q", want)
+		}
+	}
+}
+
+// goModule 在临时模块中生成 Go 代码 (包 sb), 并写入附加文件 (路径相对模块根目录)
+func goModule(t *testing.T, schema *ast.Schema, server string, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil || testing.Short() {
+		t.Skip("requires the go toolchain")
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/app\n\ngo 1.25\n"
+	if err := NewGoGenerator(Config{GoDir: dir, TplFS: TplFS, GoServer: server}).Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// goRun 在模块目录中执行 go 命令
+func goRun(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// vetGo 生成 Go 代码并执行 go vet, 确认生成结果可以编译
+func vetGo(t *testing.T, schema *ast.Schema, server string) {
+	t.Helper()
+	if out, err := goRun(goModule(t, schema, server, map[string]string{}), "vet", "./..."); err != nil {
+		t.Fatalf("go vet: %v\n%s", err, out)
+	}
+}
+
+func parseSchema(t *testing.T, src string) *ast.Schema {
+	t.Helper()
+	schema, err := parser.New(lexer.New(src)).ParseSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// TestGoHandlerStubs 示例 Schema 的全部 API 及结构体, 列表返回值生成的处理函数桩与服务接口均可编译
+func TestGoHandlerStubs(t *testing.T) {
+	src, err := os.ReadFile("../../aaa.sb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := parseSchema(t, string(src)+`
+sim.get(id u32) => SimInfo
+sim.list(ids [u32]) => [SimInfo]
+sim.names() => [text]
+`)
+	for _, server := range []string{ServerFunctions, ServerInterface} {
+		t.Run(server, func(t *testing.T) { vetGo(t, schema, server) })
+	}
+}
+
+// TestGoArgNames 参数与生成代码中的局部变量, 包名, 关键字或预声明标识符同名时仍可编译
+func TestGoArgNames(t *testing.T) {
+	schema := parseSchema(t, `
+Kind = A | B
+
+Info {
+	id u32
+}
+
+sim.locals(c u32, req text, impl u32, opts u32, in u32, ctx u32, result text, err u32) => u32
+sim.names(http text, fmt text, errors u32, slog text, time u32, context text) => Info
+sim.builtins(type u32, string text, len u32, bytes [u8], info Info, kind Kind) => nil
+`)
+	for _, server := range []string{ServerFunctions, ServerInterface} {
+		t.Run(server, func(t *testing.T) { vetGo(t, schema, server) })
+	}
+}
+
+// TestGoUndeclaredError 服务接口只能返回声明的错误枚举; 未声明的业务错误 (如经拦截器返回) 按内部错误处理
+func TestGoUndeclaredError(t *testing.T) {
+	schema := parseSchema(t, `
+SimError = NotFound(1)
+OrderError = Closed(1)
+sim.set() => nil ! SimError
+order.close() => u8 ! OrderError
+ping() => nil
+`)
+
+	t.Run("Compile", func(t *testing.T) {
+		dir := goModule(t, schema, ServerInterface, map[string]string{"sb/impl.go": `package sb
+
+import "context"
+
+type impl struct{}
+
+func (impl) Set(ctx context.Context) SimErrorFault { return OrderErrorClosed }
+`})
+		out, err := goRun(dir, "vet", "./...")
+		if err == nil || !strings.Contains(out, "OrderError does not implement SimErrorFault") {
+			t.Errorf("go vet err = %v, want OrderError rejected:\n%s", err, out)
+		}
+	})
+
+	t.Run("Runtime", func(t *testing.T) {
+		dir := goModule(t, schema, ServerInterface, map[string]string{"sb/impl_test.go": `package sb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type impl struct{}
+
+func (impl) Set(ctx context.Context) SimErrorFault { return SimErrorNotFound }
+func (impl) Close(ctx context.Context) (uint8, OrderErrorFault) {
+	return 0, InternalError(errors.New("db down"))
+}
+func (impl) Ping(ctx context.Context) error { return SimErrorNotFound }
+
+func TestErrors(t *testing.T) {
+	var reports []string
+	swap := func(ctx context.Context, info *UnaryInfo, req any, next UnaryHandler) (any, error) {
+		res, err := next(ctx, req)
+		if info.Api == "sim.set" { err = OrderErrorClosed }
+		return res, err
+	}
+	opts := &ServerOptions{
+		Interceptors:  []UnaryInterceptor{swap},
+		ErrorReporter: func(r *http.Request, e ErrorReport) { reports = append(reports, e.Api+": "+e.Err.Error()) },
+	}
+	c := NewClient("")
+	c.Transport = NewLocalTransport(impl{}, opts)
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); !errors.Is(err, RpcRespErr) {
+		t.Errorf("ping err = %v, want RpcRespErr", err)
+	}
+	if err := c.SimSet(ctx); !errors.Is(err, RpcRespErr) {
+		t.Errorf("sim.set err = %v, want RpcRespErr", err)
+	}
+	if _, err := c.OrderClose(ctx); !errors.Is(err, RpcRespErr) {
+		t.Errorf("order.close err = %v, want RpcRespErr", err)
+	}
+	want := []string{
+		"ping: 未声明的业务错误 sb.SimError: SimError.NotFound",
+		"sim.set: 未声明的业务错误 sb.OrderError: OrderError.Closed",
+		"order.close: rpc status 500: db down",
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("reports = %q, want %q", reports, want)
+	}
+	for i := range want {
+		if reports[i] != want[i] {
+			t.Errorf("report %d = %q, want %q", i, reports[i], want[i])
+		}
+	}
+}
+`})
+		if out, err := goRun(dir, "test", "./..."); err != nil {
+			t.Fatalf("go test: %v\n%s", err, out)
+		}
+	})
+}
+
+// TestGoNameCollision 生成的标识符同名时在写入任何文件前报错
+func TestGoNameCollision(t *testing.T) {
+	for _, src := range []string{
+		"GetCountRequest { id u32 }\nget_count(page u8) => GetCountRequest",
+		"UserGetCountRequest { id u32 }\nuser.get_count(page u8) => UserGetCountRequest",
+	} {
+		mem := NewMemory()
+		err := NewGoGenerator(Config{GoDir: t.TempDir(), TplFS: TplFS, Output: mem}).Generate(parseSchema(t, src))
+		if err == nil || !strings.Contains(err.Error(), "2:1: error[name-collision]") {
+			t.Errorf("%q: err = %v, want name-collision at 2:1", src, err)
+		}
+		if files := mem.Files(); len(files) > 0 {
+			t.Errorf("%q: files written before failing: %v", src, files)
+		}
+	}
+}
+
+// TestGoReservedNames Schema 占用生成代码中的固定名称时在写入任何文件前报错
+func TestGoReservedNames(t *testing.T) {
+	for _, name := range names.Reserved {
+		src := name + " { id u32 }"
+		if !token.IsExported(name) {
+			src = name + "() => nil"
+		}
+		mem := NewMemory()
+		err := NewGoGenerator(Config{GoDir: t.TempDir(), TplFS: TplFS, Output: mem, File: "a.sb"}).Generate(parseSchema(t, src))
+		if err == nil || !strings.Contains(err.Error(), "a.sb:1:1: error[name-collision]") {
+			t.Errorf("%s: err = %v, want name-collision at a.sb:1:1", name, err)
+		}
+		if files := mem.Files(); len(files) > 0 {
+			t.Errorf("%s: files written before failing: %v", name, files)
+		}
+	}
+}
+
+// TestGoReservedNamesComplete 生成代码中的包级标识符与 Client 方法, 除由 Schema 派生的以外均已登记在 names 中
+// 以示例 Schema 分别按两种服务端组织方式生成, 逐个检查同名的结构体 (导出名称) 或 API (snake_case 名称) 会被拒绝
+func TestGoReservedNamesComplete(t *testing.T) {
+	src, err := os.ReadFile("../../aaa.sb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := parseSchema(t, string(src))
+	base := len(names.Check(schema, "", diag.LangZH))
+	collides := func(def func(s *ast.Schema)) bool {
+		s := *schema
+		s.Structs = slices.Clone(schema.Structs)
+		s.Apis = slices.Clone(schema.Apis)
+		def(&s)
+		return len(names.Check(&s, "", diag.LangZH)) > base
+	}
+	for _, server := range []string{ServerInterface, ServerFunctions} {
+		dir := t.TempDir()
+		if err := NewGoGenerator(Config{GoDir: dir, TplFS: TplFS, GoServer: server}).Generate(schema); err != nil {
+			t.Fatal(err)
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*", "*.go"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no generated files: %v", err)
+		}
+		fset := token.NewFileSet()
+		for _, path := range files {
+			file, err := goparser.ParseFile(fset, path, nil, goparser.SkipObjectResolution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range packageIdents(file) {
+				switch {
+				case token.IsExported(id):
+					if !collides(func(s *ast.Schema) { s.Structs = append(s.Structs, ast.Struct{Name: id}) }) {
+						t.Errorf("%s: %s: struct %s is not rejected", server, filepath.Base(path), id)
+					}
+				case util.SnakeCase(id) == id:
+					if !collides(func(s *ast.Schema) { s.Apis = append(s.Apis, ast.Api{Name: id}) }) {
+						t.Errorf("%s: %s: API %s is not rejected", server, filepath.Base(path), id)
+					}
+				}
+			}
+			for _, method := range clientMethods(file) {
+				if !collides(func(s *ast.Schema) { s.Apis = append(s.Apis, ast.Api{Name: util.SnakeCase(method)}) }) {
+					t.Errorf("%s: %s: API %s is not rejected", server, filepath.Base(path), util.SnakeCase(method))
+				}
+			}
+		}
+	}
+}
+
+// packageIdents 文件中声明的包级类型, 函数, 变量与常量
+func packageIdents(file *goast.File) []string {
+	var ids []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *goast.FuncDecl:
+			if d.Recv == nil {
+				ids = append(ids, d.Name.Name)
+			}
+		case *goast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *goast.TypeSpec:
+					ids = append(ids, s.Name.Name)
+				case *goast.ValueSpec:
+					for _, n := range s.Names {
+						ids = append(ids, n.Name)
+					}
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// clientMethods 文件中声明的 *Client 导出方法
+func clientMethods(file *goast.File) []string {
+	var methods []string
+	for _, decl := range file.Decls {
+		d, ok := decl.(*goast.FuncDecl)
+		if !ok || d.Recv == nil || !d.Name.IsExported() {
+			continue
+		}
+		if star, ok := d.Recv.List[0].Type.(*goast.StarExpr); ok {
+			if recv, ok := star.X.(*goast.Ident); ok && recv.Name == "Client" {
+				methods = append(methods, d.Name.Name)
+			}
+		}
+	}
+	return methods
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+)
+
+// 服务端业务逻辑的组织方式, 对应 go 目标的 server 选项
+const (
+	ServerInterface = "interface" // 每个模块生成服务接口, 由使用方实现后注册 (默认)
+	ServerFunctions = "functions" // 业务逻辑为包内的处理函数 api.<name>.go (旧版方式)
+)
+
+// 处理函数 (api.<name>.go) 签名与 Schema 不一致时的处理方式, 对应 go 目标的 handlers 选项
+const (
+	HandlersRewrite = "rewrite" // 改写函数签名, 保留函数体 (默认)
+	HandlersReport  = "report"  // 不修改文件, 报告需要更新的文件
+)
+
+// handlerDrift 签名与 Schema 不一致的处理函数
+type handlerDrift struct {
+	Path, Have, Want string
+}
+
+// syncHandler 比较已存在的处理函数与 stub (按当前 Schema 生成的内容) 中同名函数的签名
+// 只比较参数与返回值的类型, 参数改名不视为不一致。文件无法解析或其中没有该函数时
+// (正在编辑, 或已移到其他文件) 不做处理, 交由编译器报告
+func (g *GoGenerator) syncHandler(path string, stub []byte, name string) (*handlerDrift, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, 0)
+	if err != nil {
+		return nil, nil
+	}
+	have := findFunc(file, name)
+	if have == nil {
+		return nil, nil
+	}
+	stubFile, err := parser.ParseFile(fset, "", stub, 0)
+	if err != nil {
+		return nil, fmt.Errorf("处理函数模板生成的代码无法解析: %w", err)
+	}
+	want := findFunc(stubFile, name)
+	if want == nil || signatureKey(have.Type) == signatureKey(want.Type) {
+		return nil, nil
+	}
+
+	hs, he := fset.Position(have.Type.Pos()).Offset, fset.Position(have.Type.End()).Offset
+	ws, we := fset.Position(want.Type.Pos()).Offset, fset.Position(want.Type.End()).Offset
+	drift := &handlerDrift{Path: path, Have: string(src[hs:he]), Want: string(stub[ws:we])}
+	if g.Config.GoHandlers == HandlersReport {
+		return drift, nil
+	}
+
+	var out []byte
+	out = append(out, src[:hs]...)
+	out = append(out, drift.Want...)
+	out = append(out, src[he:]...)
+	if err := g.Config.output().WriteFile(path, out); err != nil {
+		return nil, err
+	}
+	g.notices = append(g.notices, fmt.Sprintf("已按 Schema 更新 %s 中 %s 的函数签名, 请检查函数体", path, name))
+	return nil, nil
+}
+
+// Notices 本次改写了签名的处理函数
+func (g *GoGenerator) Notices() []string {
+	return g.notices
+}
+
+func findFunc(file *goast.File, name string) *goast.FuncDecl {
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*goast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name {
+			return fn
+		}
+	}
+	return nil
+}
+
+// signatureKey 参数与返回值类型组成的签名, 忽略名称
+func signatureKey(ft *goast.FuncType) string {
+	list := func(fl *goast.FieldList) string {
+		if fl == nil {
+			return ""
+		}
+		var parts []string
+		for _, f := range fl.List {
+			for range max(len(f.Names), 1) {
+				parts = append(parts, types.ExprString(f.Type))
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	return "(" + list(ft.Params) + ") (" + list(ft.Results) + ")"
+}
+
+// driftError 汇总签名不一致的处理函数
+func driftError(drifts []handlerDrift) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d 个处理函数的签名与 Schema 不一致, 请手动修改 (或将 go 目标的 handlers 选项设为 %s 自动改写):", len(drifts), HandlersRewrite)
+	for _, d := range drifts {
+		fmt.Fprintf(&sb, "\n  %s\n    当前: %s\n    应为: %s", d.Path, d.Have, d.Want)
+	}
+	return fmt.Errorf("%s", sb.String())
+}
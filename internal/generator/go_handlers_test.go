@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const handlerStub = `package sb
+
+import (
+	"context"
+)
+
+func user_get(ctx context.Context, page uint8, tag string) (result uint8, errCode RpcErrCode) {
+	return 0, RpcRespErr
+}
+`
+
+func TestSyncHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		mode     string
+		want     string // 改写后的文件内容, 为空表示文件不变
+		drift    bool
+	}{
+		{
+			name:     "Renamed params are in sync",
+			existing: "package sb\n\nfunc user_get(c context.Context, p uint8, t string) (r uint8, e RpcErrCode) { return p, RpcOk }\n",
+		},
+		{
+			name:     "Rewrite keeps body",
+			existing: "package sb\n\n// 获取用户\nfunc user_get(ctx context.Context, page uint8) (result uint8, errCode RpcErrCode) {\n\treturn page, RpcOk\n}\n\nfunc helper() {}\n",
+			want:     "package sb\n\n// 获取用户\nfunc user_get(ctx context.Context, page uint8, tag string) (result uint8, errCode RpcErrCode) {\n\treturn page, RpcOk\n}\n\nfunc helper() {}\n",
+		},
+		{
+			name:     "Report",
+			existing: "package sb\n\nfunc user_get(ctx context.Context, page uint16) (result uint8, errCode RpcErrCode) { return 0, RpcOk }\n",
+			mode:     HandlersReport,
+			drift:    true,
+		},
+		{
+			name:     "Moved to another file",
+			existing: "package sb\n\nfunc other() {}\n",
+		},
+		{
+			name:     "Syntax error",
+			existing: "package sb\n\nfunc user_get(ctx context.Context, page uint8 {\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "api.user.get.go")
+			if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			g := NewGoGenerator(Config{TplFS: TplFS, GoHandlers: tt.mode})
+			drift, err := g.syncHandler(path, []byte(handlerStub), "user_get")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (drift != nil) != tt.drift {
+				t.Errorf("drift = %+v, want %v", drift, tt.drift)
+			}
+			got, _ := os.ReadFile(path)
+			want := tt.want
+			if want == "" {
+				want = tt.existing
+			}
+			if string(got) != want {
+				t.Errorf("file =\n%s\nwant\n%s", got, want)
+			}
+			if rewritten := tt.want != ""; rewritten != (len(g.Notices()) == 1) {
+				t.Errorf("notices = %v", g.Notices())
+			}
+		})
+	}
+}
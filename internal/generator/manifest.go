@@ -0,0 +1,168 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ManifestFile 输出目录中的生成文件清单
+//
+// 每行为 "<目标> <类别> <相对路径>", 类别为 gen (每次重新生成) 或 stub (只在首次生成, 如
+// api.<name>.go)。重新生成时, 清单中属于本次目标却不再生成的 gen 文件被删除; stub 文件含有
+// 手写的业务逻辑, 只在显式要求时删除, 否则保留并继续记录在清单中。
+const ManifestFile = ".sb-manifest"
+
+const manifestHeader = "# sb 生成文件清单, 用于删除不再生成的文件; 请勿手动修改\n"
+
+// 文件类别
+const (
+	kindGen  = "gen"
+	kindStub = "stub"
+)
+
+// Tracker 包装 Output, 记录一个目标写入的文件
+type Tracker struct {
+	Output
+	Target string
+	Root   string // 目标的输出目录; 其下的文件记录在该目录的清单中, 为空时记录在文件所在目录
+
+	files map[string]string // 路径 -> 类别
+}
+
+func NewTracker(out Output, target string) *Tracker {
+	return &Tracker{Output: out, Target: target, files: make(map[string]string)}
+}
+
+func (t *Tracker) WriteFile(name string, data []byte) error {
+	name = filepath.Clean(name)
+	if _, ok := t.files[name]; !ok {
+		t.files[name] = kindGen
+	}
+	return t.Output.WriteFile(name, data)
+}
+
+// Exists 询问是否存在的文件视为 stub: 生成器只在其不存在时写入
+func (t *Tracker) Exists(name string) (bool, error) {
+	t.files[filepath.Clean(name)] = kindStub
+	return t.Output.Exists(name)
+}
+
+// manifestPath 文件所属的清单目录与清单中的相对路径
+func (t *Tracker) manifestPath(name string) (dir, rel string) {
+	if t.Root != "" {
+		root := filepath.Clean(t.Root)
+		if r, err := filepath.Rel(root, name); err == nil && validRelPath(filepath.ToSlash(r)) {
+			return root, filepath.ToSlash(r)
+		}
+	}
+	return filepath.Dir(name), filepath.Base(name)
+}
+
+type manifestEntry struct {
+	target, kind, path string
+}
+
+// Prune 更新同一输入全部目标的清单, 删除不再生成的文件
+// 只处理 trackers 中的目标, 清单中其他目标的记录保持不变; pruneStubs 为 false 时保留
+// 不再生成的 stub 文件, 返回其路径以便提示
+func Prune(out Output, trackers []*Tracker, pruneStubs bool) (kept []string, err error) {
+	current := make(map[string][]manifestEntry) // 清单目录 -> 本次生成的记录
+	targets := make(map[string]bool)
+	for _, t := range trackers {
+		targets[t.Target] = true
+		if t.Root != "" {
+			current[filepath.Clean(t.Root)] = current[filepath.Clean(t.Root)]
+		}
+		for name, kind := range t.files {
+			if filepath.Base(name) == ManifestFile {
+				continue
+			}
+			dir, rel := t.manifestPath(name)
+			current[dir] = append(current[dir], manifestEntry{t.Target, kind, rel})
+		}
+	}
+
+	for _, dir := range slices.Sorted(maps.Keys(current)) {
+		entries := current[dir]
+		old, err := readManifest(filepath.Join(dir, ManifestFile))
+		if err != nil {
+			return kept, err
+		}
+		produced := make(map[string]bool)
+		for _, e := range entries {
+			produced[e.target+" "+e.path] = true
+		}
+		for _, e := range old {
+			if !targets[e.target] {
+				entries = append(entries, e) // 本次未生成的目标
+				continue
+			}
+			if produced[e.target+" "+e.path] {
+				continue
+			}
+			path := filepath.Join(dir, filepath.FromSlash(e.path))
+			if e.kind == kindStub && !pruneStubs {
+				if exists, _ := Disk.Exists(path); exists {
+					kept = append(kept, path)
+					entries = append(entries, e)
+				}
+				continue
+			}
+			if err := out.Remove(path); err != nil {
+				return kept, err
+			}
+		}
+		if err := writeManifest(out, filepath.Join(dir, ManifestFile), entries); err != nil {
+			return kept, err
+		}
+	}
+	return kept, nil
+}
+
+func readManifest(path string) ([]manifestEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []manifestEntry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 || fields[1] != kindGen && fields[1] != kindStub || !validRelPath(fields[2]) {
+			return nil, fmt.Errorf("%s:%d: invalid manifest entry %q", path, n, line)
+		}
+		entries = append(entries, manifestEntry{fields[0], fields[1], fields[2]})
+	}
+	return entries, sc.Err()
+}
+
+// writeManifest 写入清单; 没有记录时删除清单
+func writeManifest(out Output, path string, entries []manifestEntry) error {
+	if len(entries) == 0 {
+		return out.Remove(path)
+	}
+	slices.SortFunc(entries, func(a, b manifestEntry) int {
+		return strings.Compare(a.target+" "+a.path, b.target+" "+b.path)
+	})
+	var buf bytes.Buffer
+	buf.WriteString(manifestHeader)
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "%s %s %s\n", e.target, e.kind, e.path)
+	}
+	return out.WriteFile(path, buf.Bytes())
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// generate 用 tracker 模拟一次生成: gen 文件写入, stub 文件不存在时写入
+func generate(t *testing.T, target, root string, gen, stubs []string) *Tracker {
+	t.Helper()
+	tr := NewTracker(Disk, target)
+	tr.Root = root
+	for _, name := range gen {
+		if err := tr.WriteFile(filepath.Join(root, name), []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range stubs {
+		path := filepath.Join(root, name)
+		if exists, _ := tr.Exists(path); !exists {
+			if err := tr.WriteFile(path, []byte(name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return tr
+}
+
+func listDir(t *testing.T, dir string) string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return strings.Join(names, " ")
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	run := func(pruneStubs bool, trackers ...*Tracker) []string {
+		kept, err := Prune(Disk, trackers, pruneStubs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return kept
+	}
+
+	run(false,
+		generate(t, "go", dir, []string{"a.go", "b.go"}, []string{"api.x.go", "api.y.go"}),
+		generate(t, "doc", "", []string{filepath.Join(dir, "DOC.md")}, nil),
+	)
+	// 手写文件不在清单中, 不会被删除
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// b.go 与 api.y.go 不再生成; 只生成 go 目标时 doc 的文件保持不变
+	kept := run(false, generate(t, "go", dir, []string{"a.go"}, []string{"api.x.go"}))
+	if want := []string{filepath.Join(dir, "api.y.go")}; !slices.Equal(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+	if got, want := listDir(t, dir), ".sb-manifest DOC.md a.go api.x.go api.y.go user.go"; got != want {
+		t.Errorf("files = %s, want %s", got, want)
+	}
+
+	run(true, generate(t, "go", dir, []string{"a.go"}, []string{"api.x.go"}))
+	if got, want := listDir(t, dir), ".sb-manifest DOC.md a.go api.x.go user.go"; got != want {
+		t.Errorf("files = %s, want %s", got, want)
+	}
+
+	// 目标不再生成任何文件时清单随之删除
+	run(true, generate(t, "go", dir, nil, nil), generate(t, "doc", "", nil, nil))
+	if got, want := listDir(t, dir), "user.go"; got != want {
+		t.Errorf("files = %s, want %s", got, want)
+	}
+}
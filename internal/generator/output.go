@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Output 生成结果的写入目标; 生成器不直接写磁盘, 以便 -check 在内存中生成后与磁盘比较
+type Output interface {
+	// WriteFile 写入文件, 按需创建所在目录
+	WriteFile(name string, data []byte) error
+	// Exists 文件是否已存在 (用于只在首次生成的文件, 如 api.<name>.go)
+	Exists(name string) (bool, error)
+	// Remove 删除不再生成的文件, 文件不存在时不报错
+	Remove(name string) error
+}
+
+// Disk 直接写入磁盘
+var Disk Output = diskOutput{}
+
+type diskOutput struct{}
+
+func (diskOutput) WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+func (diskOutput) Exists(name string) (bool, error) {
+	_, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (diskOutput) Remove(name string) error {
+	err := os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Memory 把生成结果保存在内存中, 不修改磁盘
+type Memory struct {
+	files   map[string][]byte // 键为 filepath.Clean 后的路径
+	removed map[string]bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string][]byte), removed: make(map[string]bool)}
+}
+
+func (m *Memory) WriteFile(name string, data []byte) error {
+	name = filepath.Clean(name)
+	m.files[name] = slices.Clone(data)
+	delete(m.removed, name)
+	return nil
+}
+
+func (m *Memory) Remove(name string) error {
+	name = filepath.Clean(name)
+	delete(m.files, name)
+	m.removed[name] = true
+	return nil
+}
+
+// Removed 将被删除的文件 (按路径排序)
+func (m *Memory) Removed() []string {
+	return slices.Sorted(maps.Keys(m.removed))
+}
+
+// Exists 本次已生成, 或磁盘上已存在且未被删除
+func (m *Memory) Exists(name string) (bool, error) {
+	if _, ok := m.files[filepath.Clean(name)]; ok {
+		return true, nil
+	}
+	if m.removed[filepath.Clean(name)] {
+		return false, nil
+	}
+	return Disk.Exists(name)
+}
+
+// Files 已生成的全部文件 (按路径排序)
+func (m *Memory) Files() []string {
+	return slices.Sorted(maps.Keys(m.files))
+}
+
+// ReadFile 已生成文件的内容
+func (m *Memory) ReadFile(name string) ([]byte, bool) {
+	data, ok := m.files[filepath.Clean(name)]
+	return data, ok
+}
@@ -0,0 +1,43 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sb/internal/ast"
+)
+
+func TestMemory(t *testing.T) {
+	dir := t.TempDir()
+	mem := NewMemory()
+	schema := &ast.Schema{
+		Structs: []ast.Struct{{Name: "Sim", Fields: []ast.StructField{{Name: "id", Type: ast.Type{Name: "u32", Kind: ast.KindBase}}}}},
+		Apis:    []ast.Api{{Name: "sim.get", Result: ast.Type{Name: "nil"}}},
+	}
+	gen := NewGoGenerator(Config{GoDir: dir, TplFS: TplFS, Output: mem, GoServer: ServerFunctions})
+	if err := gen.Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("dry run wrote %d entries to disk", len(entries))
+	}
+	if _, ok := mem.ReadFile(filepath.Join(dir, "sb", "struct_sim.go")); !ok {
+		t.Errorf("struct_sim.go not generated, files: %v", mem.Files())
+	}
+
+	// 已存在的处理函数不再生成
+	handler := filepath.Join(dir, "sb", "api.sim.get.go")
+	if err := Disk.WriteFile(handler, []byte("package sb\n")); err != nil {
+		t.Fatal(err)
+	}
+	mem = NewMemory()
+	gen.Config.Output = mem
+	if err := gen.Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mem.ReadFile(handler); ok {
+		t.Error("existing handler was regenerated")
+	}
+}
@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sb/internal/ast"
+	"sb/internal/descriptor"
+	"strings"
+)
+
+// 外部生成器插件 (类似 protoc 插件)
+//
+// 未注册的目标 <name> 由 PATH 中的可执行文件 sb-gen-<name> 提供。sb 将 PluginRequest
+// 以 JSON 写入插件的标准输入, 插件在标准输出返回 PluginResponse, 由 sb 写入目标的输出目录。
+// 插件的标准错误原样转发, 可用于输出日志; 退出码非 0 或 Error 非空时生成失败。
+
+// PluginPrefix 插件可执行文件名前缀
+const PluginPrefix = "sb-gen-"
+
+// PluginVersion 插件协议版本; 新增可选字段不改变版本号
+const PluginVersion = 1
+
+// PluginRequest 写入插件标准输入的请求
+type PluginRequest struct {
+	Version    int               `json:"version"`    // 协议版本 (PluginVersion)
+	Target     string            `json:"target"`     // 目标名称
+	Parameters map[string]string `json:"parameters"` // 目标配置中的 options
+	Schema     *descriptor.File  `json:"schema"`     // 与 sb descriptor 的输出相同
+}
+
+// PluginResponse 插件标准输出中的响应
+type PluginResponse struct {
+	Files []PluginFile `json:"files"`
+	Error string       `json:"error,omitempty"` // 非空时生成失败, 内容作为错误信息报告
+}
+
+// PluginFile 插件生成的文件
+type PluginFile struct {
+	Name    string `json:"name"`    // 相对输出目录的路径, 以 / 分隔, 不得包含 ..
+	Content string `json:"content"` // 文件内容
+}
+
+// lookupPlugin 在 PATH 中查找提供目标 name 的插件
+func lookupPlugin(name string) (Target, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return Target{}, false
+	}
+	exe, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return Target{}, false
+	}
+	return Target{
+		Name:        name,
+		Description: "插件 " + exe,
+		DefaultOut:  "./" + name,
+		New: func(tc TargetConfig, _ map[string]TargetConfig) (Generator, error) {
+			return &PluginGenerator{Name: name, Path: exe, Out: tc.Out, Parameters: tc.Options, Output: tc.Output}, nil
+		},
+	}, true
+}
+
+// PluginGenerator 调用外部插件生成代码
+type PluginGenerator struct {
+	Name       string            // 目标名称
+	Path       string            // 插件可执行文件
+	Out        string            // 输出目录
+	Parameters map[string]string // 传给插件的参数
+	Output     Output            // 为 nil 时直接写入磁盘
+}
+
+func (g *PluginGenerator) output() Output {
+	return Config{Output: g.Output}.output()
+}
+
+// OutputDir 插件文件写入的目录
+func (g *PluginGenerator) OutputDir(schema *ast.Schema) (string, error) {
+	return g.Out, nil
+}
+
+func (g *PluginGenerator) Generate(schema *ast.Schema) error {
+	req, err := json.Marshal(PluginRequest{
+		Version:    PluginVersion,
+		Target:     g.Name,
+		Parameters: g.Parameters,
+		Schema:     descriptor.FromSchema(schema),
+	})
+	if err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(g.Path)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("plugin %s: %w", g.Path, err)
+	}
+
+	var resp PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("plugin %s: invalid response: %w", g.Path, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("plugin %s: %s", g.Path, resp.Error)
+	}
+
+	// 先校验全部文件名, 避免写入一部分后才失败
+	for _, f := range resp.Files {
+		if !validRelPath(f.Name) {
+			return fmt.Errorf("plugin %s: invalid file name %q", g.Path, f.Name)
+		}
+	}
+	for _, f := range resp.Files {
+		dst := filepath.Join(g.Out, filepath.FromSlash(f.Name))
+		if err := g.output().WriteFile(dst, []byte(f.Content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validRelPath 是否为目录内的规范相对路径 (以 / 分隔, 不含 ..)
+func validRelPath(name string) bool {
+	return name != "" && !path.IsAbs(name) && !strings.Contains(name, `\`) &&
+		path.Clean(name) == name && name != "." && name != ".." && !strings.HasPrefix(name, "../")
+}
@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sb/internal/ast"
+)
+
+// writePlugin 在临时目录创建插件脚本并加入 PATH
+func writePlugin(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, PluginPrefix+name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestPlugin(t *testing.T) {
+	writePlugin(t, "echo", `req=$(cat)
+case "$req" in
+*'"parameters":{"lang":"dart"}'*'"name":"Sim"'*) ;;
+*) echo '{"error":"unexpected request"}'; exit 0 ;;
+esac
+echo '{"files":[{"name":"lib/sim.txt","content":"sim"}]}'
+`)
+	target, ok := LookupTarget("echo")
+	if !ok {
+		t.Fatal("plugin target not found")
+	}
+	out := t.TempDir()
+	gen, err := target.New(TargetConfig{Out: out, Options: map[string]string{"lang": "dart"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := &ast.Schema{Structs: []ast.Struct{{Name: "Sim"}}}
+	if err := gen.Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(out, "lib", "sim.txt"))
+	if err != nil || string(got) != "sim" {
+		t.Errorf("content = %q, %v", got, err)
+	}
+}
+
+func TestPlugin_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"error", `echo '{"error":"no dart sdk"}'`, "no dart sdk"},
+		{"exit", `exit 3`, "exit status 3"},
+		{"invalid json", `echo nope`, "invalid response"},
+		{"escape", `echo '{"files":[{"name":"../x","content":""}]}'`, `invalid file name "../x"`},
+		{"absolute", `echo '{"files":[{"name":"/x","content":""}]}'`, `invalid file name "/x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writePlugin(t, "bad", "cat >/dev/null\n"+tt.script+"\n")
+			target, ok := LookupTarget("bad")
+			if !ok {
+				t.Fatal("plugin target not found")
+			}
+			gen, _ := target.New(TargetConfig{Out: t.TempDir()}, nil)
+			err := gen.Generate(&ast.Schema{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderTargets(t *testing.T) {
+	writePlugin(t, "dart", "")
+	got, err := OrderTargets([]string{"dart", "doc", "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, target := range got {
+		names = append(names, target.Name)
+	}
+	if strings.Join(names, ",") != "go,doc,dart" {
+		t.Errorf("order = %v", names)
+	}
+	if _, err := OrderTargets([]string{"java"}); err == nil || !strings.Contains(err.Error(), "sb-gen-java") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+)
+
+// TargetConfig 单个生成目标的配置 (来自命令行或 sb.yaml)
+type TargetConfig struct {
+	Out     string            // 输出目录
+	Package string            // 包名 (go)
+	Subdir  string            // 相对 Out 的子目录 (go)
+	Import  string            // 完整导入路径 (go)
+	Tags    []string          // 附加的 struct tag (go)
+	Options map[string]string // 其余目标特定选项
+	TplFS   fs.FS             // 模板文件系统, 为 nil 时使用内置模板
+	Output  Output            // 生成结果的写入目标, 为 nil 时直接写入磁盘
+	File    string            // Schema 文件路径, 用于诊断
+	Lang    diag.Lang         // 诊断信息语言
+}
+
+// templates 目标使用的模板文件系统
+func (tc TargetConfig) templates() fs.FS {
+	if tc.TplFS == nil {
+		return TplFS
+	}
+	return tc.TplFS
+}
+
+// Factory 由目标配置创建生成器
+// all 为本次启用的全部目标配置 (含 tc 自身), 供需要参照其他目标的生成器 (如文档) 使用
+type Factory func(tc TargetConfig, all map[string]TargetConfig) (Generator, error)
+
+// Target 已注册的生成目标
+type Target struct {
+	Name        string
+	Description string
+	DefaultOut  string // 未配置输出目录时使用, 为空表示由生成器自行决定
+	Code        bool   // 是否生成代码; 未指定输出目录的文档写入各代码目标的目录
+	New         Factory
+}
+
+// OutputDirer 可选接口: 报告生成器写入的目录, 用于检测多个输入之间的输出冲突
+type OutputDirer interface {
+	OutputDir(schema *ast.Schema) (string, error)
+}
+
+// Noticer 可选接口: 生成后需要告知用户的信息, 如改写了手写的文件; -check 时不输出
+type Noticer interface {
+	Notices() []string
+}
+
+// targets 按注册顺序排列, 也是生成顺序
+var targets []Target
+
+// 内置目标; 文档排在代码之后, 以便引用代码目标的输出目录
+func init() {
+	Register(Target{
+		Name:        "go",
+		Description: "Go 结构体, 编解码与 RPC 服务端/客户端",
+		DefaultOut:  "./go",
+		Code:        true,
+		New: func(tc TargetConfig, _ map[string]TargetConfig) (Generator, error) {
+			cfg := goConfig(tc)
+			switch cfg.GoServer {
+			case "", ServerInterface, ServerFunctions:
+			default:
+				return nil, fmt.Errorf("unknown server option %q (%s|%s)", cfg.GoServer, ServerInterface, ServerFunctions)
+			}
+			switch cfg.GoHandlers {
+			case "", HandlersRewrite, HandlersReport:
+			default:
+				return nil, fmt.Errorf("unknown handlers option %q (%s|%s)", cfg.GoHandlers, HandlersRewrite, HandlersReport)
+			}
+			return NewGoGenerator(cfg), nil
+		},
+	})
+	Register(Target{
+		Name:        "ts",
+		Description: "TypeScript 类型, 编解码与 RPC 客户端",
+		DefaultOut:  "./ts",
+		Code:        true,
+		New: func(tc TargetConfig, _ map[string]TargetConfig) (Generator, error) {
+			return NewTsGenerator(Config{TsDir: tc.Out, TplFS: tc.templates(), Output: tc.Output}), nil
+		},
+	})
+	Register(Target{
+		Name:        "doc",
+		Description: "Markdown 接口文档 (DOC.md), 未指定输出目录时写入各代码目录",
+		New: func(tc TargetConfig, all map[string]TargetConfig) (Generator, error) {
+			return &DocGenerator{Config: Config{TplFS: tc.templates(), Output: tc.Output}, Out: tc.Out, Targets: all}, nil
+		},
+	})
+}
+
+// goConfig go 目标配置对应的生成配置
+func goConfig(tc TargetConfig) Config {
+	return Config{
+		GoDir:      tc.Out,
+		GoTag:      strings.Join(tc.Tags, ","),
+		GoPackage:  tc.Package,
+		GoSubdir:   tc.Subdir,
+		GoImport:   tc.Import,
+		GoServer:   tc.Options["server"],
+		GoHandlers: tc.Options["handlers"],
+		TplFS:      tc.templates(),
+		Output:     tc.Output,
+		File:       tc.File,
+		Lang:       tc.Lang,
+	}
+}
+
+// Register 注册生成目标, 通常在生成器所在文件的 init 中调用; 名称重复时 panic
+func Register(t Target) {
+	if t.Name == "" || t.New == nil {
+		panic("generator: Register requires Name and New")
+	}
+	if _, ok := LookupTarget(t.Name); ok {
+		panic("generator: target " + t.Name + " registered twice")
+	}
+	targets = append(targets, t)
+}
+
+// LookupTarget 按名称查找目标: 先查找已注册的目标, 再查找 PATH 中的插件 sb-gen-<name>
+func LookupTarget(name string) (Target, bool) {
+	i := slices.IndexFunc(targets, func(t Target) bool { return t.Name == name })
+	if i < 0 {
+		return lookupPlugin(name)
+	}
+	return targets[i], true
+}
+
+// OrderTargets 按生成顺序排列 names 对应的目标: 已注册的目标按注册顺序在前, 插件按名称在后
+func OrderTargets(names []string) ([]Target, error) {
+	var ordered, plugins []Target
+	for _, t := range targets {
+		if slices.Contains(names, t.Name) {
+			ordered = append(ordered, t)
+		}
+	}
+	for _, name := range slices.Sorted(slices.Values(names)) {
+		if slices.ContainsFunc(ordered, func(t Target) bool { return t.Name == name }) {
+			continue
+		}
+		t, ok := lookupPlugin(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown target %q (available: %s, or a plugin %s%s in PATH)", name, strings.Join(TargetNames(), ", "), PluginPrefix, name)
+		}
+		plugins = append(plugins, t)
+	}
+	return append(ordered, plugins...), nil
+}
+
+// Targets 全部已注册的目标 (按注册顺序)
+func Targets() []Target {
+	return slices.Clone(targets)
+}
+
+// TargetNames 全部已注册的目标名称 (按注册顺序)
+func TargetNames() []string {
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// codeDirs 全部启用的代码目标的输出目录 (按注册顺序)
+func codeDirs(schema *ast.Schema, all map[string]TargetConfig) ([]string, error) {
+	var dirs []string
+	for _, t := range targets {
+		tc, ok := all[t.Name]
+		if !ok || !t.Code {
+			continue
+		}
+		gen, err := t.New(tc, all)
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := gen.(OutputDirer); ok {
+			dir, err := d.OutputDir(schema)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
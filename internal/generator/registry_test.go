@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"sb/internal/ast"
+)
+
+func TestTargets(t *testing.T) {
+	if got, want := TargetNames(), []string{"go", "ts", "doc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TargetNames() = %v, want %v", got, want)
+	}
+	if _, ok := LookupTarget("java"); ok {
+		t.Error("LookupTarget(java) found an unregistered target")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate target did not panic")
+		}
+	}()
+	Register(Target{Name: "go", New: func(TargetConfig, map[string]TargetConfig) (Generator, error) { return nil, nil }})
+}
+
+func TestDocGenerator_Dirs(t *testing.T) {
+	root := t.TempDir()
+	schema := &ast.Schema{}
+	tests := []struct {
+		name    string
+		targets map[string]TargetConfig
+		want    []string
+	}{
+		{
+			name:    "CodeDirs",
+			targets: map[string]TargetConfig{"go": {Out: filepath.Join(root, "go")}, "ts": {Out: filepath.Join(root, "ts")}, "doc": {}},
+			want:    []string{filepath.Join(root, "go", "sb"), filepath.Join(root, "ts", "sb")},
+		},
+		{
+			name:    "TsOnly",
+			targets: map[string]TargetConfig{"ts": {Out: filepath.Join(root, "b")}, "doc": {}},
+			want:    []string{filepath.Join(root, "b", "sb")},
+		},
+		{
+			name:    "Out",
+			targets: map[string]TargetConfig{"go": {Out: filepath.Join(root, "c")}, "doc": {Out: filepath.Join(root, "docs")}},
+			want:    []string{filepath.Join(root, "docs")},
+		},
+		{
+			name:    "NoCode",
+			targets: map[string]TargetConfig{"doc": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := LookupTarget("doc")
+			gen, err := doc.New(tt.targets["doc"], tt.targets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := gen.Generate(schema); err != nil {
+				t.Fatal(err)
+			}
+			for _, dir := range tt.want {
+				if _, err := os.Stat(filepath.Join(dir, "DOC.md")); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
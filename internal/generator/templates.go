@@ -1,6 +1,62 @@
 package generator
 
-import "embed"
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
 
 //go:embed _tpl/*
-var TplFS embed.FS
\ No newline at end of file
+var TplFS embed.FS
+
+// TemplateDir 模板在 TplFS 中所在的目录
+const TemplateDir = "_tpl"
+
+// TemplateNames 内置模板的文件名 (按名称排序)
+func TemplateNames() []string {
+	entries, _ := fs.ReadDir(TplFS, TemplateDir)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// Overlay 返回以 dir 中的同名文件逐个覆盖内置模板的文件系统
+// dir 中出现内置模板之外的文件时报错, 以免文件名拼写错误被静默忽略; 以 . 开头的文件不检查
+func Overlay(dir string) (fs.FS, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := TemplateNames()
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if e.IsDir() || !slices.Contains(names, e.Name()) {
+			return nil, fmt.Errorf("%s: unknown template %q (available: %s)", dir, e.Name(), strings.Join(names, ", "))
+		}
+	}
+	return overlayFS{top: os.DirFS(dir), base: TplFS}, nil
+}
+
+type overlayFS struct {
+	top  fs.FS // 用户模板目录, 对应 base 中的 _tpl
+	base fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if dir, file := path.Split(name); dir == TemplateDir+"/" {
+		f, err := o.top.Open(file)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+	}
+	return o.base.Open(name)
+}
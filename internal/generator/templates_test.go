@@ -0,0 +1,44 @@
+package generator
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.api.tpl"), []byte("custom"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".keep"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := Overlay(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := fs.ReadFile(fsys, "_tpl/go.api.tpl")
+	if err != nil || string(got) != "custom" {
+		t.Errorf("overridden template = %q, %v", got, err)
+	}
+	got, err = fs.ReadFile(fsys, "_tpl/go.rpc.tpl")
+	want, _ := fs.ReadFile(TplFS, "_tpl/go.rpc.tpl")
+	if err != nil || string(got) != string(want) {
+		t.Errorf("built-in template not used: %v", err)
+	}
+}
+
+func TestOverlay_UnknownTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.apis.tpl"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Overlay(dir)
+	if err == nil || !strings.Contains(err.Error(), `unknown template "go.apis.tpl"`) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package generator
 
 import (
 	"bytes"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sb/internal/ast"
@@ -17,16 +18,17 @@ type TsGenerator struct {
 func NewTsGenerator(cfg Config) *TsGenerator {
 	g := &TsGenerator{Config: cfg}
 	g.FuncMap = template.FuncMap{
-		"PascalCase":  util.PascalCase,
-		"SnakeCase":   util.SnakeCase,
-		"CamelCase":   util.CamelCase,
-		"TsType":      g.getTsType,
-		"TsValue":     g.getTsValue,
-		"TsLogicType": g.getTsLogicType,
-		"IsBaseType":  func(t ast.Type) bool { return t.Kind == ast.KindBase },
-		"IsEnum":      func(t ast.Type) bool { return t.Kind == ast.KindEnum },
-		"IsStruct":    func(t ast.Type) bool { return t.Kind == ast.KindStruct },
-		"IsList":      func(t ast.Type) bool { return t.IsList },
+		"PascalCase":       util.PascalCase,
+		"SnakeCase":        util.SnakeCase,
+		"CamelCase":        util.CamelCase,
+		"TsType":           g.getTsType,
+		"TsValue":          g.getTsValue,
+		"TsLogicType":      g.getTsLogicType,
+		"IsBaseType":       func(t ast.Type) bool { return t.Kind == ast.KindBase },
+		"IsEnum":           func(t ast.Type) bool { return t.Kind == ast.KindEnum },
+		"IsStruct":         func(t ast.Type) bool { return t.Kind == ast.KindStruct },
+		"IsList":           func(t ast.Type) bool { return t.IsList },
+		"DeprecatedReason": DeprecatedReason,
 	}
 	return g
 }
@@ -65,12 +67,11 @@ func (g *TsGenerator) getTsLogicType(t ast.Type) string {
 
 func (g *TsGenerator) Generate(schema *ast.Schema) error {
 	targetDir := filepath.Join(g.Config.TsDir, "sb")
-	os.MkdirAll(targetDir, 0755)
 
 	// 0. 从嵌入文件系统中复制 type.ts
-	typeTs, err := g.Config.TplFS.ReadFile("_tpl/type.ts")
+	typeTs, err := fs.ReadFile(g.Config.TplFS, "_tpl/type.ts")
 	if err != nil { return err }
-	if err := os.WriteFile(filepath.Join(targetDir, "type.ts"), typeTs, 0644); err != nil { return err }
+	if err := g.Config.output().WriteFile(filepath.Join(targetDir, "type.ts"), typeTs); err != nil { return err }
 
 	// 1. 生成枚举
 	if err := g.executeTemplate("_tpl/ts.enum.tpl", filepath.Join(targetDir, "enum.ts"), map[string]any{
@@ -101,13 +102,13 @@ func (g *TsGenerator) Generate(schema *ast.Schema) error {
 }
 
 func (g *TsGenerator) executeTemplate(tplPath, destPath string, data any) error {
-	tplContent, err := g.Config.TplFS.ReadFile(tplPath)
+	tplContent, err := fs.ReadFile(g.Config.TplFS, tplPath)
 	if err != nil { return err }
 	tpl, err := template.New(filepath.Base(tplPath)).Funcs(g.FuncMap).Parse(string(tplContent))
 	if err != nil { return err }
 	var buf bytes.Buffer
 	if err := tpl.Execute(&buf, data); err != nil { return err }
-	return os.WriteFile(destPath, buf.Bytes(), 0644)
+	return g.Config.output().WriteFile(destPath, buf.Bytes())
 }
 
 func copyFile(src, dst string) error {
@@ -115,3 +116,8 @@ func copyFile(src, dst string) error {
 	if err != nil { return err }
 	return os.WriteFile(dst, data, 0644)
 }
+
+// OutputDir 生成代码所在目录 (<TsDir>/sb)
+func (g *TsGenerator) OutputDir(schema *ast.Schema) (string, error) {
+	return filepath.Join(g.Config.TsDir, "sb"), nil
+}
@@ -1,7 +1,6 @@
 package lexer
 
 import (
-	"fmt"
 	"strings"
 	"unicode"
 )
@@ -23,167 +22,196 @@ const (
 	TokenPipe     // |
 	TokenComma    // ,
 	TokenDot      // .
-		TokenArrow    // =>
-		TokenComment  // 注释
-	)
-	
-	type Token struct {
-		Type  TokenType
-		Value string
-		Line  int
-	}
-	
-	// Lexer 词法分析器状态
-	type Lexer struct {
-		input []rune // 完整的输入字符流
-		pos   int    // 当前处理的字符位置
-		line  int    // 当前行号 (用于错误报告)
-	}
-	
-	func New(input string) *Lexer {
-		return &Lexer{input: []rune(input), line: 1}
+	TokenArrow    // =>
+	TokenComment  // 注释
+	TokenAt       // @ (注解前缀)
+	TokenBang     // ! (API 错误类型前缀)
+)
+
+// Token 词法单元, Line/Col 为 Token 起始位置 (均从 1 开始, Col 按字符计)
+// TokenError 的 Value 为引发错误的原始字符, 或缺少结束引号的 Tag 字符串 (以引号开头)
+type Token struct {
+	Type  TokenType
+	Value string
+	Line  int
+	Col   int
+}
+
+// Lexer 词法分析器状态
+type Lexer struct {
+	input     []rune // 完整的输入字符流
+	pos       int    // 当前处理的字符位置
+	line      int    // 当前行号 (用于错误报告)
+	lineStart int    // 当前行首字符位置 (用于计算列号)
+
+	tokLine int // 当前 Token 起始行
+	tokCol  int // 当前 Token 起始列
+}
+
+func New(input string) *Lexer {
+	return &Lexer{input: []rune(input), line: 1}
+}
+
+// NextToken 获取下一个 Token (核心状态机)
+// 自动跳过空白, 处理标识符, 数字, 字符串及特殊符号
+func (l *Lexer) NextToken() Token {
+	l.skipWhitespace()
+	l.tokLine, l.tokCol = l.line, l.pos-l.lineStart+1
+
+	if l.pos >= len(l.input) {
+		return l.makeToken(TokenEOF, "")
 	}
-	
-	// NextToken 获取下一个 Token (核心状态机)
-	// 自动跳过空白, 处理标识符, 数字, 字符串及特殊符号
-	func (l *Lexer) NextToken() Token {
-		l.skipWhitespace()
-	
-		if l.pos >= len(l.input) {
-			return Token{Type: TokenEOF, Line: l.line}
-		}
-	
-		ch := l.input[l.pos]
-	
-		// 标识符 (Identifier)
-		if isIdentStart(ch) {
-			return l.readIdent()
-		}
-	
-		// 数字 (Number) - 支持负数
-		if unicode.IsDigit(ch) || (ch == '-' && unicode.IsDigit(l.peek())) {
-			return l.readNumber()
-		}
-	
-		// Tag 字符串 ("..." or `...`)
-		if ch == '"' || ch == '`' {
-			return l.readTag(ch)
-		}
-	
-		// 双字符 Token
-		if ch == '/' && l.peek() == '/' {
-			return l.readComment()
-		}
-		if ch == '=' && l.peek() == '>' {
-			l.pos += 2
-			return Token{Type: TokenArrow, Value: "=>", Line: l.line}
-		}
-	
-		// 单字符 Token
-		switch ch {
-		case '{':
-			return l.advanceAndMakeToken(TokenLBrace, "{")
-		case '}':
-			return l.advanceAndMakeToken(TokenRBrace, "}")
-		case '(':
-			return l.advanceAndMakeToken(TokenLParen, "(")
-		case ')':
-			return l.advanceAndMakeToken(TokenRParen, ")")
-		case '[':
-			return l.advanceAndMakeToken(TokenLBracket, "[")
-		case ']':
-			return l.advanceAndMakeToken(TokenRBracket, "]")
-		case '=':
-			return l.advanceAndMakeToken(TokenAssign, "=")
-		case '|':
-			return l.advanceAndMakeToken(TokenPipe, "|")
-		case ',':
-			return l.advanceAndMakeToken(TokenComma, ",")
-		case '.':
-			return l.advanceAndMakeToken(TokenDot, ".")
-		}
-	
-		// 错误处理: 遇到非法字符必须推进指针, 防止死循环
-		l.pos++
-		return Token{Type: TokenError, Value: fmt.Sprintf("未预期字符 %q", ch), Line: l.line}
+
+	ch := l.input[l.pos]
+
+	// 标识符 (Identifier)
+	if isIdentStart(ch) {
+		return l.readIdent()
 	}
-	
-	func (l *Lexer) advanceAndMakeToken(t TokenType, val string) Token {
-		l.pos++
-		return Token{Type: t, Value: val, Line: l.line}
+
+	// 数字 (Number) - 支持负数
+	if unicode.IsDigit(ch) || (ch == '-' && unicode.IsDigit(l.peek())) {
+		return l.readNumber()
 	}
-	
-	func (l *Lexer) peek() rune {
-		if l.pos+1 >= len(l.input) {
-			return 0
-		}
-		return l.input[l.pos+1]
+
+	// Tag 字符串 ("..." or `...`)
+	if ch == '"' || ch == '`' {
+		return l.readTag(ch)
 	}
-	
-	func (l *Lexer) skipWhitespace() {
-		for l.pos < len(l.input) {
-			ch := l.input[l.pos]
-			if ch == '\n' {
-				l.line++
-				l.pos++
-				continue
-			}
-			if unicode.IsSpace(ch) {
-				l.pos++
-				continue
-			}
-			break
-		}
+
+	// 双字符 Token
+	if ch == '/' && l.peek() == '/' {
+		return l.readComment()
 	}
-	
-	func isIdentStart(ch rune) bool {
-		return unicode.IsLetter(ch) || ch == '_'
+	if ch == '=' && l.peek() == '>' {
+		l.pos += 2
+		return l.makeToken(TokenArrow, "=>")
 	}
-	
-	func isIdentPart(ch rune) bool {
-		return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' || ch == '.'
+
+	// 单字符 Token
+	switch ch {
+	case '{':
+		return l.advanceAndMakeToken(TokenLBrace, "{")
+	case '}':
+		return l.advanceAndMakeToken(TokenRBrace, "}")
+	case '(':
+		return l.advanceAndMakeToken(TokenLParen, "(")
+	case ')':
+		return l.advanceAndMakeToken(TokenRParen, ")")
+	case '[':
+		return l.advanceAndMakeToken(TokenLBracket, "[")
+	case ']':
+		return l.advanceAndMakeToken(TokenRBracket, "]")
+	case '=':
+		return l.advanceAndMakeToken(TokenAssign, "=")
+	case '|':
+		return l.advanceAndMakeToken(TokenPipe, "|")
+	case ',':
+		return l.advanceAndMakeToken(TokenComma, ",")
+	case '.':
+		return l.advanceAndMakeToken(TokenDot, ".")
+	case '@':
+		return l.advanceAndMakeToken(TokenAt, "@")
+	case '!':
+		return l.advanceAndMakeToken(TokenBang, "!")
 	}
-	
-	func (l *Lexer) readIdent() Token {
-		start := l.pos
-		for l.pos < len(l.input) && isIdentPart(l.input[l.pos]) {
-			l.pos++
-		}
-		return Token{Type: TokenIdent, Value: string(l.input[start:l.pos]), Line: l.line}
+
+	// 错误处理: 遇到非法字符必须推进指针, 防止死循环
+	return l.advanceAndMakeToken(TokenError, string(ch))
+}
+
+func (l *Lexer) makeToken(t TokenType, val string) Token {
+	return Token{Type: t, Value: val, Line: l.tokLine, Col: l.tokCol}
+}
+
+func (l *Lexer) advanceAndMakeToken(t TokenType, val string) Token {
+	l.pos++
+	return l.makeToken(t, val)
+}
+
+func (l *Lexer) peek() rune {
+	if l.pos+1 >= len(l.input) {
+		return 0
 	}
-	
-	func (l *Lexer) readNumber() Token {
-		start := l.pos
-		if l.input[l.pos] == '-' {
-			l.pos++
+	return l.input[l.pos+1]
+}
+
+// newline 消费一个换行符并更新行列信息
+func (l *Lexer) newline() {
+	l.pos++
+	l.line++
+	l.lineStart = l.pos
+}
+
+func (l *Lexer) skipWhitespace() {
+	for l.pos < len(l.input) {
+		ch := l.input[l.pos]
+		if ch == '\n' {
+			l.newline()
+			continue
 		}
-		for l.pos < len(l.input) && unicode.IsDigit(l.input[l.pos]) {
+		if unicode.IsSpace(ch) {
 			l.pos++
+			continue
 		}
-		return Token{Type: TokenNumber, Value: string(l.input[start:l.pos]), Line: l.line}
+		break
+	}
+}
+
+func isIdentStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+func isIdentPart(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' || ch == '.'
+}
+
+func (l *Lexer) readIdent() Token {
+	start := l.pos
+	for l.pos < len(l.input) && isIdentPart(l.input[l.pos]) {
+		l.pos++
 	}
-	
-	func (l *Lexer) readTag(quote rune) Token {
-		start := l.pos
-		l.pos++ // 跳过起始引号
-		for l.pos < len(l.input) && l.input[l.pos] != quote {
-			if l.input[l.pos] == '\n' {
-				l.line++
+	return l.makeToken(TokenIdent, string(l.input[start:l.pos]))
+}
+
+func (l *Lexer) readNumber() Token {
+	start := l.pos
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+	for l.pos < len(l.input) && unicode.IsDigit(l.input[l.pos]) {
+		l.pos++
+	}
+	return l.makeToken(TokenNumber, string(l.input[start:l.pos]))
+}
+
+// readTag 读取 Tag 字符串; 与 Go 一致, "..." 不能跨行, `...` 可以跨行
+// 缺少结束引号时返回 TokenError, "..." 在行尾结束以免吞掉后续定义
+func (l *Lexer) readTag(quote rune) Token {
+	start := l.pos
+	l.pos++ // 跳过起始引号
+	for l.pos < len(l.input) && l.input[l.pos] != quote {
+		if l.input[l.pos] == '\n' {
+			if quote == '"' {
+				return l.makeToken(TokenError, strings.TrimRight(string(l.input[start:l.pos]), "\r"))
 			}
-			l.pos++
+			l.newline()
+			continue
 		}
-		if l.pos < len(l.input) {
-			l.pos++ // 跳过结束引号
-		}
-		return Token{Type: TokenIdent, Value: string(l.input[start:l.pos]), Line: l.line}
+		l.pos++
 	}
-	
-	func (l *Lexer) readComment() Token {
-		l.pos += 2 // 跳过 //
-		start := l.pos
-		for l.pos < len(l.input) && l.input[l.pos] != '\n' {
-			l.pos++
-		}
-		return Token{Type: TokenComment, Value: strings.TrimSpace(string(l.input[start:l.pos])), Line: l.line}
+	if l.pos == len(l.input) {
+		return l.makeToken(TokenError, string(l.input[start:l.pos]))
+	}
+	l.pos++ // 跳过结束引号
+	return l.makeToken(TokenIdent, string(l.input[start:l.pos]))
+}
+
+func (l *Lexer) readComment() Token {
+	l.pos += 2 // 跳过 //
+	start := l.pos
+	for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+		l.pos++
 	}
-	
\ No newline at end of file
+	return l.makeToken(TokenComment, strings.TrimSpace(string(l.input[start:l.pos])))
+}
@@ -0,0 +1,228 @@
+// Package lint 基于 ast.Schema 的风格与一致性检查
+//
+// 规则可通过配置文件整体关闭或调整级别, 也可在源码中用指令注释按行抑制:
+//
+//	// sb:lint-ignore naming,unused   写在行尾作用于本行, 单独成行作用于下一行
+//	// sb:lint-ignore-file unused     作用于整个文件
+//
+// 指令不列出规则时表示抑制全部规则。
+package lint
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/lexer"
+)
+
+// DefaultConfigFile 未指定 -config 时在当前目录查找的配置文件
+const DefaultConfigFile = "sblint.json"
+
+// Config 规则配置 (JSON)
+//
+//	{
+//	    "disable": ["missing-note"],
+//	    "severity": {"unused": "error"},
+//	    "field_limit_warn": 200
+//	}
+type Config struct {
+	Disable        []string          `json:"disable"`          // 关闭的规则
+	Severity       map[string]string `json:"severity"`         // 覆盖规则级别: error | warning
+	FieldLimitWarn int               `json:"field_limit_warn"` // 字段数达到该值时告警, 0 使用默认值
+}
+
+// defaultFieldLimitWarn 默认在字段数达到上限的约 80% 时告警
+const defaultFieldLimitWarn = 200
+
+// LoadConfig 读取并校验 JSON 配置文件
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate 校验规则名称与级别
+func (c Config) Validate() error {
+	for _, r := range c.Disable {
+		if !slices.Contains(Rules, r) {
+			return fmt.Errorf("unknown rule %q", r)
+		}
+	}
+	for r, s := range c.Severity {
+		if !slices.Contains(Rules, r) {
+			return fmt.Errorf("unknown rule %q", r)
+		}
+		if _, err := parseSeverity(s); err != nil {
+			return err
+		}
+	}
+	if c.FieldLimitWarn < 0 || c.FieldLimitWarn > ast.MaxStructFields {
+		return fmt.Errorf("field_limit_warn must be in range 0-%d", ast.MaxStructFields)
+	}
+	return nil
+}
+
+func parseSeverity(s string) (diag.Severity, error) {
+	switch s {
+	case "error":
+		return diag.SeverityError, nil
+	case "warning":
+		return diag.SeverityWarning, nil
+	}
+	return diag.SeverityWarning, fmt.Errorf("invalid severity %q (error|warning)", s)
+}
+
+// Linter 对单个 Schema 执行全部启用的规则
+type Linter struct {
+	Config Config
+	File   string    // 诊断中显示的文件名
+	Lang   diag.Lang // 诊断信息语言
+
+	diags diag.List
+}
+
+// New 创建 Linter
+func New(cfg Config) *Linter {
+	return &Linter{Config: cfg, Lang: diag.LangZH}
+}
+
+// Lint 检查已通过解析的 Schema; src 为原始源码, 用于识别抑制指令 (可为 nil)
+// 返回结果按位置排序, 同一位置同一规则只报告一次
+func (l *Linter) Lint(schema *ast.Schema, src []byte) diag.List {
+	l.diags = nil
+	checks := map[string]func(*ast.Schema){
+		RuleMissingNote:   l.checkMissingNote,
+		RuleNaming:        l.checkNaming,
+		RuleEnumGap:       l.checkEnumGap,
+		RuleEnumDupID:     l.checkEnumDupID,
+		RuleFieldLimit:    l.checkFieldLimit,
+		RuleUnused:        l.checkUnused,
+		RuleNameCollision: l.checkNameCollision,
+	}
+	for _, rule := range Rules {
+		if !slices.Contains(l.Config.Disable, rule) {
+			checks[rule](schema)
+		}
+	}
+
+	sup := parseSuppressions(src)
+	result := slices.DeleteFunc(l.diags, func(d diag.Diagnostic) bool {
+		return sup.suppressed(d.Code, d.Line)
+	})
+	slices.SortStableFunc(result, func(a, b diag.Diagnostic) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Col, b.Col))
+	})
+	// 嵌入展开后同一源码位置可能被多个结构体检查到, 只保留一条
+	return slices.CompactFunc(result, func(a, b diag.Diagnostic) bool {
+		return a.Line == b.Line && a.Col == b.Col && a.Code == b.Code
+	})
+}
+
+// report 记录一条诊断, 级别取配置覆盖值或规则默认值
+func (l *Linter) report(pos ast.Pos, rule string, msg diag.Message, args ...any) {
+	l.reportSeverity(pos, rule, defaultSeverity[rule], msg, args...)
+}
+
+func (l *Linter) reportSeverity(pos ast.Pos, rule string, sev diag.Severity, msg diag.Message, args ...any) {
+	if s, ok := l.Config.Severity[rule]; ok {
+		sev, _ = parseSeverity(s)
+	}
+	l.diags = append(l.diags, diag.Diagnostic{
+		File:     l.File,
+		Line:     pos.Line,
+		Col:      pos.Col,
+		Severity: sev,
+		Code:     rule,
+		Message:  msg.Format(l.Lang, args...),
+	})
+}
+
+// --- 抑制指令 ---
+
+const (
+	directiveIgnore     = "sb:lint-ignore"
+	directiveIgnoreFile = "sb:lint-ignore-file"
+)
+
+// ruleSet 被抑制的规则集合, all 表示全部规则
+type ruleSet struct {
+	all   bool
+	rules map[string]bool
+}
+
+func (s *ruleSet) add(rules []string) {
+	if len(rules) == 0 {
+		s.all = true
+		return
+	}
+	if s.rules == nil {
+		s.rules = make(map[string]bool)
+	}
+	for _, r := range rules {
+		s.rules[r] = true
+	}
+}
+
+func (s ruleSet) has(rule string) bool { return s.all || s.rules[rule] }
+
+type suppressions struct {
+	file  ruleSet
+	lines map[int]*ruleSet
+}
+
+// parseSuppressions 扫描源码中的指令注释
+// 行尾指令作用于所在行; 单独成行的指令作用于下一行
+func parseSuppressions(src []byte) suppressions {
+	sup := suppressions{lines: make(map[int]*ruleSet)}
+	if src == nil {
+		return sup
+	}
+	lx := lexer.New(string(src))
+	prevLine := 0
+	for tok := lx.NextToken(); tok.Type != lexer.TokenEOF; tok = lx.NextToken() {
+		trailing := tok.Line == prevLine
+		prevLine = tok.Line
+		if tok.Type != lexer.TokenComment {
+			continue
+		}
+		name, rest, _ := strings.Cut(tok.Value, " ")
+		rules := strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' })
+		switch name {
+		case directiveIgnoreFile:
+			sup.file.add(rules)
+		case directiveIgnore:
+			line := tok.Line
+			if !trailing {
+				line++
+			}
+			if sup.lines[line] == nil {
+				sup.lines[line] = &ruleSet{}
+			}
+			sup.lines[line].add(rules)
+		}
+	}
+	return sup
+}
+
+func (s suppressions) suppressed(rule string, line int) bool {
+	if s.file.has(rule) {
+		return true
+	}
+	rs := s.lines[line]
+	return rs != nil && rs.has(rule)
+}
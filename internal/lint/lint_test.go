@@ -0,0 +1,261 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/lexer"
+	"sb/internal/parser"
+)
+
+func lintSource(t *testing.T, src string, cfg Config) diag.List {
+	t.Helper()
+	schema, err := parser.New(lexer.New(src)).ParseSchema()
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	return New(cfg).Lint(schema, []byte(src))
+}
+
+// codesOf 诊断列表简化为 "行:代码" 便于比较
+func codesOf(diags diag.List) []string {
+	var out []string
+	for _, d := range diags {
+		out = append(out, fmt.Sprintf("%d:%s", d.Line, d.Code))
+	}
+	return out
+}
+
+// TestLint_Rules 每条规则的典型触发场景 (只启用被测规则)
+func TestLint_Rules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		input string
+		want  []string
+	}{
+		{
+			name: "Missing Note",
+			rule: RuleMissingNote,
+			input: `
+User {
+    id   u32 // 编号
+    name text
+}
+user.get(id u32) => User
+// 列表
+user.list() => [User]
+`,
+			want: []string{"4:missing-note", "6:missing-note"},
+		},
+		{
+			name: "Missing Note Embedded Reported Once",
+			rule: RuleMissingNote,
+			input: `
+Base { id u32 }
+Admin {
+    Base
+    role text // 角色
+}
+`,
+			want: []string{"2:missing-note"},
+		},
+		{
+			name: "Naming",
+			rule: RuleNaming,
+			input: `
+user_info { userId u32 }
+Status = ok | Err
+User.Get(Id user_info) => nil
+`,
+			want: []string{"2:naming", "2:naming", "3:naming", "4:naming", "4:naming"},
+		},
+		{
+			name: "Enum Gap",
+			rule: RuleEnumGap,
+			input: `
+A = X | Y | Z
+B = X(0) | Y(2) | Z(6)
+`,
+			want: []string{"3:enum-gap"},
+		},
+		{
+			name: "Enum Duplicate ID",
+			rule: RuleEnumDupID,
+			input: `
+Status = Ok(0) | Err(1)
+    | Fail(1)
+`,
+			want: []string{"3:enum-duplicate-id"},
+		},
+		{
+			name: "Unused",
+			rule: RuleUnused,
+			input: `
+Level = Low | High
+Unused = A | B
+Base { id u32 }
+Admin {
+    Base
+    level Level
+}
+Orphan { id u32 }
+admin.get(id u32) => Admin
+`,
+			want: []string{"3:unused", "9:unused"},
+		},
+		{
+			name: "Name Collision",
+			rule: RuleNameCollision,
+			input: `
+SimOrder2 { id u32 }
+sim_order_2 {
+    id  u32
+    _id u32
+}
+sim.get(sim_order u8, simOrder u8) => nil
+sim_get() => nil
+`,
+			want: []string{"3:name-collision", "5:name-collision", "7:name-collision", "8:name-collision"},
+		},
+		{
+			name: "Generated Names",
+			rule: RuleNameCollision,
+			input: `
+GetCountRequest { id u32 }
+UserService { id u32 }
+E = A | B
+EFault { id u32 }
+get_count(page u8) => u8
+user.get(id u32) => UserService ! E
+all.get() => nil
+unary() => nil
+ServerOptions { id u32 }
+LocalTransport { id u32 }
+`,
+			want: []string{"4:name-collision", "6:name-collision", "7:name-collision", "8:name-collision", "9:name-collision", "10:name-collision", "11:name-collision"},
+		},
+		{
+			name: "Enum Variant Collides With Type",
+			rule: RuleNameCollision,
+			input: `
+AB = C | D
+A = BC
+`,
+			want: []string{"3:name-collision"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var disable []string
+			for _, r := range Rules {
+				if r != tt.rule {
+					disable = append(disable, r)
+				}
+			}
+			got := codesOf(lintSource(t, tt.input, Config{Disable: disable}))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLint_FieldLimit 接近上限告警, 超过上限报错
+func TestLint_FieldLimit(t *testing.T) {
+	build := func(n int) string {
+		var b strings.Builder
+		b.WriteString("Big {\n")
+		for i := range n {
+			fmt.Fprintf(&b, "    f%d u8 // 字段\n", i)
+		}
+		b.WriteString("}\n")
+		return b.String()
+	}
+	cfg := Config{Disable: []string{RuleUnused}, FieldLimitWarn: 10}
+
+	if got := lintSource(t, build(9), cfg); len(got) != 0 {
+		t.Errorf("9 fields: unexpected diagnostics %v", got)
+	}
+	got := lintSource(t, build(10), cfg)
+	if len(got) != 1 || got[0].Code != RuleFieldLimit || got[0].Severity != diag.SeverityWarning {
+		t.Errorf("10 fields: got %v, want one field-limit warning", got)
+	}
+	got = lintSource(t, build(ast.MaxStructFields+1), cfg)
+	if len(got) != 1 || got[0].Severity != diag.SeverityError {
+		t.Errorf("%d fields: got %v, want one field-limit error", ast.MaxStructFields+1, got)
+	}
+}
+
+// TestLint_Suppression 行内与文件级抑制指令; 指令注释不会成为文档注释
+func TestLint_Suppression(t *testing.T) {
+	src := `
+// sb:lint-ignore-file enum-gap
+// 用户
+// sb:lint-ignore unused
+User {
+    id   u32 // sb:lint-ignore missing-note
+    name text
+}
+Status = Ok(0) | Err(5)
+Level = Low | High // sb:lint-ignore
+`
+	got := codesOf(lintSource(t, src, Config{}))
+	want := []string{"7:missing-note", "9:unused"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	schema, _ := parser.New(lexer.New(src)).ParseSchema()
+	if note := schema.Structs[0].Note; note != "用户" {
+		t.Errorf("struct note = %q, want %q", note, "用户")
+	}
+	if note := schema.Structs[0].Fields[0].Note; note != "" {
+		t.Errorf("field note = %q, want empty", note)
+	}
+}
+
+// TestLint_Config 配置校验与级别覆盖
+func TestLint_Config(t *testing.T) {
+	if err := (Config{Disable: []string{"no-such-rule"}}).Validate(); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+	if err := (Config{Severity: map[string]string{RuleUnused: "fatal"}}).Validate(); err == nil {
+		t.Error("expected error for invalid severity")
+	}
+
+	cfg := Config{
+		Disable:  []string{RuleMissingNote},
+		Severity: map[string]string{RuleUnused: "error"},
+	}
+	got := lintSource(t, "Orphan { id u32 }\n", cfg)
+	if len(got) != 1 || got[0].Code != RuleUnused || got[0].Severity != diag.SeverityError {
+		t.Errorf("got %v, want one unused error", got)
+	}
+
+	cfg = Config{
+		Disable:  []string{RuleMissingNote, RuleNaming, RuleUnused},
+		Severity: map[string]string{RuleNameCollision: "warning"},
+	}
+	got = lintSource(t, "Client { id u32 }\n", cfg)
+	if len(got) != 1 || got[0].Code != RuleNameCollision || got[0].Severity != diag.SeverityWarning {
+		t.Errorf("got %v, want one name-collision warning", got)
+	}
+}
+
+// TestLint_Lang 英文诊断信息
+func TestLint_Lang(t *testing.T) {
+	schema, _ := parser.New(lexer.New("Orphan { id u32 // 编号\n}\n")).ParseSchema()
+	l := New(Config{})
+	l.File = "a.sb"
+	l.Lang = diag.LangEN
+	got := l.Lint(schema, nil)
+	want := "a.sb:1:1: warning[unused]: struct Orphan is not referenced by any struct or API"
+	if len(got) != 1 || got[0].String() != want {
+		t.Errorf("got %v, want %q", got, want)
+	}
+}
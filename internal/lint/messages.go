@@ -0,0 +1,62 @@
+package lint
+
+import (
+	"sb/internal/diag"
+	"sb/internal/names"
+)
+
+// 规则名称, 同时作为诊断代码
+const (
+	RuleMissingNote   = "missing-note"
+	RuleNaming        = "naming"
+	RuleEnumGap       = "enum-gap"
+	RuleEnumDupID     = "enum-duplicate-id"
+	RuleFieldLimit    = "field-limit"
+	RuleUnused        = "unused"
+	RuleNameCollision = names.Code
+)
+
+// Rules 全部规则 (按执行顺序)
+var Rules = []string{
+	RuleMissingNote,
+	RuleNaming,
+	RuleEnumGap,
+	RuleEnumDupID,
+	RuleFieldLimit,
+	RuleUnused,
+	RuleNameCollision,
+}
+
+// defaultSeverity 规则的默认级别; 会导致生成代码无法编译或数据歧义的为错误
+var defaultSeverity = map[string]diag.Severity{
+	RuleMissingNote:   diag.SeverityWarning,
+	RuleNaming:        diag.SeverityWarning,
+	RuleEnumGap:       diag.SeverityWarning,
+	RuleEnumDupID:     diag.SeverityError,
+	RuleFieldLimit:    diag.SeverityWarning,
+	RuleUnused:        diag.SeverityWarning,
+	RuleNameCollision: diag.SeverityError,
+}
+
+var (
+	msgMissingApiNote   = diag.Message{ZH: "API %s 缺少注释", EN: "API %s has no comment"}
+	msgMissingFieldNote = diag.Message{ZH: "字段 %s.%s 缺少注释", EN: "field %s.%s has no comment"}
+	msgNaming           = diag.Message{ZH: "%s %s 应使用 %s 命名", EN: "%s %s should be %s"}
+	msgEnumGap          = diag.Message{ZH: "枚举 %s 的数值不连续, 缺少 %s", EN: "enum %s has a gap in its values: %s missing"}
+	msgEnumDupID        = diag.Message{ZH: "枚举成员 %s.%s 的数值 %d 与 %s 重复", EN: "enum variant %s.%s reuses value %d of %s"}
+	msgFieldLimitNear   = diag.Message{ZH: "结构体 %s 有 %d 个字段, 接近上限 %d", EN: "struct %s has %d fields, approaching the limit of %d"}
+	msgFieldLimitOver   = diag.Message{ZH: "结构体 %s 有 %d 个字段, 超过上限 %d", EN: "struct %s has %d fields, exceeding the limit of %d"}
+	msgUnused           = diag.Message{ZH: "%s %s 未被任何结构体或 API 引用", EN: "%s %s is not referenced by any struct or API"}
+)
+
+// 消息中引用的名词
+var (
+	nounStruct      = diag.Message{ZH: "结构体", EN: "struct"}
+	nounEnum        = diag.Message{ZH: "枚举", EN: "enum"}
+	nounEnumVariant = diag.Message{ZH: "枚举成员", EN: "enum variant"}
+	nounField       = diag.Message{ZH: "字段", EN: "field"}
+	nounApi         = diag.Message{ZH: "API", EN: "API"}
+	nounArg         = diag.Message{ZH: "参数", EN: "argument"}
+	nounPascalCase  = diag.Message{ZH: "PascalCase", EN: "PascalCase"}
+	nounSnakeCase   = diag.Message{ZH: "snake_case", EN: "snake_case"}
+)
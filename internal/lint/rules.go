@@ -0,0 +1,170 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/names"
+)
+
+var (
+	pascalCaseRe = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	snakeCaseRe  = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+)
+
+// checkMissingNote API 与字段应带有注释 (会输出到生成代码与 DOC.md)
+func (l *Linter) checkMissingNote(schema *ast.Schema) {
+	for _, s := range schema.Structs {
+		for _, f := range s.OwnFields() {
+			if f.Note == "" {
+				l.report(f.Pos, RuleMissingNote, msgMissingFieldNote, s.Name, f.Name)
+			}
+		}
+	}
+	for _, api := range schema.Apis {
+		if api.Note == "" {
+			l.report(api.Pos, RuleMissingNote, msgMissingApiNote, api.Name)
+		}
+	}
+}
+
+// checkNaming 类型与枚举成员使用 PascalCase, 字段, 参数与 API 各段使用 snake_case
+func (l *Linter) checkNaming(schema *ast.Schema) {
+	pascal := func(pos ast.Pos, noun diag.Message, name string) {
+		if !pascalCaseRe.MatchString(name) {
+			l.report(pos, RuleNaming, msgNaming, noun.Format(l.Lang), name, nounPascalCase.Format(l.Lang))
+		}
+	}
+	snake := func(pos ast.Pos, noun diag.Message, name string) {
+		for _, part := range strings.Split(name, ".") {
+			if !snakeCaseRe.MatchString(part) {
+				l.report(pos, RuleNaming, msgNaming, noun.Format(l.Lang), name, nounSnakeCase.Format(l.Lang))
+				return
+			}
+		}
+	}
+
+	for _, s := range schema.Structs {
+		pascal(s.Pos, nounStruct, s.Name)
+		for _, f := range s.OwnFields() {
+			snake(f.Pos, nounField, f.Name)
+		}
+	}
+	for _, e := range schema.Enums {
+		pascal(e.Pos, nounEnum, e.Name)
+		for _, c := range e.Children {
+			pascal(c.Pos, nounEnumVariant, c.Name)
+		}
+	}
+	for _, api := range schema.Apis {
+		snake(api.Pos, nounApi, api.Name)
+		for _, arg := range api.Args {
+			snake(arg.Pos, nounArg, arg.Name)
+		}
+	}
+}
+
+// checkEnumGap 枚举数值应当连续, 空洞通常意味着遗漏或删除了成员
+func (l *Linter) checkEnumGap(schema *ast.Schema) {
+	for _, e := range schema.Enums {
+		ids := make([]int, 0, len(e.Children))
+		for _, c := range e.Children {
+			ids = append(ids, int(c.ID))
+		}
+		slices.Sort(ids)
+		ids = slices.Compact(ids)
+
+		var missing []string
+		for i := 1; i < len(ids); i++ {
+			from, to := ids[i-1]+1, ids[i]-1
+			switch {
+			case from == to:
+				missing = append(missing, fmt.Sprint(from))
+			case from < to:
+				missing = append(missing, fmt.Sprintf("%d-%d", from, to))
+			}
+		}
+		if len(missing) > 0 {
+			l.report(e.Pos, RuleEnumGap, msgEnumGap, e.Name, strings.Join(missing, ", "))
+		}
+	}
+}
+
+// checkEnumDupID 同一枚举内数值重复会导致解码结果无法区分
+func (l *Linter) checkEnumDupID(schema *ast.Schema) {
+	for _, e := range schema.Enums {
+		seen := make(map[uint8]string)
+		for _, c := range e.Children {
+			if prev, ok := seen[c.ID]; ok {
+				l.report(c.Pos, RuleEnumDupID, msgEnumDupID, e.Name, c.Name, c.ID, prev)
+				continue
+			}
+			seen[c.ID] = c.Name
+		}
+	}
+}
+
+// checkFieldLimit 字段数 (含嵌入展开) 接近或超过编码上限
+func (l *Linter) checkFieldLimit(schema *ast.Schema) {
+	warn := l.Config.FieldLimitWarn
+	if warn == 0 {
+		warn = defaultFieldLimitWarn
+	}
+	for _, s := range schema.Structs {
+		n := len(s.Fields)
+		switch {
+		case n > ast.MaxStructFields:
+			l.reportSeverity(s.Pos, RuleFieldLimit, diag.SeverityError, msgFieldLimitOver, s.Name, n, ast.MaxStructFields)
+		case n >= warn:
+			l.report(s.Pos, RuleFieldLimit, msgFieldLimitNear, s.Name, n, ast.MaxStructFields)
+		}
+	}
+}
+
+// checkUnused 未被任何字段, 嵌入或 API 引用的结构体与枚举
+func (l *Linter) checkUnused(schema *ast.Schema) {
+	used := make(map[string]bool)
+	for _, s := range schema.Structs {
+		for _, f := range s.Fields {
+			used[f.Type.Name] = true
+		}
+		for _, embed := range s.Embeds {
+			used[embed.Name] = true
+		}
+	}
+	for _, api := range schema.Apis {
+		for _, arg := range api.Args {
+			used[arg.Type.Name] = true
+		}
+		used[api.Result.Name] = true
+		if api.Error != nil {
+			used[api.Error.Name] = true
+		}
+	}
+
+	for _, s := range schema.Structs {
+		if !used[s.Name] {
+			l.report(s.Pos, RuleUnused, msgUnused, nounStruct.Format(l.Lang), s.Name)
+		}
+	}
+	for _, e := range schema.Enums {
+		if !used[e.Name] {
+			l.report(e.Pos, RuleUnused, msgUnused, nounEnum.Format(l.Lang), e.Name)
+		}
+	}
+}
+
+// checkNameCollision 名称经 PascalCase / SnakeCase 等转换后在生成代码中冲突, 或与生成代码中的固定名称冲突
+// 与代码生成共用 names.Check, 只按配置调整级别
+func (l *Linter) checkNameCollision(schema *ast.Schema) {
+	for _, d := range names.Check(schema, l.File, l.Lang) {
+		if s, ok := l.Config.Severity[RuleNameCollision]; ok {
+			d.Severity, _ = parseSeverity(s)
+		}
+		l.diags = append(l.diags, d)
+	}
+}
@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/lexer"
+	"sb/internal/parser"
+)
+
+// symbolKind 文档中一处名称出现的类别
+type symbolKind int
+
+const (
+	symStruct  symbolKind = iota // 结构体定义
+	symEnum                      // 枚举定义
+	symTypeRef                   // 类型引用 (字段类型, 嵌入, 参数或返回类型)
+	symField                     // 字段定义
+	symVariant                   // 枚举成员定义
+	symApi                       // API 定义
+	symArg                       // API 参数定义
+)
+
+// occurrence 名称在源码中的一次出现
+type occurrence struct {
+	kind   symbolKind
+	name   string // 源码中的名称
+	pos    ast.Pos
+	length int // 按字符计
+
+	// 以下字段按类别填写, 供悬停提示使用
+	typ     ast.Type
+	parent  string // 字段所属结构体, 成员所属枚举, 参数所属 API
+	note    string
+	deprec  *ast.Deprecation
+	variant ast.EnumChild
+}
+
+func (o occurrence) contains(line, col int) bool {
+	return o.pos.Line == line && col >= o.pos.Col && col < o.pos.Col+o.length
+}
+
+// isType 结构体/枚举的定义或引用, 可跳转与查找引用
+func (o occurrence) isType() bool {
+	return o.kind == symStruct || o.kind == symEnum || o.kind == symTypeRef
+}
+
+// document 已打开的文档及其分析结果; 每次内容变化时整体重建
+type document struct {
+	uri    string
+	text   string
+	lines  []string
+	schema *ast.Schema
+	diags  diag.List
+	occurs []occurrence
+}
+
+func newDocument(uri, text string, lang diag.Lang) *document {
+	d := &document{uri: uri, text: text, lines: strings.Split(text, "\n")}
+	p := parser.New(lexer.New(text))
+	p.File = uriToPath(uri)
+	p.Lang = lang
+	d.schema, d.diags = p.Parse()
+	d.index()
+	return d
+}
+
+// uriToPath file:// URI 转为本地路径, 解析失败时原样返回
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// index 由 AST 收集全部名称出现位置
+// 嵌入展开后的字段与基础结构体共享位置, 只记录结构体自身声明的字段
+func (d *document) index() {
+	add := func(o occurrence) {
+		if o.pos.Line == 0 || o.name == "" {
+			return
+		}
+		o.length = utf8.RuneCountInString(o.name)
+		d.occurs = append(d.occurs, o)
+	}
+	addType := func(t ast.Type) {
+		if t.Name != "nil" {
+			add(occurrence{kind: symTypeRef, name: t.Name, pos: t.Pos, typ: t})
+		}
+	}
+
+	for _, s := range d.schema.Structs {
+		add(occurrence{kind: symStruct, name: s.Name, pos: s.Pos, note: s.Note})
+		for _, e := range s.Embeds {
+			addType(e)
+		}
+		for _, f := range s.OwnFields() {
+			add(occurrence{kind: symField, name: f.Name, pos: f.Pos, typ: f.Type, parent: s.Name, note: f.Note, deprec: f.Deprecated})
+			addType(f.Type)
+		}
+	}
+	for _, e := range d.schema.Enums {
+		add(occurrence{kind: symEnum, name: e.Name, pos: e.Pos, note: e.Note})
+		for _, c := range e.Children {
+			add(occurrence{kind: symVariant, name: c.Name, pos: c.Pos, parent: e.Name, note: c.Note, deprec: c.Deprecated, variant: c})
+		}
+	}
+	for _, api := range d.schema.Apis {
+		add(occurrence{kind: symApi, name: api.Name, pos: api.Pos, note: api.Note, deprec: api.Deprecated})
+		for _, arg := range api.Args {
+			add(occurrence{kind: symArg, name: arg.Name, pos: arg.Pos, typ: arg.Type, parent: api.Name})
+			addType(arg.Type)
+		}
+		addType(api.Result)
+		if api.Error != nil {
+			addType(*api.Error)
+		}
+	}
+}
+
+// occurrenceAt 光标所在的名称
+func (d *document) occurrenceAt(pos Position) (occurrence, bool) {
+	line, col := d.toSource(pos)
+	for _, o := range d.occurs {
+		if o.contains(line, col) {
+			return o, true
+		}
+	}
+	return occurrence{}, false
+}
+
+// definition 类型名称对应的定义
+func (d *document) definition(name string) (occurrence, bool) {
+	for _, o := range d.occurs {
+		if (o.kind == symStruct || o.kind == symEnum) && o.name == name {
+			return o, true
+		}
+	}
+	return occurrence{}, false
+}
+
+// --- 位置换算: 源码位置为 1 起始的行与字符列, LSP 为 0 起始的行与 UTF-16 列 ---
+
+func (d *document) line(n int) string {
+	if n < 0 || n >= len(d.lines) {
+		return ""
+	}
+	return strings.TrimSuffix(d.lines[n], "\r")
+}
+
+// toSource LSP 位置转为源码行列
+func (d *document) toSource(pos Position) (line, col int) {
+	units := 0
+	col = 1
+	for _, r := range d.line(pos.Line) {
+		if units >= pos.Character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		col++
+	}
+	return pos.Line + 1, col
+}
+
+// toPosition 源码行列转为 LSP 位置
+func (d *document) toPosition(line, col int) Position {
+	units := 0
+	n := 1
+	for _, r := range d.line(line - 1) {
+		if n >= col {
+			break
+		}
+		units += utf16.RuneLen(r)
+		n++
+	}
+	return Position{Line: max(line-1, 0), Character: units}
+}
+
+// rangeOf 从源码位置开始, 长度为 length 个字符的范围
+func (d *document) rangeOf(pos ast.Pos, length int) Range {
+	return Range{Start: d.toPosition(pos.Line, pos.Col), End: d.toPosition(pos.Line, pos.Col+length)}
+}
+
+// wordLength 源码位置处标识符的字符数, 不是标识符时为 1
+func (d *document) wordLength(line, col int) int {
+	runes := []rune(d.line(line - 1))
+	n := 0
+	for i := col - 1; i >= 0 && i < len(runes) && isWordRune(runes[i]); i++ {
+		n++
+	}
+	return max(n, 1)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// fullRange 覆盖整个文档的范围
+func (d *document) fullRange() Range {
+	last := len(d.lines)
+	return Range{End: d.toPosition(last, utf8.RuneCountInString(d.line(last-1))+1)}
+}
@@ -0,0 +1,290 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/format"
+	"sb/internal/generator"
+)
+
+// baseTypes 基础类型及其说明 (按 README 中的顺序)
+var baseTypes = []struct {
+	name string
+	desc diag.Message
+}{
+	{"u8", diag.Message{ZH: "无符号 8 位整数", EN: "unsigned 8-bit integer"}},
+	{"u16", diag.Message{ZH: "无符号 16 位整数", EN: "unsigned 16-bit integer"}},
+	{"u32", diag.Message{ZH: "无符号 32 位整数", EN: "unsigned 32-bit integer"}},
+	{"u64", diag.Message{ZH: "无符号 64 位整数", EN: "unsigned 64-bit integer"}},
+	{"i8", diag.Message{ZH: "有符号 8 位整数", EN: "signed 8-bit integer"}},
+	{"i16", diag.Message{ZH: "有符号 16 位整数", EN: "signed 16-bit integer"}},
+	{"i32", diag.Message{ZH: "有符号 32 位整数", EN: "signed 32-bit integer"}},
+	{"i64", diag.Message{ZH: "有符号 64 位整数", EN: "signed 64-bit integer"}},
+	{"f32", diag.Message{ZH: "32 位浮点数", EN: "32-bit float"}},
+	{"f64", diag.Message{ZH: "64 位浮点数", EN: "64-bit float"}},
+	{"bool", diag.Message{ZH: "布尔值", EN: "boolean"}},
+	{"text", diag.Message{ZH: "字符串, 最大 65535 字节", EN: "string, at most 65535 bytes"}},
+	{"bin", diag.Message{ZH: "二进制数据, 最大 65535 字节", EN: "binary data, at most 65535 bytes"}},
+}
+
+var (
+	labelDeprecated = diag.Message{ZH: "已弃用", EN: "Deprecated"}
+	labelNil        = diag.Message{ZH: "无返回数据", EN: "no response body"}
+)
+
+// --- 诊断 ---
+
+func (s *Server) diagnostics(d *document) []Diagnostic {
+	result := make([]Diagnostic, 0, len(d.diags))
+	for _, dg := range d.diags {
+		line, col := max(dg.Line, 1), max(dg.Col, 1)
+		sev := severityError
+		switch dg.Severity {
+		case diag.SeverityWarning:
+			sev = severityWarning
+		case diag.SeverityInfo:
+			sev = severityInformation
+		}
+		result = append(result, Diagnostic{
+			Range:    d.rangeOf(ast.Pos{Line: line, Col: col}, d.wordLength(line, col)),
+			Severity: sev,
+			Code:     dg.Code,
+			Source:   "sb",
+			Message:  dg.Message,
+		})
+	}
+	return result
+}
+
+// --- 悬停提示 ---
+
+func (s *Server) hover(d *document, pos Position) *Hover {
+	o, ok := d.occurrenceAt(pos)
+	if !ok {
+		return nil
+	}
+	var code, note string
+	var deprec *ast.Deprecation
+	switch o.kind {
+	case symStruct, symEnum:
+		code, note = s.describeType(d, o.name)
+	case symTypeRef:
+		if o.typ.Kind == ast.KindBase {
+			code, note = o.name, baseTypeDesc(o.name).Format(s.Lang)
+		} else {
+			code, note = s.describeType(d, o.name)
+		}
+	case symField:
+		code = fmt.Sprintf("%s.%s %s", o.parent, o.name, typeString(o.typ))
+		note, deprec = o.note, o.deprec
+	case symVariant:
+		code = fmt.Sprintf("%s.%s = %d", o.parent, o.name, o.variant.ID)
+		note, deprec = o.note, o.deprec
+	case symApi:
+		if i := slices.IndexFunc(d.schema.Apis, func(a ast.Api) bool { return a.Name == o.name }); i >= 0 {
+			code = apiSignature(d.schema.Apis[i])
+			if d.schema.Apis[i].Idempotent {
+				code = "@idempotent\n" + code
+			}
+		}
+		note, deprec = o.note, o.deprec
+	case symArg:
+		code = fmt.Sprintf("%s %s", o.name, typeString(o.typ))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "```sb\n%s\n```", code)
+	if deprec != nil {
+		fmt.Fprintf(&b, "\n\n**%s**: %s", labelDeprecated.Format(s.Lang), generator.DeprecatedReason(deprec))
+	}
+	if note != "" {
+		fmt.Fprintf(&b, "\n\n%s", note)
+	}
+	r := d.rangeOf(o.pos, o.length)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: b.String()}, Range: &r}
+}
+
+// describeType 结构体或枚举的源码形式与注释
+func (s *Server) describeType(d *document, name string) (code, note string) {
+	if i := slices.IndexFunc(d.schema.Structs, func(st ast.Struct) bool { return st.Name == name }); i >= 0 {
+		st := d.schema.Structs[i]
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s {\n", st.Name)
+		for _, f := range st.Fields {
+			fmt.Fprintf(&b, "    %s %s", f.Name, typeString(f.Type))
+			if f.Note != "" {
+				fmt.Fprintf(&b, " // %s", f.Note)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}")
+		return b.String(), st.Note
+	}
+	if i := slices.IndexFunc(d.schema.Enums, func(e ast.Enum) bool { return e.Name == name }); i >= 0 {
+		e := d.schema.Enums[i]
+		variants := make([]string, len(e.Children))
+		for j, c := range e.Children {
+			variants[j] = fmt.Sprintf("%s(%d)", c.Name, c.ID)
+		}
+		return e.Name + " = " + strings.Join(variants, " | "), e.Note
+	}
+	return name, ""
+}
+
+func baseTypeDesc(name string) diag.Message {
+	for _, t := range baseTypes {
+		if t.name == name {
+			return t.desc
+		}
+	}
+	return diag.Message{}
+}
+
+func typeString(t ast.Type) string {
+	if t.IsList {
+		return "[" + t.Name + "]"
+	}
+	return t.Name
+}
+
+func apiSignature(api ast.Api) string {
+	args := make([]string, len(api.Args))
+	for i, a := range api.Args {
+		args[i] = a.Name + " " + typeString(a.Type)
+	}
+	sig := fmt.Sprintf("%s(%s) => %s", api.Name, strings.Join(args, ", "), typeString(api.Result))
+	if api.Error != nil {
+		sig += " ! " + typeString(*api.Error)
+	}
+	return sig
+}
+
+// --- 跳转与引用 ---
+
+func (s *Server) definition(d *document, pos Position) []Location {
+	o, ok := d.occurrenceAt(pos)
+	if !ok || !o.isType() {
+		return nil
+	}
+	def, ok := d.definition(o.name)
+	if !ok {
+		return nil
+	}
+	return []Location{{URI: d.uri, Range: d.rangeOf(def.pos, def.length)}}
+}
+
+func (s *Server) references(d *document, pos Position, includeDecl bool) []Location {
+	o, ok := d.occurrenceAt(pos)
+	if !ok || !o.isType() {
+		return nil
+	}
+	var result []Location
+	seen := make(map[ast.Pos]bool)
+	for _, ref := range d.occurs {
+		if !ref.isType() || ref.name != o.name || seen[ref.pos] {
+			continue
+		}
+		if ref.kind != symTypeRef && !includeDecl {
+			continue
+		}
+		seen[ref.pos] = true
+		result = append(result, Location{URI: d.uri, Range: d.rangeOf(ref.pos, ref.length)})
+	}
+	return result
+}
+
+// --- 补全 ---
+
+// completion 默认补全类型名称; 光标前为 "枚举名." 时补全该枚举的成员 (用于注释与注解中引用成员)
+func (s *Server) completion(d *document, pos Position) []CompletionItem {
+	line, col := d.toSource(pos)
+	before := string([]rune(d.line(line - 1))[:col-1])
+
+	word := before[strings.LastIndexFunc(before, func(r rune) bool { return !isWordRune(r) })+1:]
+	if i := strings.LastIndex(word, "."); i >= 0 {
+		enumName := word[:i]
+		for _, e := range d.schema.Enums {
+			if e.Name != enumName {
+				continue
+			}
+			items := make([]CompletionItem, len(e.Children))
+			for j, c := range e.Children {
+				items[j] = CompletionItem{Label: c.Name, Kind: completionEnumMember, Detail: fmt.Sprintf("%s.%s = %d", e.Name, c.Name, c.ID), Documentation: c.Note}
+			}
+			return items
+		}
+		return nil
+	}
+	if strings.Contains(before, "//") {
+		return nil
+	}
+
+	var items []CompletionItem
+	// API 的错误类型只能是枚举
+	if strings.Contains(before, "=>") && strings.Contains(before, "!") {
+		for _, e := range d.schema.Enums {
+			items = append(items, CompletionItem{Label: e.Name, Kind: completionEnum, Documentation: e.Note})
+		}
+		return items
+	}
+	for _, st := range d.schema.Structs {
+		items = append(items, CompletionItem{Label: st.Name, Kind: completionStruct, Documentation: st.Note})
+	}
+	for _, e := range d.schema.Enums {
+		items = append(items, CompletionItem{Label: e.Name, Kind: completionEnum, Documentation: e.Note})
+	}
+	for _, t := range baseTypes {
+		items = append(items, CompletionItem{Label: t.name, Kind: completionKeyword, Detail: t.desc.Format(s.Lang)})
+	}
+	if strings.Contains(before, "=>") {
+		items = append(items, CompletionItem{Label: "nil", Kind: completionKeyword, Detail: labelNil.Format(s.Lang)})
+	}
+	return items
+}
+
+// --- 文档大纲 ---
+
+func (s *Server) documentSymbols(d *document) []DocumentSymbol {
+	symbol := func(name, detail string, kind int, pos ast.Pos, deprec *ast.Deprecation) DocumentSymbol {
+		r := d.rangeOf(pos, len([]rune(name)))
+		return DocumentSymbol{Name: name, Detail: detail, Kind: kind, Deprecated: deprec != nil, Range: r, SelectionRange: r}
+	}
+
+	var result []DocumentSymbol
+	for _, st := range d.schema.Structs {
+		sym := symbol(st.Name, st.Note, symbolStruct, st.Pos, nil)
+		for _, f := range st.OwnFields() {
+			sym.Children = append(sym.Children, symbol(f.Name, typeString(f.Type), symbolField, f.Pos, f.Deprecated))
+		}
+		result = append(result, sym)
+	}
+	for _, e := range d.schema.Enums {
+		sym := symbol(e.Name, e.Note, symbolEnum, e.Pos, nil)
+		for _, c := range e.Children {
+			sym.Children = append(sym.Children, symbol(c.Name, fmt.Sprint(c.ID), symbolEnumMember, c.Pos, c.Deprecated))
+		}
+		result = append(result, sym)
+	}
+	for _, api := range d.schema.Apis {
+		result = append(result, symbol(api.Name, apiSignature(api), symbolFunction, api.Pos, api.Deprecated))
+	}
+	slices.SortStableFunc(result, func(a, b DocumentSymbol) int {
+		return a.Range.Start.Line - b.Range.Start.Line
+	})
+	return result
+}
+
+// --- 格式化 ---
+
+// formatting 存在语法错误时不做修改
+func (s *Server) formatting(d *document) []TextEdit {
+	out, err := format.Source([]byte(d.text))
+	if err != nil || string(out) == d.text {
+		return []TextEdit{}
+	}
+	return []TextEdit{{Range: d.fullRange(), NewText: string(out)}}
+}
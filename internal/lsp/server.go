@@ -0,0 +1,247 @@
+// Package lsp .sb 文件的语言服务器 (Language Server Protocol, stdio 传输)
+//
+// 基于现有的词法与语法分析器: 每次文档变化都整体重新解析, 由 ast.Schema
+// 中记录的位置建立名称索引, 提供诊断, 悬停提示, 跳转定义, 查找引用,
+// 补全, 文档大纲与格式化。
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"sb/internal/diag"
+)
+
+// ErrExitWithoutShutdown 客户端未发送 shutdown 就要求退出 (按协议应以非零状态退出)
+var ErrExitWithoutShutdown = errors.New("exit without shutdown")
+
+// Server 单线程处理请求的语言服务器
+type Server struct {
+	Lang   diag.Lang   // 诊断与提示信息语言, 可被 initializationOptions.lang 覆盖
+	Logger *log.Logger // 可选, 记录协议错误
+
+	in          *bufio.Reader
+	out         io.Writer
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer 创建从 in 读取请求, 向 out 写出响应的服务器
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		Lang: diag.LangZH,
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: make(map[string]*document),
+	}
+}
+
+// Run 处理消息直到收到 exit 通知或输入结束
+func (s *Server) Run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rpcErr *responseError
+			if errors.As(err, &rpcErr) {
+				s.logf("%v", err)
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+	}
+}
+
+// handle 分发一条消息; 只有写出失败才返回错误
+func (s *Server) handle(msg *message) error {
+	result, err := s.dispatch(msg)
+	if msg.ID == nil {
+		if err != nil {
+			s.logf("%s: %v", msg.Method, err)
+		}
+		return nil
+	}
+	if err != nil {
+		var rpcErr *responseError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		return writeMessage(s.out, &errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr})
+	}
+	return writeMessage(s.out, &response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *Server) dispatch(msg *message) (any, error) {
+	if !s.initialized && msg.Method != "initialize" {
+		if msg.ID == nil {
+			return nil, nil // 初始化前的通知直接丢弃
+		}
+		return nil, &responseError{Code: codeNotInitialized, Message: "server not initialized"}
+	}
+	if s.shutdown && msg.ID != nil {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(params)
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			return nil, s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publish(params.TextDocument.URI, []Diagnostic{})
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		d, err := s.docParams(msg, &params, &params.TextDocument)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		if h := s.hover(d, params.Position); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		d, err := s.docParams(msg, &params, &params.TextDocument)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return s.definition(d, params.Position), nil
+	case "textDocument/references":
+		var params ReferenceParams
+		d, err := s.docParams(msg, &params, &params.TextDocument)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return s.references(d, params.Position, params.Context.IncludeDeclaration), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		d, err := s.docParams(msg, &params, &params.TextDocument)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return s.completion(d, params.Position), nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		d, err := s.docParams(msg, &params, &params.TextDocument)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return s.documentSymbols(d), nil
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		d, err := s.docParams(msg, &params, &params.TextDocument)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return s.formatting(d), nil
+	}
+
+	if msg.ID == nil {
+		return nil, nil // 未知通知按协议忽略
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func (s *Server) initialize(params InitializeParams) (*InitializeResult, error) {
+	if lang := params.InitializationOptions.Lang; lang != "" {
+		l, err := diag.ParseLang(lang)
+		if err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		s.Lang = l
+	}
+	s.initialized = true
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           syncFull,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			CompletionProvider:         &CompletionOptions{TriggerCharacters: []string{"[", "."}},
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "sb-lsp"},
+	}, nil
+}
+
+func unmarshalParams(msg *message, v any) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("%s: %v", msg.Method, err)}
+	}
+	return nil
+}
+
+// docParams 解析参数并取出对应的已打开文档; 文档未打开时返回 nil
+func (s *Server) docParams(msg *message, params any, doc *TextDocumentIdentifier) (*document, error) {
+	if err := unmarshalParams(msg, params); err != nil {
+		return nil, err
+	}
+	return s.docs[doc.URI], nil
+}
+
+// update 重新分析文档并推送诊断
+func (s *Server) update(uri, text string) error {
+	d := newDocument(uri, text, s.Lang)
+	s.docs[uri] = d
+	return s.publish(uri, s.diagnostics(d))
+}
+
+func (s *Server) publish(uri string, diags []Diagnostic) error {
+	return writeMessage(s.out, &notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  PublishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	})
+}
@@ -0,0 +1,296 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///tmp/test.sb"
+
+const testSource = `// 订单状态
+OrderStatus = Pending | Paid(2) // 已支付
+
+// 基础信息
+Info {
+    id  u32  // 编号
+    tag text
+}
+
+// 订单
+Order {
+    Info
+    status OrderStatus
+    items  [Info]      // 明细
+}
+
+// 查询订单
+order.get(id u32) => Order
+@deprecated("使用 order.get")
+order.find(info Info) => nil
+`
+
+// rpc 构造请求 (id > 0) 或通知 (id == 0)
+func rpc(id int, method string, params any) map[string]any {
+	m := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		m["id"] = id
+	}
+	return m
+}
+
+func pos(uri string, line, char int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": char},
+	}
+}
+
+// session 依次发送消息 (自动补上 initialize 与 shutdown/exit), 返回按 id 索引的响应与全部通知
+func session(t *testing.T, msgs ...map[string]any) (map[int]json.RawMessage, []json.RawMessage) {
+	t.Helper()
+	var in bytes.Buffer
+	all := append([]map[string]any{
+		rpc(1000, "initialize", map[string]any{}),
+		rpc(0, "initialized", map[string]any{}),
+		rpc(0, "textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": testURI, "version": 1, "text": testSource},
+		}),
+	}, msgs...)
+	all = append(all, rpc(1001, "shutdown", nil), rpc(0, "exit", nil))
+	for _, m := range all {
+		if err := writeMessage(&in, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	responses := make(map[int]json.RawMessage)
+	var notes []json.RawMessage
+	r := bufio.NewReader(&out)
+	for {
+		var raw struct {
+			ID     *int            `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  json.RawMessage `json:"error"`
+			Params json.RawMessage `json:"params"`
+		}
+		msg, err := readRaw(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(msg, &raw); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case raw.ID == nil:
+			notes = append(notes, raw.Params)
+		case raw.Error != nil:
+			responses[*raw.ID] = raw.Error
+		default:
+			responses[*raw.ID] = raw.Result
+		}
+	}
+	return responses, notes
+}
+
+// readRaw 读取一条消息的原始 JSON
+func readRaw(r *bufio.Reader) ([]byte, error) {
+	var length int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			length = n
+		}
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+func TestServer_Diagnostics(t *testing.T) {
+	_, notes := session(t, rpc(0, "textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": testURI, "version": 2},
+		"contentChanges": []map[string]any{{"text": "User {\n    id Missing\n}\n"}},
+	}))
+	if len(notes) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(notes))
+	}
+	var first, second PublishDiagnosticsParams
+	json.Unmarshal(notes[0], &first)
+	json.Unmarshal(notes[1], &second)
+	if len(first.Diagnostics) != 0 {
+		t.Errorf("valid source: unexpected diagnostics %+v", first.Diagnostics)
+	}
+	want := Diagnostic{
+		Range:    Range{Start: Position{1, 7}, End: Position{1, 14}},
+		Severity: severityError,
+		Code:     "undefined-type",
+		Source:   "sb",
+		Message:  "未定义类型: Missing",
+	}
+	if len(second.Diagnostics) != 1 || second.Diagnostics[0] != want {
+		t.Errorf("got %+v, want %+v", second.Diagnostics, want)
+	}
+}
+
+func TestServer_Features(t *testing.T) {
+	responses, _ := session(t,
+		rpc(1, "textDocument/hover", pos(testURI, 12, 12)),      // status OrderStatus
+		rpc(2, "textDocument/definition", pos(testURI, 13, 13)), // items [Info]
+		rpc(3, "textDocument/references", map[string]any{
+			"textDocument": map[string]any{"uri": testURI},
+			"position":     map[string]any{"line": 4, "character": 1}, // Info 定义
+			"context":      map[string]any{"includeDeclaration": false},
+		}),
+		rpc(4, "textDocument/hover", pos(testURI, 19, 3)), // order.find
+		rpc(5, "textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": testURI}}),
+		rpc(6, "textDocument/hover", pos(testURI, 5, 9)), // u32
+		rpc(7, "unknown/method", nil),
+	)
+
+	var hover Hover
+	json.Unmarshal(responses[1], &hover)
+	if !strings.Contains(hover.Contents.Value, "OrderStatus = Pending(0) | Paid(2)") || !strings.Contains(hover.Contents.Value, "订单状态") {
+		t.Errorf("hover type: %q", hover.Contents.Value)
+	}
+
+	var defs []Location
+	json.Unmarshal(responses[2], &defs)
+	if len(defs) != 1 || defs[0].Range != (Range{Start: Position{4, 0}, End: Position{4, 4}}) {
+		t.Errorf("definition: %+v", defs)
+	}
+
+	var refs []Location
+	json.Unmarshal(responses[3], &refs)
+	var lines []int
+	for _, r := range refs {
+		lines = append(lines, r.Range.Start.Line)
+	}
+	if want := []int{11, 13, 19}; !slices.Equal(lines, want) {
+		t.Errorf("references lines = %v, want %v", lines, want)
+	}
+
+	json.Unmarshal(responses[4], &hover)
+	if !strings.Contains(hover.Contents.Value, "order.find(info Info) => nil") || !strings.Contains(hover.Contents.Value, "已弃用") {
+		t.Errorf("hover api: %q", hover.Contents.Value)
+	}
+
+	var symbols []DocumentSymbol
+	json.Unmarshal(responses[5], &symbols)
+	var names []string
+	for _, s := range symbols {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "OrderStatus,Info,Order,order.get,order.find" {
+		t.Errorf("symbols = %s", got)
+	}
+	if len(symbols[2].Children) != 2 {
+		t.Errorf("Order children = %+v, want own fields only", symbols[2].Children)
+	}
+
+	json.Unmarshal(responses[6], &hover)
+	if !strings.Contains(hover.Contents.Value, "无符号 32 位整数") {
+		t.Errorf("hover base type: %q", hover.Contents.Value)
+	}
+
+	var rpcErr responseError
+	json.Unmarshal(responses[7], &rpcErr)
+	if rpcErr.Code != codeMethodNotFound {
+		t.Errorf("unknown method: %+v", rpcErr)
+	}
+}
+
+func TestServer_Completion(t *testing.T) {
+	src := "Status = Ok | Err\nUser {\n    s St\n}\n// 参见 Status.\nget() => \n"
+	responses, _ := session(t,
+		rpc(0, "textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///c.sb", "version": 1, "text": src},
+		}),
+		rpc(1, "textDocument/completion", pos("file:///c.sb", 2, 8)),
+		rpc(2, "textDocument/completion", pos("file:///c.sb", 4, 13)),
+		rpc(3, "textDocument/completion", pos("file:///c.sb", 5, 9)),
+	)
+	labels := func(id int) []string {
+		var items []CompletionItem
+		json.Unmarshal(responses[id], &items)
+		var out []string
+		for _, it := range items {
+			out = append(out, it.Label)
+		}
+		return out
+	}
+
+	types := labels(1)
+	if !slices.Contains(types, "Status") || !slices.Contains(types, "User") || !slices.Contains(types, "u32") || slices.Contains(types, "nil") {
+		t.Errorf("type completion = %v", types)
+	}
+	if got := labels(2); strings.Join(got, ",") != "Ok,Err" {
+		t.Errorf("variant completion = %v", got)
+	}
+	if got := labels(3); !slices.Contains(got, "nil") {
+		t.Errorf("result completion = %v, want nil included", got)
+	}
+}
+
+func TestServer_Formatting(t *testing.T) {
+	src := "User{\nid u32\n  name   text // 名称\n}\n"
+	responses, _ := session(t,
+		rpc(0, "textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///f.sb", "version": 1, "text": src},
+		}),
+		rpc(1, "textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": "file:///f.sb"}}),
+	)
+	var edits []TextEdit
+	json.Unmarshal(responses[1], &edits)
+	want := "User {\n    id   u32\n    name text // 名称\n}\n"
+	if len(edits) != 1 || edits[0].NewText != want {
+		t.Fatalf("edits = %+v", edits)
+	}
+	if edits[0].Range.End != (Position{4, 0}) {
+		t.Errorf("edit range = %+v", edits[0].Range)
+	}
+}
+
+func TestServer_ExitWithoutShutdown(t *testing.T) {
+	var in, out bytes.Buffer
+	writeMessage(&in, rpc(0, "exit", nil))
+	if err := NewServer(&in, &out).Run(); !errors.Is(err, ErrExitWithoutShutdown) {
+		t.Errorf("Run = %v, want ErrExitWithoutShutdown", err)
+	}
+}
+
+func TestDocument_UTF16(t *testing.T) {
+	d := newDocument("file:///u.sb", "// 😀 表情\nA { id u8 }\n", "zh")
+	// 😀 占两个 UTF-16 单元
+	if p := d.toPosition(1, 6); p != (Position{0, 6}) {
+		t.Errorf("toPosition = %+v", p)
+	}
+	if line, col := d.toSource(Position{0, 6}); line != 1 || col != 6 {
+		t.Errorf("toSource = %d:%d", line, col)
+	}
+}
@@ -0,0 +1,16 @@
+package names
+
+import "sb/internal/diag"
+
+var msgCollision = diag.Message{ZH: "%s 与 %s 生成的名称 %s 冲突", EN: "%s collides with %s: both generate %s"}
+
+// 消息中引用的名词
+var (
+	nounStruct      = diag.Message{ZH: "结构体", EN: "struct"}
+	nounEnum        = diag.Message{ZH: "枚举", EN: "enum"}
+	nounEnumVariant = diag.Message{ZH: "枚举成员", EN: "enum variant"}
+	nounField       = diag.Message{ZH: "字段", EN: "field"}
+	nounApi         = diag.Message{ZH: "API", EN: "API"}
+	nounArg         = diag.Message{ZH: "参数", EN: "argument"}
+	nounGenerated   = diag.Message{ZH: "生成代码中的", EN: "the generated"}
+)
@@ -0,0 +1,177 @@
+// Package names 生成代码中由 Schema 派生的名称与固定名称, 以及两者之间的冲突检查
+//
+// Schema 中不同的名称经 PascalCase / SnakeCase 等转换后可能生成同一个标识符,
+// 也可能与模板直接写出的固定标识符 (如 ServerOptions, Client) 同名, 生成的代码因此无法编译。
+// lint 的 name-collision 规则与代码生成共用这里的检查, 代码生成在写入任何文件之前拒绝这样的 Schema。
+package names
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/util"
+)
+
+// Code 名称冲突的诊断代码, 同时是 lint 的规则名
+const Code = "name-collision"
+
+// Reserved 生成的 Go 包中与 Schema 无关的固定包级标识符 (模板直接写出的类型, 函数, 变量与常量)。
+// 未导出的标识符只登记可能与 API 的 snake_case 函数名相同的 (仅由小写字母, 数字与下划线组成)。
+// 修改模板时同步更新, generator 的 TestGoReservedNames 检查生成代码中的固定标识符均已登记
+var Reserved = slices.Concat(baseTypeNames(), []string{
+	// type.go
+	"Serializable", "Deserializable", "GetAll", "SetAll", "GetBit", "SetBit", "get", "set",
+	// rpc.go
+	"RpcErrCode", "RpcOk", "RpcNoConn", "RpcTimeout", "RpcNotExist", "RpcNotAuth", "RpcReqErr", "RpcRespErr", "RpcBizErr", "RpcTooLarge",
+	"RpcError", "NewRpcError", "InternalError", "AsRpcError",
+	"Client", "NewClient", "Transport", "DefaultRetryCodes", "DefaultRetryDelay", "DefaultRetryMaxDelay",
+	"UnaryInfo", "UnaryHandler", "UnaryInterceptor", "intercept",
+	// api._.go
+	"ServerOptions", "DefaultMaxBodySize", "ErrorReport", "ErrorReporter", "Middleware",
+	"Router", "Services", "RegisterAll", "LocalTransport", "NewLocalTransport",
+})
+
+// baseTypeNames type.go 为每个基础类型 T 生成的 T, TList 及其 Get/Set/Eq 函数
+func baseTypeNames() []string {
+	var result []string
+	for _, t := range []string{"I8", "U8", "I16", "U16", "I32", "U32", "I64", "U64", "F32", "F64", "Bool", "Bin", "Text"} {
+		for _, name := range []string{t, t + "List"} {
+			result = append(result, name, "Get"+name, "Set"+name, "Eq"+name)
+		}
+	}
+	return result
+}
+
+// clientMethods Client 的固定导出方法, 不能与 API 生成的客户端方法同名
+var clientMethods = []string{
+	"SetHeader", "GetHeader", "RemoveHeader", "SetAuthorization", "GetAuthorization", "RemoveAuthorization", "IsAuthorized",
+}
+
+// structMethods 生成的结构体类型的方法, 不能与字段同名
+var structMethods = []string{"Get", "Set", "Eq"}
+
+type origin struct {
+	desc string
+	pos  ast.Pos
+}
+
+// namespace 生成代码中的一个命名空间 (如 Go 包级标识符, 某个结构体的字段)
+type namespace map[string]origin
+
+type checker struct {
+	file  string
+	lang  diag.Lang
+	diags diag.List
+}
+
+// claim 登记生成名称, 已被占用时在后出现的定义处报告冲突
+func (c *checker) claim(ns namespace, generated, desc string, pos ast.Pos) {
+	if prev, ok := ns[generated]; ok {
+		c.diags = append(c.diags, diag.Diagnostic{
+			File:     c.file,
+			Line:     pos.Line,
+			Col:      pos.Col,
+			Severity: diag.SeverityError,
+			Code:     Code,
+			Message:  msgCollision.Format(c.lang, desc, prev.desc, generated),
+		})
+		return
+	}
+	ns[generated] = origin{desc: desc, pos: pos}
+}
+
+func (c *checker) describe(noun diag.Message, name string) string {
+	return noun.Format(c.lang) + " " + name
+}
+
+// reserve 登记固定名称
+func (c *checker) reserve(ns namespace, fixed []string) {
+	for _, name := range fixed {
+		c.claim(ns, name, c.describe(nounGenerated, name), ast.Pos{})
+	}
+}
+
+// apiModule API 所属模块 (名称中第一个 . 之前的部分), 无模块的 API 归入 api 模块
+func apiModule(name string) string {
+	if m, _, ok := strings.Cut(name, "."); ok {
+		return m
+	}
+	return "api"
+}
+
+// Check 检查 Schema 中的名称在生成代码中是否冲突, 诊断按位置排序, 级别均为错误
+// 例如 sim_order2 与 sim_order_2 都会生成 SimOrder2, 结构体 GetCountRequest 与 API get_count 的请求参数结构体同名
+func Check(schema *ast.Schema, file string, lang diag.Lang) diag.List {
+	c := &checker{file: file, lang: lang}
+	pkg := make(namespace)    // Go 包级标识符
+	files := make(namespace)  // 结构体文件名 struct_<snake>.go / .ts
+	client := make(namespace) // Client 的方法
+	c.reserve(pkg, Reserved)
+	c.reserve(client, clientMethods)
+
+	for _, s := range schema.Structs {
+		desc := c.describe(nounStruct, s.Name)
+		name := util.PascalCase(s.Name)
+		for _, generated := range []string{name, name + "List", "Get" + name, "Set" + name, "Eq" + name} {
+			c.claim(pkg, generated, desc, s.Pos)
+		}
+		c.claim(files, "struct_"+util.SnakeCase(s.Name), desc, s.Pos)
+
+		// 字段与嵌入的结构体 (Go 中以类型名作为字段名) 同处结构体的字段与方法集合
+		fields := make(namespace)
+		c.reserve(fields, structMethods)
+		for _, e := range s.Embeds {
+			c.claim(fields, util.PascalCase(e.Name), c.describe(nounStruct, e.Name), e.Pos)
+		}
+		for _, f := range s.Fields {
+			c.claim(fields, util.PascalCase(f.Name), c.describe(nounField, s.Name+"."+f.Name), f.Pos)
+		}
+	}
+	errorEnums := make(map[string]bool)
+	for _, api := range schema.Apis {
+		if api.Error != nil {
+			errorEnums[api.Error.Name] = true
+		}
+	}
+	for _, e := range schema.Enums {
+		desc := c.describe(nounEnum, e.Name)
+		name := util.PascalCase(e.Name)
+		c.claim(pkg, name, desc, e.Pos)
+		c.claim(pkg, name+"List", desc, e.Pos)
+		if errorEnums[e.Name] {
+			c.claim(pkg, name+"Fault", desc, e.Pos)
+		}
+		for _, v := range e.Children {
+			c.claim(pkg, name+util.PascalCase(v.Name), c.describe(nounEnumVariant, e.Name+"."+v.Name), v.Pos)
+		}
+	}
+	modules := make(map[string]bool)
+	for _, api := range schema.Apis {
+		desc := c.describe(nounApi, api.Name)
+		name := util.PascalCase(api.Name)
+		c.claim(pkg, name+"Handler", desc, api.Pos)
+		c.claim(pkg, name+"Request", desc, api.Pos)
+		c.claim(pkg, util.SnakeCase(api.Name), desc, api.Pos)
+		c.claim(client, name, desc, api.Pos)
+		// 模块的服务接口, 注册函数与处理函数适配类型, 在模块的第一个 API 处登记
+		if module := apiModule(api.Name); !modules[module] {
+			modules[module] = true
+			c.claim(pkg, util.PascalCase(module)+"Service", desc, api.Pos)
+			c.claim(pkg, "Register"+util.PascalCase(module), desc, api.Pos)
+			c.claim(pkg, util.CamelCase(module)+"Funcs", desc, api.Pos)
+		}
+
+		args := make(namespace)
+		for _, arg := range api.Args {
+			c.claim(args, util.CamelCase(arg.Name), c.describe(nounArg, api.Name+"("+arg.Name+")"), arg.Pos)
+		}
+	}
+
+	slices.SortStableFunc(c.diags, func(a, b diag.Diagnostic) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Col, b.Col))
+	})
+	return c.diags
+}
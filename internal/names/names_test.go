@@ -0,0 +1,64 @@
+package names
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+
+	"sb/internal/ast"
+	"sb/internal/diag"
+	"sb/internal/util"
+)
+
+// reservedSchema 使用固定名称 name 的 Schema: 导出名称定义为结构体, 其余定义为同名 API (snake_case 函数)
+func reservedSchema(name string) *ast.Schema {
+	pos := ast.Pos{Line: 3, Col: 1}
+	if token.IsExported(name) {
+		return &ast.Schema{Structs: []ast.Struct{{Name: name, Pos: pos}}}
+	}
+	return &ast.Schema{Apis: []ast.Api{{Name: name, Pos: pos}}}
+}
+
+// TestCheck_Reserved 每个固定名称都不能被 Schema 中的定义占用
+func TestCheck_Reserved(t *testing.T) {
+	for _, name := range Reserved {
+		t.Run(name, func(t *testing.T) {
+			diags := Check(reservedSchema(name), "a.sb", diag.LangZH)
+			if len(diags) == 0 {
+				t.Fatalf("no collision reported")
+			}
+			d := diags[0]
+			if d.File != "a.sb" || d.Line != 3 || d.Col != 1 || d.Code != Code || d.Severity != diag.SeverityError {
+				t.Errorf("diagnostic = %+v", d)
+			}
+			if want := "生成代码中的 " + name; !strings.Contains(d.Message, want) {
+				t.Errorf("message = %q, want containing %q", d.Message, want)
+			}
+		})
+	}
+}
+
+// TestCheck_Methods API 不能占用 Client 的固定方法, 字段不能占用结构体的方法
+func TestCheck_Methods(t *testing.T) {
+	pos := ast.Pos{Line: 2, Col: 5}
+	for _, method := range clientMethods {
+		schema := &ast.Schema{Apis: []ast.Api{{Name: util.SnakeCase(method), Pos: pos}}}
+		if diags := Check(schema, "", diag.LangZH); len(diags) != 1 || diags[0].Line != 2 {
+			t.Errorf("API %s: got %v", method, diags)
+		}
+	}
+	for _, method := range structMethods {
+		schema := &ast.Schema{Structs: []ast.Struct{{Name: "User", Fields: []ast.StructField{{Name: strings.ToLower(method), Pos: pos}}}}}
+		if diags := Check(schema, "", diag.LangZH); len(diags) != 1 || diags[0].Line != 2 {
+			t.Errorf("field %s: got %v", method, diags)
+		}
+	}
+}
+
+func TestCheck_Lang(t *testing.T) {
+	diags := Check(reservedSchema("ServerOptions"), "a.sb", diag.LangEN)
+	want := "a.sb:3:1: error[name-collision]: struct ServerOptions collides with the generated ServerOptions: both generate ServerOptions"
+	if len(diags) == 0 || diags[0].String() != want {
+		t.Errorf("got %v, want %s", diags, want)
+	}
+}
@@ -0,0 +1,99 @@
+package parser
+
+import "sb/internal/diag"
+
+// 诊断代码, 保持稳定以便工具 (LSP, CI) 按代码过滤
+const (
+	CodeIllegalChar        = "illegal-char"
+	CodeUnexpectedToken    = "unexpected-token"
+	CodeUnexpectedIdent    = "unexpected-ident"
+	CodeExpectedToken      = "expected-token"
+	CodeDuplicateDef       = "duplicate-definition"
+	CodeDuplicateApi       = "duplicate-api"
+	CodeDuplicateArg       = "duplicate-argument"
+	CodeInvalidEnumValue   = "invalid-enum-value"
+	CodeEnumOverflow       = "enum-overflow"
+	CodeUndefinedType      = "undefined-type"
+	CodeInvalidNil         = "invalid-nil"
+	CodeInvalidEmbed       = "invalid-embed"
+	CodeInvalidApiError    = "invalid-api-error"
+	CodeCircularEmbed      = "circular-embed"
+	CodeUnknownAnnotation  = "unknown-annotation"
+	CodeInvalidAnnotation  = "invalid-annotation"
+	CodeDanglingAnnotation = "dangling-annotation"
+	CodeInvalidDirective   = "invalid-directive"
+	CodeUnterminatedString = "unterminated-string"
+	CodeUnclosedBrace      = "unclosed-brace"
+)
+
+// messages 诊断代码对应的双语消息模板
+var messages = map[string]diag.Message{
+	CodeIllegalChar:        {ZH: "非法字符 %q", EN: "illegal character %q"},
+	CodeUnexpectedToken:    {ZH: "未预期的 Token %q", EN: "unexpected token %q"},
+	CodeUnexpectedIdent:    {ZH: "未预期标识符 %q", EN: "unexpected identifier %q"},
+	CodeExpectedToken:      {ZH: "缺少 %s, 实际为 %q", EN: "expected %s, found %q"},
+	CodeDuplicateDef:       {ZH: "%s 重复定义", EN: "%s is already defined"},
+	CodeDuplicateApi:       {ZH: "API %s 重复定义", EN: "API %s is already defined"},
+	CodeDuplicateArg:       {ZH: "API %s 的参数 %s 重复", EN: "API %s has duplicate argument %s"},
+	CodeInvalidEnumValue:   {ZH: "无效枚举值 %q (范围 0-255)", EN: "invalid enum value %q (range 0-255)"},
+	CodeEnumOverflow:       {ZH: "枚举 %s 的值溢出 (超过 255)", EN: "value of enum variant %s overflows u8 (max 255)"},
+	CodeUndefinedType:      {ZH: "未定义类型: %s", EN: "undefined type: %s"},
+	CodeInvalidNil:         {ZH: "nil 只能作为 API 的返回类型", EN: "nil is only allowed as an API result type"},
+	CodeInvalidEmbed:       {ZH: "嵌入类型 %s 不是结构体", EN: "embedded type %s is not a struct"},
+	CodeInvalidApiError:    {ZH: "API 的错误类型 %s 必须是枚举 (且不能是列表)", EN: "API error type %s must be an enum (and not a list)"},
+	CodeCircularEmbed:      {ZH: "检测到循环嵌入: %s", EN: "circular embedding detected: %s"},
+	CodeUnknownAnnotation:  {ZH: "未知注解 @%s", EN: "unknown annotation @%s"},
+	CodeInvalidAnnotation:  {ZH: "注解 @%s 无效: %s", EN: "invalid annotation @%s: %s"},
+	CodeDanglingAnnotation: {ZH: "注解 @%s 后缺少%s", EN: "annotation @%s must be followed by %s"},
+	CodeInvalidDirective:   {ZH: "指令 %s 无效: %s", EN: "invalid directive %s: %s"},
+	CodeUnterminatedString: {ZH: "字符串缺少结束的 %s", EN: "string literal not terminated, missing closing %s"},
+	CodeUnclosedBrace:      {ZH: "结构体 %s 的 '{' 缺少对应的 '}'", EN: "'{' of struct %s is never closed"},
+}
+
+// 消息中引用的名词, 随语言切换
+var (
+	nounDefinition    = diag.Message{ZH: "定义", EN: "a definition"}
+	nounField         = diag.Message{ZH: "字段", EN: "a field"}
+	nounEnumVariant   = diag.Message{ZH: "枚举成员", EN: "an enum variant"}
+	nounMissingName   = diag.Message{ZH: "缺少注解名称", EN: "missing annotation name"}
+	nounString        = diag.Message{ZH: "参数必须是字符串", EN: "argument must be a string"}
+	nounOnStruct      = diag.Message{ZH: "结构体定义不支持注解", EN: "not allowed on struct definitions"}
+	nounOnEnum        = diag.Message{ZH: "枚举定义不支持注解", EN: "not allowed on enum definitions"}
+	nounOnEmbed       = diag.Message{ZH: "嵌入结构体不支持注解", EN: "not allowed on embedded structs"}
+	nounOnlyOnApi     = diag.Message{ZH: "只能用于 API, 不能用于%s", EN: "only allowed on APIs, not on %s"}
+	nounNoArgument    = diag.Message{ZH: "不接受参数", EN: "takes no argument"}
+	nounRParen        = diag.Message{ZH: "')'", EN: "')'"}
+	nounRBracket      = diag.Message{ZH: "']'", EN: "']'"}
+	nounTypeName      = diag.Message{ZH: "类型名称", EN: "a type name"}
+	nounVariantValue  = diag.Message{ZH: "枚举数值", EN: "an enum value"}
+	nounApiName       = diag.Message{ZH: "API 名称", EN: "an API name"}
+	nounArgName       = diag.Message{ZH: "参数名称", EN: "an argument name"}
+	nounLParen        = diag.Message{ZH: "'('", EN: "'('"}
+	nounArrow         = diag.Message{ZH: "'=>'", EN: "'=>'"}
+	nounCommaOrRParen = diag.Message{ZH: "',' 或 ')'", EN: "',' or ')'"}
+	nounResultType    = diag.Message{ZH: "返回类型 (或 nil)", EN: "a result type (or nil)"}
+	nounErrorType     = diag.Message{ZH: "错误枚举类型", EN: "an error enum type"}
+	nounEOF           = diag.Message{ZH: "文件结尾", EN: "end of file"}
+	nounEOL           = diag.Message{ZH: "行尾", EN: "end of line"}
+	nounImportPath    = diag.Message{ZH: "缺少导入路径", EN: "missing import path"}
+	nounPackageName   = diag.Message{ZH: "包名 %q 不是合法的 Go 标识符", EN: "package name %q is not a valid Go identifier"}
+	nounRepeated      = diag.Message{ZH: "每个文件只能出现一次", EN: "may appear only once per file"}
+)
+
+// semanticCodes 语义阶段的诊断代码, 不影响源码的语法结构
+var semanticCodes = map[string]bool{
+	CodeDuplicateDef:     true,
+	CodeDuplicateApi:     true,
+	CodeDuplicateArg:     true,
+	CodeUndefinedType:    true,
+	CodeInvalidNil:       true,
+	CodeInvalidEmbed:     true,
+	CodeInvalidApiError:  true,
+	CodeCircularEmbed:    true,
+	CodeInvalidDirective: true,
+}
+
+// IsSyntaxError 诊断代码是否属于语法错误 (格式化等只依赖语法结构的工具据此判断能否继续)
+func IsSyntaxError(code string) bool {
+	return !semanticCodes[code]
+}
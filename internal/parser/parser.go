@@ -1,23 +1,34 @@
 package parser
 
 import (
-	"fmt"
+	"cmp"
+	"go/token"
 	"sb/internal/ast"
+	"sb/internal/diag"
 	"sb/internal/lexer"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Parser 语法分析器
-// 采用递归下降 (Recursive Descent) 策略
+// 采用递归下降 (Recursive Descent) 策略, 出错后同步到下一个定义继续解析,
+// 一次运行即可报告文件中的全部问题
 type Parser struct {
 	l         *lexer.Lexer
 	curToken  lexer.Token
 	peekToken lexer.Token
+	lastEnd   ast.Pos // 上一个非注释 Token 的结束位置
 
 	// 符号表: 用于快速校验类型引用有效性
 	structNames map[string]bool
 	enumNames   map[string]bool
+	apiNames    map[string]bool
+
+	File  string    // 诊断信息中的文件名 (可为空)
+	Lang  diag.Lang // 诊断信息语言, 默认中文
+	diags diag.List
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -25,6 +36,7 @@ func New(l *lexer.Lexer) *Parser {
 		l:           l,
 		structNames: make(map[string]bool),
 		enumNames:   make(map[string]bool),
+		apiNames:    make(map[string]bool),
 	}
 	p.nextToken()
 	p.nextToken()
@@ -32,760 +44,886 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	if p.curToken.Line > 0 && p.curToken.Type != lexer.TokenComment {
+		p.lastEnd = ast.Pos{Line: p.curToken.Line, Col: p.curToken.Col + utf8.RuneCountInString(p.curToken.Value)}
+	}
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
 }
 
 // ParseSchema 解析完整的 Schema 文件
+// 存在错误时返回 nil 与 diag.List (包含全部诊断信息)
+func (p *Parser) ParseSchema() (*ast.Schema, error) {
+	schema, diags := p.Parse()
+	if err := diags.Err(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Parse 解析完整的 Schema 文件并返回全部诊断信息
 // 包含两个阶段:
 // 1. 语法解析: 构建 AST 结构, 收集所有定义
 // 2. 类型语义分析 (resolveTypes): 校验类型引用, 展开嵌入结构体
-func (p *Parser) ParseSchema() (*ast.Schema, error) {
+// 即使存在错误也会返回尽可能完整的 AST, 供 LSP 等工具使用
+func (p *Parser) Parse() (*ast.Schema, diag.List) {
 	schema := &ast.Schema{}
 	var lastNote string
+	var lastAnnos []annotation
 
 	for p.curToken.Type != lexer.TokenEOF {
-		if p.curToken.Type == lexer.TokenError {
-			return nil, fmt.Errorf("lexing error: %s", p.curToken.Value)
-		}
-
+		switch p.curToken.Type {
 		// 收集注释作为下一个定义的文档
-		if p.curToken.Type == lexer.TokenComment {
-			if lastNote != "" {
-				lastNote += "\n"
+		case lexer.TokenComment:
+			if v, ok := strings.CutPrefix(p.curToken.Value, goPackageDirective); ok {
+				p.parseGoPackage(schema, strings.TrimSpace(v))
+			}
+			if !IsDirective(p.curToken.Value) {
+				if lastNote != "" {
+					lastNote += "\n"
+				}
+				lastNote += p.curToken.Value
 			}
-			lastNote += p.curToken.Value
 			p.nextToken()
-			continue
-		}
 
-		if p.curToken.Type == lexer.TokenIdent {
-			if err := p.parseDefinition(schema, &lastNote); err != nil {
-				return nil, err
+		// 收集注解, 作用于下一个定义
+		case lexer.TokenAt:
+			a, err := p.parseAnnotation()
+			if err != nil {
+				p.recover(err)
+				continue
 			}
-			continue
-		}
+			lastAnnos = append(lastAnnos, a)
 
-		return nil, fmt.Errorf("line %d: unexpected token %q", p.curToken.Line, p.curToken.Value)
-	}
+		case lexer.TokenIdent:
+			if err := p.parseDefinition(schema, &lastNote, &lastAnnos); err != nil {
+				p.recover(err)
+			}
 
-	// 语义分析阶段
-	if err := p.resolveTypes(schema); err != nil {
-		return nil, err
+		default:
+			p.recover(p.unexpected(p.curToken))
+		}
 	}
-	return schema, nil
-}
-
-func (p *Parser) parseDefinition(schema *ast.Schema, lastNote *string) error {
-
-	defer func() { *lastNote = "" }()
-
-	note := *lastNote
-
-
-
-	if p.peekToken.Type == lexer.TokenLBrace {
-
-		return p.parseAndAddStruct(schema, note)
-
+	if len(lastAnnos) > 0 {
+		p.errorAt(lastAnnos[0].Pos, CodeDanglingAnnotation, lastAnnos[0].Name, nounDefinition.Format(p.Lang))
 	}
 
+	// 语义分析阶段
+	p.resolveTypes(schema)
 
+	slices.SortStableFunc(p.diags, func(a, b diag.Diagnostic) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Col, b.Col))
+	})
+	return schema, p.diags
+}
 
-	if p.isEnumDefinition() {
+// Diagnostics 返回目前收集到的全部诊断信息
+func (p *Parser) Diagnostics() diag.List {
+	return p.diags
+}
 
-		return p.parseAndAddEnum(schema, note)
+// --- 诊断与错误恢复 ---
 
+// newError 构造指定位置的错误诊断
+func (p *Parser) newError(pos ast.Pos, code string, args ...any) *diag.Diagnostic {
+	return &diag.Diagnostic{
+		File:     p.File,
+		Line:     pos.Line,
+		Col:      pos.Col,
+		Severity: diag.SeverityError,
+		Code:     code,
+		Message:  messages[code].Format(p.Lang, args...),
 	}
+}
 
+// errorAt 记录错误诊断 (不中断解析)
+func (p *Parser) errorAt(pos ast.Pos, code string, args ...any) {
+	p.diags = append(p.diags, *p.newError(pos, code, args...))
+}
 
-
-	if p.isApiDefinition() {
-
-		return p.parseAndAddApi(schema, note)
-
+// unexpected 当前 Token 不符合语法时的错误, 非法字符与未结束的字符串单独报告
+func (p *Parser) unexpected(tok lexer.Token) *diag.Diagnostic {
+	if tok.Type == lexer.TokenError && isQuoted(tok.Value) {
+		return p.newError(posOf(tok), CodeUnterminatedString, tok.Value[:1])
 	}
+	if tok.Type == lexer.TokenError {
+		return p.newError(posOf(tok), CodeIllegalChar, tok.Value)
+	}
+	return p.newError(posOf(tok), CodeUnexpectedToken, tok.Value)
+}
 
+// expected 缺少指定 Token 的错误
+// 当前 Token 已换行时在上一行行尾报告, 出错行即缺少 Token 的定义所在行, 错误恢复不会跳过下一行的定义
+func (p *Parser) expected(what diag.Message) *diag.Diagnostic {
+	pos, found := posOf(p.curToken), p.curToken.Value
+	if p.lastEnd.Line > 0 && p.curToken.Line > p.lastEnd.Line {
+		pos, found = p.lastEnd, nounEOL.Format(p.Lang)
+	}
+	if p.curToken.Type == lexer.TokenEOF {
+		found = nounEOF.Format(p.Lang)
+	}
+	return p.newError(pos, CodeExpectedToken, what.Format(p.Lang), found)
+}
 
+// recover 记录错误并同步到下一个定义的起点
+func (p *Parser) recover(d *diag.Diagnostic) {
+	p.diags = append(p.diags, *d)
+	p.synchronize(d.Line)
+}
 
-	return fmt.Errorf("行 %d: 未预期标识符 %q", p.curToken.Line, p.curToken.Value)
-
+// synchronize 跳过 Token 直到出错行之后某一行的行首出现标识符, 注解或注释
+// 花括号内的内容整体跳过, 避免把结构体字段误当作顶层定义
+func (p *Parser) synchronize(line int) {
+	depth := 0
+	prevLine := line
+	for p.curToken.Type != lexer.TokenEOF {
+		lineStart := p.curToken.Line > prevLine
+		if depth == 0 && lineStart && p.curToken.Line > line && isDefinitionStart(p.curToken) {
+			return
+		}
+		switch p.curToken.Type {
+		case lexer.TokenLBrace:
+			depth++
+		case lexer.TokenRBrace:
+			if depth > 0 {
+				depth--
+			}
+		}
+		prevLine = p.curToken.Line
+		p.nextToken()
+	}
 }
 
+// skipLine 跳过截至指定行的剩余 Token (用于结构体内部的字段级恢复)
+func (p *Parser) skipLine(line int) {
+	for p.curToken.Type != lexer.TokenEOF && p.curToken.Type != lexer.TokenRBrace && p.curToken.Line <= line {
+		p.nextToken()
+	}
+}
 
+func isDefinitionStart(tok lexer.Token) bool {
+	return tok.Type == lexer.TokenIdent || tok.Type == lexer.TokenAt || tok.Type == lexer.TokenComment
+}
 
-func (p *Parser) parseAndAddStruct(schema *ast.Schema, note string) error {
+func posOf(tok lexer.Token) ast.Pos {
+	return ast.Pos{Line: tok.Line, Col: tok.Col}
+}
 
-	if p.isDefined(p.curToken.Value) {
+// --- 定义 ---
 
-		return fmt.Errorf("行 %d: %s 重复定义", p.curToken.Line, p.curToken.Value)
+func (p *Parser) parseDefinition(schema *ast.Schema, lastNote *string, lastAnnos *[]annotation) *diag.Diagnostic {
+	defer func() { *lastNote = ""; *lastAnnos = nil }()
+	note := *lastNote
+	annos := *lastAnnos
 
+	if p.peekToken.Type == lexer.TokenLBrace {
+		p.rejectAnnotations(annos, nounOnStruct)
+		p.parseAndAddStruct(schema, note)
+		return nil
 	}
 
-	s, err := p.parseStruct(note)
+	if p.isEnumDefinition() {
+		p.rejectAnnotations(annos, nounOnEnum)
+		p.parseAndAddEnum(schema, note)
+		return nil
+	}
 
-	if err != nil {
+	if p.isApiDefinition() {
+		return p.parseAndAddApi(schema, note, annos)
+	}
 
-		return err
+	return p.newError(posOf(p.curToken), CodeUnexpectedIdent, p.curToken.Value)
+}
 
+func (p *Parser) parseAndAddStruct(schema *ast.Schema, note string) {
+	dup := p.checkDuplicate()
+	s := p.parseStruct(note)
+	if dup {
+		return
 	}
-
 	schema.Structs = append(schema.Structs, s)
-
 	p.structNames[s.Name] = true
-
-	return nil
-
 }
 
-
-
-func (p *Parser) parseAndAddEnum(schema *ast.Schema, note string) error {
-
-	if p.isDefined(p.curToken.Value) {
-
-		return fmt.Errorf("行 %d: %s 重复定义", p.curToken.Line, p.curToken.Value)
-
-	}
-
-	e, err := p.parseEnum(note)
-
-	if err != nil {
-
-		return err
-
+func (p *Parser) parseAndAddEnum(schema *ast.Schema, note string) {
+	dup := p.checkDuplicate()
+	e := p.parseEnum(note)
+	if dup {
+		return
 	}
-
 	schema.Enums = append(schema.Enums, e)
-
 	p.enumNames[e.Name] = true
-
-	return nil
-
 }
 
-
-
-func (p *Parser) parseAndAddApi(schema *ast.Schema, note string) error {
-
+func (p *Parser) parseAndAddApi(schema *ast.Schema, note string, annos []annotation) *diag.Diagnostic {
 	api, err := p.parseApi(note)
-
 	if err != nil {
-
 		return err
-
 	}
-
+	api.Deprecated = deprecationOf(annos)
+	api.Idempotent = hasAnnotation(annos, "idempotent")
+	if p.apiNames[api.Name] {
+		p.errorAt(api.Pos, CodeDuplicateApi, api.Name)
+		return nil
+	}
+	p.apiNames[api.Name] = true
 	schema.Apis = append(schema.Apis, api)
-
 	return nil
-
 }
 
-
+// checkDuplicate 报告重复定义; 定义体仍会被解析以便继续检查其内容
+func (p *Parser) checkDuplicate() bool {
+	if !p.isDefined(p.curToken.Value) {
+		return false
+	}
+	p.errorAt(posOf(p.curToken), CodeDuplicateDef, p.curToken.Value)
+	return true
+}
 
 func (p *Parser) isDefined(name string) bool {
-
 	return p.structNames[name] || p.enumNames[name]
-
 }
 
-
-
 func (p *Parser) isEnumDefinition() bool {
-
 	return p.peekToken.Type == lexer.TokenAssign || p.peekToken.Type == lexer.TokenPipe
-
 }
 
-
-
 func (p *Parser) isApiDefinition() bool {
-
 	return p.curToken.Type == lexer.TokenIdent && (p.peekToken.Type == lexer.TokenLParen || p.peekToken.Type == lexer.TokenDot)
-
 }
 
+// --- 结构体 ---
 
-
-func (p *Parser) parseStruct(note string) (ast.Struct, error) {
-
-	s := ast.Struct{Name: p.curToken.Value, Note: note}
-
+// parseStruct 字段级错误会被记录并跳过该行, 不会中断整个结构体
+func (p *Parser) parseStruct(note string) ast.Struct {
+	s := ast.Struct{Name: p.curToken.Value, Note: note, Pos: posOf(p.curToken)}
 	p.nextToken() // 名称
-
+	lbrace := posOf(p.curToken)
 	p.nextToken() // {
 
-
-
 	for p.curToken.Type != lexer.TokenRBrace && p.curToken.Type != lexer.TokenEOF {
-
 		if p.curToken.Type == lexer.TokenComment {
-
 			p.nextToken()
-
 			continue
-
 		}
-
 		if p.curToken.Type == lexer.TokenComma {
-
 			p.nextToken() // 跳过可选逗号
-
 			continue
-
 		}
 
-
-
-		field, err := p.parseStructField()
-
+		fieldLine := p.curToken.Line
+		field, err := p.parseAnnotatedField()
 		if err != nil {
-
-			return s, err
-
+			p.diags = append(p.diags, *err)
+			p.skipLine(fieldLine)
+			continue
 		}
-
 		s.Fields = append(s.Fields, field)
-
 	}
-
+	if p.curToken.Type == lexer.TokenEOF {
+		p.errorAt(lbrace, CodeUnclosedBrace, s.Name)
+	}
 	p.nextToken() // }
-
-	return s, nil
-
+	return s
 }
 
+func (p *Parser) parseAnnotatedField() (ast.StructField, *diag.Diagnostic) {
+	var f ast.StructField
+	annos, err := p.parseAnnotations()
+	if err != nil {
+		return f, err
+	}
+	if len(annos) > 0 && (p.curToken.Type == lexer.TokenRBrace || p.curToken.Type == lexer.TokenEOF) {
+		return f, p.newError(annos[0].Pos, CodeDanglingAnnotation, annos[0].Name, nounField.Format(p.Lang))
+	}
 
+	f, err = p.parseStructField()
+	if err != nil {
+		return f, err
+	}
+	if len(annos) > 0 {
+		if f.Name == "" {
+			return f, p.newError(annos[0].Pos, CodeInvalidAnnotation, annos[0].Name, nounOnEmbed.Format(p.Lang))
+		}
+		p.rejectApiAnnotations(annos, nounField)
+		f.Deprecated = deprecationOf(annos)
+	}
+	return f, nil
+}
 
-func (p *Parser) parseStructField() (ast.StructField, error) {
-
+func (p *Parser) parseStructField() (ast.StructField, *diag.Diagnostic) {
 	var f ast.StructField
-
+	if p.curToken.Type != lexer.TokenIdent {
+		return f, p.unexpected(p.curToken)
+	}
 	startLine := p.curToken.Line
-
-
+	nameTok := p.curToken
 
 	f.Name = p.curToken.Value
-
+	f.Pos = posOf(nameTok)
 	p.nextToken()
 
-
-
 	// 嵌入结构体情况: 名称实际上是类型
-
 	if p.curToken.Line != startLine {
-
-		f.Type = ast.Type{Name: f.Name}
-
+		f.Type = ast.Type{Name: f.Name, Pos: f.Pos}
 		f.Name = ""
-
 		return f, nil
-
 	}
 
-	
-
 	// 普通字段情况
-
 	if p.curToken.Type == lexer.TokenIdent || p.curToken.Type == lexer.TokenLBracket {
-
-		f.Type = p.parseType()
-
-		if p.curToken.Type == lexer.TokenIdent && (strings.HasPrefix(p.curToken.Value, "\"") || strings.HasPrefix(p.curToken.Value, "`")) {
-
+		t, err := p.parseType()
+		if err != nil {
+			return f, err
+		}
+		f.Type = t
+		if p.curToken.Type == lexer.TokenIdent && isQuoted(p.curToken.Value) {
 			f.Tag = strings.Trim(p.curToken.Value, "\"`")
-
 			p.nextToken()
-
 		}
-
-	} else {
-
+	} else if p.curToken.Type == lexer.TokenComment || p.curToken.Type == lexer.TokenRBrace || p.curToken.Type == lexer.TokenComma {
 		// 嵌入兜底
-
-		f.Type = ast.Type{Name: f.Name}
-
+		f.Type = ast.Type{Name: f.Name, Pos: f.Pos}
 		f.Name = ""
-
+	} else {
+		return f, p.unexpected(p.curToken)
 	}
 
-
-
 	if p.curToken.Type == lexer.TokenComment && p.curToken.Line == startLine {
-
-		f.Note = p.curToken.Value
-
+		f.Note = noteOf(p.curToken.Value)
 		p.nextToken()
-
 	}
 
-
-
 	return f, nil
-
 }
 
-
-
-func (p *Parser) parseType() ast.Type {
-
+func (p *Parser) parseType() (ast.Type, *diag.Diagnostic) {
 	var t ast.Type
-
 	if p.curToken.Type != lexer.TokenLBracket {
-
+		if p.curToken.Type != lexer.TokenIdent {
+			return t, p.expected(nounTypeName)
+		}
 		t.Name = p.curToken.Value
-
+		t.Pos = posOf(p.curToken)
 		p.nextToken()
-
-		return t
-
+		return t, nil
 	}
 
-
-
 	t.IsList = true
-
 	p.nextToken() // [
-
+	if p.curToken.Type != lexer.TokenIdent {
+		return t, p.expected(nounTypeName)
+	}
 	t.Name = p.curToken.Value
-
+	t.Pos = posOf(p.curToken)
 	p.nextToken() // 名称
-
+	if p.curToken.Type != lexer.TokenRBracket {
+		return t, p.expected(nounRBracket)
+	}
 	p.nextToken() // ]
-
-	return t
-
+	return t, nil
 }
 
+// --- 枚举 ---
 
-
-func (p *Parser) parseEnum(note string) (ast.Enum, error) {
-
-	e := ast.Enum{Name: p.curToken.Value, Note: note}
-
+// parseEnum 成员级错误会被记录并跳到下一个 |, 枚举本身始终加入符号表以避免连锁报错
+func (p *Parser) parseEnum(note string) ast.Enum {
+	e := ast.Enum{Name: p.curToken.Value, Note: note, Pos: posOf(p.curToken)}
 	p.nextToken() // 名称
-
 	if p.curToken.Type == lexer.TokenAssign {
-
 		p.nextToken() // =
-
 	}
 
-
-
 	var lastID uint8 = 0
-
 	isFirst := true
 
-
-
 	for p.curToken.Type != lexer.TokenEOF {
-
 		if p.curToken.Type == lexer.TokenPipe {
-
 			p.nextToken()
-
 			continue
-
-		}
-
-		if p.curToken.Type != lexer.TokenIdent {
-
-			break
-
 		}
 
-
-
-		child, err := p.parseEnumChild(&lastID, &isFirst)
-
+		child, err := p.parseAnnotatedChild(&lastID, &isFirst)
 		if err != nil {
-
-			return e, err
-
+			p.diags = append(p.diags, *err)
+			if !p.skipToNextVariant(err.Line) {
+				break
+			}
+			continue
 		}
-
-		e.Children = append(e.Children, child)
-
-
+		if child == nil {
+			break
+		}
+		e.Children = append(e.Children, *child)
 
 		if p.curToken.Type != lexer.TokenPipe {
-
 			break
-
 		}
-
 	}
-
-	return e, nil
-
+	return e
 }
 
+// skipToNextVariant 跳到下一个 | 继续解析; 若先到达出错行之后的新定义则返回 false
+func (p *Parser) skipToNextVariant(line int) bool {
+	for p.curToken.Type != lexer.TokenEOF {
+		if p.curToken.Type == lexer.TokenPipe {
+			return true
+		}
+		if p.curToken.Line > line {
+			return false
+		}
+		p.nextToken()
+	}
+	return false
+}
 
+// parseAnnotatedChild 当前位置不是枚举成员时返回 nil (枚举结束)
+func (p *Parser) parseAnnotatedChild(lastID *uint8, isFirst *bool) (*ast.EnumChild, *diag.Diagnostic) {
+	annos, err := p.parseAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	if p.curToken.Type != lexer.TokenIdent {
+		if len(annos) > 0 {
+			return nil, p.newError(annos[0].Pos, CodeDanglingAnnotation, annos[0].Name, nounEnumVariant.Format(p.Lang))
+		}
+		return nil, nil
+	}
 
-func (p *Parser) parseEnumChild(lastID *uint8, isFirst *bool) (ast.EnumChild, error) {
-
-	child := ast.EnumChild{Name: p.curToken.Value}
-
-	childLine := p.curToken.Line
-
-	p.nextToken()
-
-
+	child, err := p.parseEnumChild(lastID, isFirst)
+	if err != nil {
+		return nil, err
+	}
+	p.rejectApiAnnotations(annos, nounEnumVariant)
+	child.Deprecated = deprecationOf(annos)
+	return &child, nil
+}
 
-	if p.curToken.Type == lexer.TokenLParen {
+func (p *Parser) parseEnumChild(lastID *uint8, isFirst *bool) (ast.EnumChild, *diag.Diagnostic) {
+	child := ast.EnumChild{Name: p.curToken.Value, Pos: posOf(p.curToken)}
+	childLine := p.curToken.Line
+	p.nextToken()
 
+	if p.curToken.Type == lexer.TokenLParen {
 		p.nextToken() // (
-
+		if p.curToken.Type != lexer.TokenNumber {
+			return child, p.expected(nounVariantValue)
+		}
 		id, err := strconv.ParseUint(p.curToken.Value, 10, 8)
-
 		if err != nil {
-
-			return child, fmt.Errorf("行 %d: 无效枚举值 %q: %w", p.curToken.Line, p.curToken.Value, err)
-
+			return child, p.newError(posOf(p.curToken), CodeInvalidEnumValue, p.curToken.Value)
 		}
-
 		child.ID = uint8(id)
-
 		*lastID = child.ID
-
 		p.nextToken() // 数值
-
+		if p.curToken.Type != lexer.TokenRParen {
+			return child, p.expected(nounRParen)
+		}
 		p.nextToken() // )
-
 		*isFirst = false
-
 	} else {
-
 		if *isFirst {
-
 			child.ID = 0
-
 			*isFirst = false
-
 		} else {
-
 			if *lastID == 255 {
-
-				return child, fmt.Errorf("行 %d: 枚举值溢出", childLine)
-
+				return child, p.newError(child.Pos, CodeEnumOverflow, child.Name)
 			}
-
 			*lastID++
-
 			child.ID = *lastID
-
 		}
-
 	}
 
-
-
 	if p.curToken.Type == lexer.TokenComment && p.curToken.Line == childLine {
-
-		child.Note = p.curToken.Value
-
+		child.Note = noteOf(p.curToken.Value)
 		p.nextToken()
-
 	}
-
 	return child, nil
-
 }
 
+// --- API ---
 
-
-func (p *Parser) parseApi(note string) (ast.Api, error) {
-
-	api := ast.Api{Note: note}
-
-	apiLine := p.curToken.Line
-
+func (p *Parser) parseApi(note string) (ast.Api, *diag.Diagnostic) {
+	api := ast.Api{Note: note, Pos: posOf(p.curToken)}
 	name := p.curToken.Value
-
 	p.nextToken()
 
-	
-
 	for p.curToken.Type == lexer.TokenDot {
-
 		p.nextToken()
-
+		if p.curToken.Type != lexer.TokenIdent {
+			return api, p.expected(nounApiName)
+		}
 		name += "." + p.curToken.Value
-
 		p.nextToken()
-
 	}
-
 	api.Name = name
 
-
-
+	if p.curToken.Type != lexer.TokenLParen {
+		return api, p.expected(nounLParen)
+	}
 	p.nextToken() // (
 
-	for p.curToken.Type != lexer.TokenRParen && p.curToken.Type != lexer.TokenEOF {
-
-		arg := ast.ApiArg{Name: p.curToken.Value}
-
-		p.nextToken()
-
-		arg.Type = p.parseType()
-
-		api.Args = append(api.Args, arg)
-
-		if p.curToken.Type == lexer.TokenComma {
-
-			p.nextToken()
-
-		}
-
+	args, err := p.parseApiArgs(api.Name)
+	if err != nil {
+		p.skipArgs()
+		return api, err
 	}
+	api.Args = args
 
-	p.nextToken() // )
-
+	if p.curToken.Type != lexer.TokenArrow {
+		return api, p.expected(nounArrow)
+	}
+	arrowLine := p.curToken.Line
 	p.nextToken() // =>
 
-
-
-	if p.curToken.Type == lexer.TokenIdent || p.curToken.Type == lexer.TokenLBracket {
-
-		api.Result = p.parseType()
-
-	} else if p.curToken.Value == "nil" {
-
-		api.Result = ast.Type{Name: "nil"}
-
-		p.nextToken()
-
+	// 返回类型必须与 => 位于同一行, 避免把下一行的定义误当作返回类型
+	if p.curToken.Line != arrowLine || (p.curToken.Type != lexer.TokenIdent && p.curToken.Type != lexer.TokenLBracket) {
+		return api, p.expected(nounResultType)
 	}
+	lastLine := p.curToken.Line
+	result, err := p.parseType()
+	if err != nil {
+		return api, err
+	}
+	api.Result = result
 
+	// 可选的错误类型: => R ! E, 同样必须位于同一行
+	if p.curToken.Type == lexer.TokenBang && p.curToken.Line == lastLine {
+		p.nextToken() // !
+		if p.curToken.Line != lastLine || (p.curToken.Type != lexer.TokenIdent && p.curToken.Type != lexer.TokenLBracket) {
+			return api, p.expected(nounErrorType)
+		}
+		lastLine = p.curToken.Line
+		errType, err := p.parseType()
+		if err != nil {
+			return api, err
+		}
+		api.Error = &errType
+	}
 
-
-	if p.curToken.Type == lexer.TokenComment && p.curToken.Line == apiLine {
-
-		api.Note = p.curToken.Value
-
+	if p.curToken.Type == lexer.TokenComment && p.curToken.Line == lastLine {
+		api.Note = noteOf(p.curToken.Value)
 		p.nextToken()
-
+	} else if p.curToken.Type != lexer.TokenEOF && p.curToken.Line == lastLine {
+		return api, p.unexpected(p.curToken)
 	}
 
-
-
 	return api, nil
-
 }
 
+// parseApiArgs 解析参数列表直到 ), 支持多行书写, 行间注释及末尾逗号
+func (p *Parser) parseApiArgs(apiName string) ([]ast.ApiArg, *diag.Diagnostic) {
+	var args []ast.ApiArg
+	seen := make(map[string]bool)
+	for {
+		for p.curToken.Type == lexer.TokenComment {
+			p.nextToken()
+		}
+		if p.curToken.Type == lexer.TokenRParen {
+			p.nextToken() // )
+			return args, nil
+		}
 
+		if p.curToken.Type != lexer.TokenIdent || strings.Contains(p.curToken.Value, ".") || isQuoted(p.curToken.Value) {
+			return args, p.expected(nounArgName)
+		}
+		arg := ast.ApiArg{Name: p.curToken.Value, Pos: posOf(p.curToken)}
+		if seen[arg.Name] {
+			p.errorAt(arg.Pos, CodeDuplicateArg, apiName, arg.Name)
+		}
+		seen[arg.Name] = true
+		p.nextToken()
 
-func (p *Parser) resolveTypes(s *ast.Schema) error {
-
-	if err := p.resolveStructFields(s); err != nil {
-
-		return err
+		t, err := p.parseType()
+		if err != nil {
+			return args, err
+		}
+		arg.Type = t
+		args = append(args, arg)
 
+		for p.curToken.Type == lexer.TokenComment {
+			p.nextToken()
+		}
+		switch p.curToken.Type {
+		case lexer.TokenComma:
+			p.nextToken()
+		case lexer.TokenRParen:
+		default:
+			return args, p.expected(nounCommaOrRParen)
+		}
 	}
+}
 
-	if err := p.resolveApiArgs(s); err != nil {
-
-		return err
-
+// skipArgs 参数列表出错后跳到 ), 避免多行参数列表的后续参数被当作顶层定义;
+// 缺少 ) 时在行首出现的下一个定义处停止
+func (p *Parser) skipArgs() {
+	for p.curToken.Type != lexer.TokenEOF && p.curToken.Type != lexer.TokenRParen {
+		if p.curToken.Line > p.lastEnd.Line && p.isDefinitionHead() {
+			return
+		}
+		p.nextToken()
 	}
-
-	return p.expandEmbeddedStructs(s)
-
 }
 
+// isDefinitionHead 当前 Token 开始一个结构体, 枚举, API 定义或注解 (参数列表中不会出现)
+func (p *Parser) isDefinitionHead() bool {
+	if p.curToken.Type == lexer.TokenAt {
+		return true
+	}
+	return p.curToken.Type == lexer.TokenIdent && (p.peekToken.Type == lexer.TokenLBrace || p.isEnumDefinition() || p.isApiDefinition())
+}
 
+// --- 语义分析 ---
 
-func (p *Parser) resolveStructFields(s *ast.Schema) error {
+// resolveTypes 校验全部类型引用并展开嵌入结构体, 错误逐条记录而不中断
+func (p *Parser) resolveTypes(s *ast.Schema) {
+	p.resolveStructFields(s)
+	p.resolveApiArgs(s)
+	p.expandEmbeddedStructs(s)
+}
 
+func (p *Parser) resolveStructFields(s *ast.Schema) {
 	for i := range s.Structs {
-
 		for j := range s.Structs[i].Fields {
-
-			if err := p.resolveType(&s.Structs[i].Fields[j].Type); err != nil {
-
-				return fmt.Errorf("结构体 %s 字段 %s: %w", s.Structs[i].Name, s.Structs[i].Fields[j].Name, err)
-
+			f := &s.Structs[i].Fields[j]
+			p.resolveType(&f.Type, false)
+			// 嵌入的必须是结构体
+			if f.Name == "" && f.Type.Kind != ast.KindStruct && p.isKnownType(f.Type.Name) {
+				p.errorAt(f.Type.Pos, CodeInvalidEmbed, f.Type.Name)
 			}
-
 		}
-
 	}
-
-	return nil
-
 }
 
-
-
-func (p *Parser) resolveApiArgs(s *ast.Schema) error {
-
+func (p *Parser) resolveApiArgs(s *ast.Schema) {
 	for i := range s.Apis {
-
 		for j := range s.Apis[i].Args {
-
-			if err := p.resolveType(&s.Apis[i].Args[j].Type); err != nil {
-
-				return fmt.Errorf("api %s 参数 %s: %w", s.Apis[i].Name, s.Apis[i].Args[j].Name, err)
-
-			}
-
+			p.resolveType(&s.Apis[i].Args[j].Type, false)
 		}
-
-		if err := p.resolveType(&s.Apis[i].Result); err != nil {
-
-			return fmt.Errorf("api %s 结果: %w", s.Apis[i].Name, err)
-
+		p.resolveType(&s.Apis[i].Result, true)
+		if e := s.Apis[i].Error; e != nil {
+			p.resolveType(e, false)
+			if e.IsList || (e.Kind != ast.KindEnum && p.isKnownType(e.Name)) {
+				name := e.Name
+				if e.IsList {
+					name = "[" + name + "]"
+				}
+				p.errorAt(e.Pos, CodeInvalidApiError, name)
+			}
 		}
-
 	}
-
-	return nil
-
 }
 
-
-
-func (p *Parser) resolveType(t *ast.Type) error {
-
-	if t.Name == "nil" || isBaseType(t.Name) {
-
+// resolveType 填充类型分类; nil 仅允许作为 API 返回类型 (且不能是列表)
+func (p *Parser) resolveType(t *ast.Type, allowNil bool) {
+	if t.Name == "nil" {
+		if !allowNil || t.IsList {
+			p.errorAt(t.Pos, CodeInvalidNil)
+		}
 		t.Kind = ast.KindBase
-
-		return nil
-
+		return
+	}
+	if ast.IsBaseType(t.Name) {
+		t.Kind = ast.KindBase
+		return
 	}
-
 	if p.structNames[t.Name] {
-
 		t.Kind = ast.KindStruct
-
-		return nil
-
+		return
 	}
-
 	if p.enumNames[t.Name] {
-
 		t.Kind = ast.KindEnum
-
-		return nil
-
+		return
 	}
-
-	return fmt.Errorf("未定义类型: %s", t.Name)
-
+	p.errorAt(t.Pos, CodeUndefinedType, t.Name)
 }
 
-
-
-func isBaseType(name string) bool {
-
-	switch name {
-
-	case "i8", "u8", "i16", "u16", "i32", "u32", "i64", "u64", 
-
-		 "f32", "f64", "bool", "text", "bin":
-
-		return true
-
-	}
-
-	return false
-
+func (p *Parser) isKnownType(name string) bool {
+	return name == "nil" || ast.IsBaseType(name) || p.isDefined(name)
 }
 
-
-
-func (p *Parser) expandEmbeddedStructs(s *ast.Schema) error {
-
+func (p *Parser) expandEmbeddedStructs(s *ast.Schema) {
 	structMap := make(map[string]ast.Struct)
-
 	for _, st := range s.Structs {
-
 		structMap[st.Name] = st
-
 	}
 
-
-
 	visited := make(map[string]bool)
-
 	for i := range s.Structs {
-
+		for _, f := range s.Structs[i].Fields {
+			if f.Name == "" {
+				s.Structs[i].Embeds = append(s.Structs[i].Embeds, f.Type)
+			}
+		}
 		clear(visited)
-
-		expanded, err := p.expandFields(s.Structs[i].Fields, structMap, visited, s.Structs[i].Name)
-
-		if err != nil {
-
-			return err
-
+		expanded, ok := p.expandFields(s.Structs[i].Fields, structMap, visited, s.Structs[i].Name)
+		if !ok {
+			p.errorAt(s.Structs[i].Pos, CodeCircularEmbed, s.Structs[i].Name)
+			continue
 		}
-
 		s.Structs[i].Fields = expanded
-
 	}
-
-	return nil
-
 }
 
-
-
-func (p *Parser) expandFields(fields []ast.StructField, structMap map[string]ast.Struct, visited map[string]bool, rootName string) ([]ast.StructField, error) {
-
+// expandFields 展开嵌入字段, 检测到循环嵌入时返回 false
+// 非结构体嵌入已在 resolveStructFields 中报告, 此处直接忽略
+func (p *Parser) expandFields(fields []ast.StructField, structMap map[string]ast.Struct, visited map[string]bool, rootName string) ([]ast.StructField, bool) {
 	if visited[rootName] {
-
-		return nil, fmt.Errorf("检测到循环嵌入: %s", rootName)
-
+		return nil, false
 	}
-
 	visited[rootName] = true
-
 	defer func() { visited[rootName] = false }()
 
-
-
 	var result []ast.StructField
-
 	for _, f := range fields {
-
 		if f.Name != "" {
-
 			result = append(result, f)
-
 			continue
-
 		}
 
-
-
 		base, ok := structMap[f.Type.Name]
+		if !ok {
+			continue
+		}
 
+		expanded, ok := p.expandFields(base.Fields, structMap, visited, f.Type.Name)
 		if !ok {
+			return nil, false
+		}
+		for _, ef := range expanded {
+			ef.Embedded = f.Type.Name
+			result = append(result, ef)
+		}
+	}
+	return result, true
+}
 
-			// 由于 resolveTypes 理论上不可达
+// --- 注解 ---
 
-			return nil, fmt.Errorf("未找到嵌入结构体 %s", f.Type.Name)
+// annotation 定义, 字段或枚举成员前的注解 (如 @deprecated("原因"), API 的 @idempotent)
+type annotation struct {
+	Name  string
+	Value string
+	Pos   ast.Pos
+}
 
+// parseAnnotations 解析连续的注解, 当前 Token 不是 @ 时返回空
+func (p *Parser) parseAnnotations() ([]annotation, *diag.Diagnostic) {
+	var annos []annotation
+	for p.curToken.Type == lexer.TokenAt {
+		a, err := p.parseAnnotation()
+		if err != nil {
+			return nil, err
 		}
+		annos = append(annos, a)
+	}
+	return annos, nil
+}
 
+// parseAnnotation 解析单个注解: @name 或 @name("value")
+func (p *Parser) parseAnnotation() (annotation, *diag.Diagnostic) {
+	a := annotation{Pos: posOf(p.curToken)}
+	p.nextToken() // @
+	if p.curToken.Type != lexer.TokenIdent || p.curToken.Line != a.Pos.Line {
+		return a, p.newError(a.Pos, CodeInvalidAnnotation, "", nounMissingName.Format(p.Lang))
+	}
+	a.Name = p.curToken.Value
+	p.nextToken()
 
+	switch a.Name {
+	case "deprecated", "idempotent":
+	default:
+		return a, p.newError(a.Pos, CodeUnknownAnnotation, a.Name)
+	}
 
-		expanded, err := p.expandFields(base.Fields, structMap, visited, f.Type.Name)
+	// 可选参数必须与注解名位于同一行, 避免吞掉下一行的 API 参数列表
+	if p.curToken.Type != lexer.TokenLParen || p.curToken.Line != a.Pos.Line {
+		return a, nil
+	}
+	if a.Name == "idempotent" {
+		return a, p.newError(posOf(p.curToken), CodeInvalidAnnotation, a.Name, nounNoArgument.Format(p.Lang))
+	}
+	p.nextToken() // (
+	if p.curToken.Type == lexer.TokenError {
+		return a, p.unexpected(p.curToken)
+	}
+	if p.curToken.Type != lexer.TokenIdent || !isQuoted(p.curToken.Value) {
+		return a, p.newError(posOf(p.curToken), CodeInvalidAnnotation, a.Name, nounString.Format(p.Lang))
+	}
+	a.Value = strings.Trim(p.curToken.Value, "\"`")
+	p.nextToken()
+	if p.curToken.Type != lexer.TokenRParen {
+		return a, p.expected(nounRParen)
+	}
+	p.nextToken() // )
+	return a, nil
+}
 
-		if err != nil {
+// goPackageDirective 文件级指令, 指定生成 Go 代码的导入路径与包名:
+//
+//	// sb:go-package example.com/app/internal/proto/billing
+//	// sb:go-package example.com/app/proto/v2;billing
+const goPackageDirective = "sb:go-package"
+
+// parseGoPackage 校验 sb:go-package 指令并记录到 Schema
+func (p *Parser) parseGoPackage(schema *ast.Schema, value string) {
+	pos := posOf(p.curToken)
+	path, name, _ := strings.Cut(value, ";")
+	switch {
+	case schema.GoPackage != "":
+		p.errorAt(pos, CodeInvalidDirective, goPackageDirective, nounRepeated.Format(p.Lang))
+	case path == "" || strings.ContainsAny(path, " \t"):
+		p.errorAt(pos, CodeInvalidDirective, goPackageDirective, nounImportPath.Format(p.Lang))
+	case name != "" && !token.IsIdentifier(name):
+		p.errorAt(pos, CodeInvalidDirective, goPackageDirective, nounPackageName.Format(p.Lang, name))
+	default:
+		schema.GoPackage = value
+	}
+}
 
-			return nil, err
+// IsDirective 以 "sb:" 开头的注释是工具指令 (如 // sb:lint-ignore naming), 不作为文档注释
+func IsDirective(comment string) bool {
+	return strings.HasPrefix(comment, "sb:")
+}
 
-		}
+// noteOf 行尾注释作为文档; 工具指令不计入
+func noteOf(comment string) string {
+	if IsDirective(comment) {
+		return ""
+	}
+	return comment
+}
 
-		result = append(result, expanded...)
+func isQuoted(v string) bool {
+	return strings.HasPrefix(v, "\"") || strings.HasPrefix(v, "`")
+}
 
+// rejectAnnotations 结构体与枚举定义本身不接受注解
+func (p *Parser) rejectAnnotations(annos []annotation, reason diag.Message) {
+	if len(annos) > 0 {
+		p.errorAt(annos[0].Pos, CodeInvalidAnnotation, annos[0].Name, reason.Format(p.Lang))
 	}
+}
 
-	return result, nil
+// rejectApiAnnotations 只能用于 API 的注解 (@idempotent) 出现在字段或枚举成员上时报错
+func (p *Parser) rejectApiAnnotations(annos []annotation, target diag.Message) {
+	for _, a := range annos {
+		if a.Name == "idempotent" {
+			p.errorAt(a.Pos, CodeInvalidAnnotation, a.Name, nounOnlyOnApi.Format(p.Lang, target.Format(p.Lang)))
+		}
+	}
+}
+
+func hasAnnotation(annos []annotation, name string) bool {
+	return slices.ContainsFunc(annos, func(a annotation) bool { return a.Name == name })
+}
 
+// deprecationOf 从注解中提取弃用标记, 不存在时返回 nil
+func deprecationOf(annos []annotation) *ast.Deprecation {
+	for _, a := range annos {
+		if a.Name == "deprecated" {
+			return &ast.Deprecation{Reason: a.Value}
+		}
+	}
+	return nil
 }
@@ -1,8 +1,13 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"sb/internal/ast"
+	"sb/internal/diag"
 	"sb/internal/lexer"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -42,7 +47,7 @@ func TestParser_Robustness(t *testing.T) {
 			input: `
 				User { id u32
 			`,
-			wantErr: false, // Parser handles EOF gracefully (returns what it has)
+			wantErr: true, // 缺少 '}' 时在 '{' 处报错, 已解析的字段仍然保留
 		},
 		{
 			name: "Invalid API - No Arrow",
@@ -88,3 +93,386 @@ func TestParser_FieldLimit(t *testing.T) {
 		t.Errorf("Expected 256 fields, got %d", len(schema.Structs[0].Fields))
 	}
 }
+
+// TestParser_Embeds 嵌入信息与展开后的字段视图并存, 字段记录直接嵌入的结构体
+func TestParser_Embeds(t *testing.T) {
+	input := `
+		C { x u8 }
+		B { C, y u16 }
+		A { z bool, B, w text }
+	`
+	schema, err := New(lexer.New(input)).ParseSchema()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	a := schema.Structs[2]
+	if len(a.Embeds) != 1 || a.Embeds[0].Name != "B" {
+		t.Errorf("A embeds = %+v, want [B]", a.Embeds)
+	}
+	var got []string
+	for _, f := range a.Fields {
+		got = append(got, f.Name+":"+f.Embedded)
+	}
+	if want := "z: x:B y:B w:"; fmt.Sprint(got) != "["+want+"]" {
+		t.Errorf("A fields = %v, want [%s]", got, want)
+	}
+	if b := schema.Structs[1]; b.Fields[0].Embedded != "C" || b.Fields[1].Embedded != "" {
+		t.Errorf("B fields = %+v", b.Fields)
+	}
+}
+
+func TestParser_Deprecated(t *testing.T) {
+	input := `
+		St = A | @deprecated("use C") B | C
+		User {
+			id u32
+			@deprecated("use phone")
+			tel text // 电话
+			@deprecated phone text
+		}
+		@deprecated("use user.get2")
+		user.get(id u32) => User
+		user.get2(id u32) => User
+	`
+	schema, err := New(lexer.New(input)).ParseSchema()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	children := schema.Enums[0].Children
+	if children[0].Deprecated != nil || children[1].Deprecated == nil || children[1].Deprecated.Reason != "use C" {
+		t.Errorf("enum deprecation mismatch: %+v", children)
+	}
+	if children[2].ID != 2 {
+		t.Errorf("Expected C = 2, got %d", children[2].ID)
+	}
+
+	fields := schema.Structs[0].Fields
+	if fields[1].Deprecated == nil || fields[1].Deprecated.Reason != "use phone" || fields[1].Note != "电话" {
+		t.Errorf("field tel mismatch: %+v", fields[1])
+	}
+	if fields[2].Deprecated == nil || fields[2].Deprecated.Reason != "" || fields[2].Type.Name != "text" {
+		t.Errorf("field phone mismatch: %+v", fields[2])
+	}
+
+	if schema.Apis[0].Deprecated == nil || schema.Apis[0].Deprecated.Reason != "use user.get2" {
+		t.Errorf("api deprecation mismatch: %+v", schema.Apis[0])
+	}
+	if schema.Apis[1].Deprecated != nil {
+		t.Errorf("Expected user.get2 not deprecated")
+	}
+}
+
+func TestParser_Idempotent(t *testing.T) {
+	input := `
+		@idempotent
+		user.get(id u32) => u32
+		@deprecated("use user.get") @idempotent
+		user.find(id u32) => u32
+		user.set(id u32) => nil
+	`
+	schema, err := New(lexer.New(input)).ParseSchema()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	want := []bool{true, true, false}
+	for i, api := range schema.Apis {
+		if api.Idempotent != want[i] {
+			t.Errorf("%s: Idempotent = %v, want %v", api.Name, api.Idempotent, want[i])
+		}
+	}
+	if schema.Apis[1].Deprecated == nil {
+		t.Errorf("Expected user.find deprecated")
+	}
+}
+
+func TestParser_DeprecatedInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"On Struct", "@deprecated\nUser { id u32 }"},
+		{"On Enum", "@deprecated\nSt = A | B"},
+		{"On Embedded", "A { id u32 }\nB {\n@deprecated\nA\n}"},
+		{"Unknown Annotation", "@foo\nuser.get() => nil"},
+		{"Dangling", "user.get() => nil\n@deprecated"},
+		{"Non-string Argument", "@deprecated(1)\nuser.get() => nil"},
+		{"Idempotent Argument", "@idempotent(\"yes\")\nuser.get() => nil"},
+		{"Idempotent On Field", "User {\n@idempotent\nid u32\n}"},
+		{"Idempotent On Variant", "St = A | @idempotent B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(lexer.New(tt.input)).ParseSchema(); err == nil {
+				t.Errorf("ParseSchema() expected error")
+			}
+		})
+	}
+}
+
+// TestParser_Recovery 单次解析报告全部错误, 并携带行列信息
+func TestParser_Recovery(t *testing.T) {
+	input := `St = A | B(300) | C
+User {
+    id u32
+    name $ text
+    tags [u32
+    info Missing
+}
+User { x u8 }
+Foo Bar
+user.get(id u32) => Nope
+`
+	p := New(lexer.New(input))
+	p.File = "bad.sb"
+	_, err := p.ParseSchema()
+
+	var diags diag.List
+	if !errors.As(err, &diags) {
+		t.Fatalf("Expected diag.List, got %v", err)
+	}
+
+	want := []struct {
+		line, col int
+		code      string
+	}{
+		{1, 12, CodeInvalidEnumValue},
+		{4, 10, CodeIllegalChar},
+		{5, 14, CodeExpectedToken},
+		{6, 10, CodeUndefinedType},
+		{8, 1, CodeDuplicateDef},
+		{9, 1, CodeUnexpectedIdent},
+		{10, 21, CodeUndefinedType},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("Expected %d diagnostics, got %d:\n%v", len(want), len(diags), diags)
+	}
+	for i, w := range want {
+		d := diags[i]
+		if d.Line != w.line || d.Col != w.col || d.Code != w.code || d.File != "bad.sb" {
+			t.Errorf("diag[%d] = %s, want %d:%d %s", i, d, w.line, w.col, w.code)
+		}
+	}
+}
+
+// TestParser_Unterminated 缺少结束的 '}' 或引号时在起始位置报错, 后续定义照常解析
+func TestParser_Unterminated(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "Struct At EOF",
+			input: "A { id u32 }\nB {\n\tid u32\n\tname text\n",
+			want:  []string{`2:3: error[unclosed-brace]: 结构体 B 的 '{' 缺少对应的 '}'`},
+		},
+		{
+			name:  "Double Quoted Tag",
+			input: "A {\n\tid u32 \"json:id\n\tname text\n}\nB { info Missing }\n",
+			want: []string{
+				`2:9: error[unterminated-string]: 字符串缺少结束的 "`,
+				`5:10: error[undefined-type]: 未定义类型: Missing`,
+			},
+		},
+		{
+			name:  "Raw Tag At EOF",
+			input: "A {\n\tid u32 `json:\"id\"\n}\n",
+			want: []string{
+				`1:3: error[unclosed-brace]: 结构体 A 的 '{' 缺少对应的 '}'`,
+				"2:9: error[unterminated-string]: 字符串缺少结束的 `",
+			},
+		},
+		{
+			name:  "Annotation Argument",
+			input: "A {\n\t@deprecated(\"old\n\tid u32\n}\n",
+			want:  []string{`2:14: error[unterminated-string]: 字符串缺少结束的 "`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := New(lexer.New(tt.input)).Parse()
+			var got []string
+			for _, d := range diags {
+				got = append(got, d.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// TestParser_ApiRecovery API 签名出错后从下一行的定义继续解析, 各 API 的错误均被报告
+func TestParser_ApiRecovery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "Missing Arrow",
+			input: "user.a() => nil\nuser.b(id u32)\nuser.c(id u32, id u8,) => nil\n",
+			want: []string{
+				`2:15: error[expected-token]: 缺少 '=>', 实际为 "行尾"`,
+				`3:16: error[duplicate-argument]: API user.c 的参数 id 重复`,
+			},
+		},
+		{
+			name:  "Missing RParen",
+			input: "user.b(id u32\nuser.c(id u32, id u8) => nil\n",
+			want: []string{
+				`1:14: error[expected-token]: 缺少 ',' 或 ')', 实际为 "行尾"`,
+				`2:16: error[duplicate-argument]: API user.c 的参数 id 重复`,
+			},
+		},
+		{
+			name:  "Multi-line Args",
+			input: "user.b(\n\tid u32,\n\tname $,\n\tage u8,\n) => nil\nuser.c(id u32, id u8) => nil\n",
+			want: []string{
+				`3:7: error[expected-token]: 缺少 类型名称, 实际为 "$"`,
+				`6:16: error[duplicate-argument]: API user.c 的参数 id 重复`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := New(lexer.New(tt.input)).Parse()
+			var got []string
+			for _, d := range diags {
+				got = append(got, d.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParser_Lang(t *testing.T) {
+	p := New(lexer.New("User { info Missing }"))
+	p.Lang = diag.LangEN
+	_, diags := p.Parse()
+	if len(diags) != 1 || diags[0].Message != "undefined type: Missing" {
+		t.Errorf("Expected English message, got %v", diags)
+	}
+
+	p = New(lexer.New("User { info Missing }"))
+	_, diags = p.Parse()
+	if len(diags) != 1 || diags[0].Message != "未定义类型: Missing" {
+		t.Errorf("Expected Chinese message, got %v", diags)
+	}
+}
+
+// TestParser_ApiSignature 严格的 API 签名语法
+func TestParser_ApiSignature(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantCode string // 为空表示解析成功
+	}{
+		{"Simple", "user.get(id u32) => u32", ""},
+		{"No Args", "user.get() => nil", ""},
+		{"Trailing Comma", "user.get(id u32, name text,) => nil", ""},
+		{"Multi-line Args", "user.get(\n\tid u32, // 用户\n\tnames [text],\n) => [u32] // 获取\n", ""},
+		{"Missing RParen", "user.get(id u32 => u32", CodeExpectedToken},
+		{"Missing LParen", "user.get id u32) => u32", CodeUnexpectedIdent},
+		{"Missing Comma", "user.get(id u32 name text) => u32", CodeExpectedToken},
+		{"Missing Arrow", "user.get(id u32) u32", CodeExpectedToken},
+		{"Missing Result", "user.get(id u32) =>", CodeExpectedToken},
+		{"Result On Next Line", "user.get(id u32) =>\nuser.other() => nil", CodeExpectedToken},
+		{"Missing Arg Type", "user.get(id) => nil", CodeExpectedToken},
+		{"Trailing Garbage", "user.get() => u32 u32", CodeUnexpectedToken},
+		{"Nil Argument", "user.get(id nil) => nil", CodeInvalidNil},
+		{"Nil List Result", "user.get() => [nil]", CodeInvalidNil},
+		{"Duplicate Api", "user.get() => nil\nuser.get(id u32) => nil", CodeDuplicateApi},
+		{"Duplicate Arg", "user.get(id u32, id text) => nil", CodeDuplicateArg},
+		{"Error Enum", "E = A | B\nuser.get() => nil ! E // 获取", ""},
+		{"Missing Error Type", "user.get() => nil !", CodeExpectedToken},
+		{"Error Not Enum", "user.get() => nil ! u8", CodeInvalidApiError},
+		{"Error List", "E = A | B\nuser.get() => nil ! [E]", CodeInvalidApiError},
+		{"Undefined Error", "user.get() => nil ! E", CodeUndefinedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := New(lexer.New(tt.input)).Parse()
+			if tt.wantCode == "" {
+				if len(diags) > 0 {
+					t.Errorf("Parse() unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+			if len(diags) == 0 || diags[0].Code != tt.wantCode {
+				t.Errorf("Parse() diagnostics = %v, want code %s", diags, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestParser_ApiMultiLine(t *testing.T) {
+	input := `
+		// 获取用户
+		user.get(
+			id u32,
+			names [text], // 名称列表
+		) => [u32] // 说明
+		user.other() => nil
+	`
+	schema, err := New(lexer.New(input)).ParseSchema()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if len(schema.Apis) != 2 {
+		t.Fatalf("Expected 2 apis, got %d", len(schema.Apis))
+	}
+	api := schema.Apis[0]
+	if len(api.Args) != 2 || api.Args[1].Name != "names" || !api.Args[1].Type.IsList {
+		t.Errorf("args mismatch: %+v", api.Args)
+	}
+	if !api.Result.IsList || api.Result.Name != "u32" || api.Note != "说明" {
+		t.Errorf("result mismatch: %+v note=%q", api.Result, api.Note)
+	}
+}
+
+func TestParser_ApiError(t *testing.T) {
+	schema, err := New(lexer.New("SimError = NotFound | Expired\nsim.set(id u32) => u8 ! SimError // 设置")).ParseSchema()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	api := schema.Apis[0]
+	if api.Error == nil || api.Error.Name != "SimError" || api.Error.Kind != ast.KindEnum || api.Note != "设置" {
+		t.Errorf("error mismatch: %+v note=%q", api.Error, api.Note)
+	}
+}
+
+func TestParser_GoPackageDirective(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // 期望的 Schema.GoPackage, 或以 "error: " 开头的诊断信息
+	}{
+		{"Import Path", "// sb:go-package example.com/app/proto/billing\nA { id u8 }", "example.com/app/proto/billing"},
+		{"With Name", "// sb:go-package example.com/app/proto/v2;billing\n", "example.com/app/proto/v2;billing"},
+		{"Not A Note", "// sb:go-package a/b\n// 说明\nA { id u8 }", "a/b"},
+		{"Empty", "// sb:go-package\n", "error: 指令 sb:go-package 无效: 缺少导入路径"},
+		{"Bad Name", "// sb:go-package a/b;my-pkg\n", `error: 指令 sb:go-package 无效: 包名 "my-pkg" 不是合法的 Go 标识符`},
+		{"Repeated", "// sb:go-package a/b\n// sb:go-package a/c\n", "error: 指令 sb:go-package 无效: 每个文件只能出现一次"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, diags := New(lexer.New(tt.input)).Parse()
+			got := schema.GoPackage
+			if len(diags) > 0 {
+				got = "error: " + diags[0].Message
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if tt.name == "Not A Note" && schema.Structs[0].Note != "说明" {
+				t.Errorf("directive leaked into note: %q", schema.Structs[0].Note)
+			}
+		})
+	}
+}
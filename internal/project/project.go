@@ -0,0 +1,284 @@
+// Package project 项目配置文件 (sb.yaml)
+//
+// 配置列出输入的 Schema 与各生成目标, 取代每个仓库各自维护的包装脚本:
+//
+//	version: 1
+//	lang: zh
+//	wire: 1                    # 编码格式版本, 目前仅支持 1
+//	templates: ./tpl           # 覆盖内置模板的目录 (可选)
+//	inputs:
+//	  - billing.sb
+//	  - file: sim.sb
+//	    targets:               # 覆盖该输入的目标配置 (按字段合并)
+//	      ts: {out: ./web/sim}
+//	targets:                   # 只生成列出的目标, 可用目标见 generator.TargetNames
+//	  go:
+//	    out: .
+//	    package: billing
+//	    subdir: internal/proto/billing
+//	    import: example.com/app/internal/proto/billing
+//	    tags: [bson, json]
+//	  ts:
+//	    out: ./web
+//	  doc:
+//	    out: ./docs            # 省略 out 时写入各代码目录
+//
+// 配置中的相对路径均相对配置文件所在目录。
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"sb/internal/generator"
+)
+
+// DefaultFile 未指定 -config 时在当前目录查找的配置文件
+const DefaultFile = "sb.yaml"
+
+// Version 当前配置格式版本
+const Version = 1
+
+// WireVersion 支持的编码格式版本
+const WireVersion = 1
+
+// 内置目标名称; 全部可用目标见 generator.TargetNames
+const (
+	TargetGo  = "go"
+	TargetTs  = "ts"
+	TargetDoc = "doc"
+)
+
+// Default 没有配置文件时的配置: 生成全部已注册的目标
+func Default() *Config {
+	targets := make(map[string]Target)
+	for _, name := range generator.TargetNames() {
+		targets[name] = Target{}
+	}
+	return &Config{
+		Version: Version,
+		Wire:    WireVersion,
+		Targets: targets,
+	}
+}
+
+// Config 项目配置
+type Config struct {
+	Version   int               `json:"version"`
+	Lang      string            `json:"lang"`
+	Wire      int               `json:"wire"`
+	Templates string            `json:"templates"` // 覆盖内置模板的目录, 见 generator.Overlay
+	Inputs    []Input           `json:"inputs"`
+	Targets   map[string]Target `json:"targets"` // 键为目标名称, 未列出的目标不生成
+
+	Dir string `json:"-"` // 配置文件所在目录, 相对路径以此为基准
+}
+
+// Input 一个输入 Schema; 配置中可直接写文件名
+type Input struct {
+	File    string            `json:"file"`
+	Targets map[string]Target `json:"targets"` // 覆盖顶层同名目标的非空字段
+}
+
+func (in *Input) UnmarshalJSON(data []byte) error {
+	var file string
+	if err := json.Unmarshal(data, &file); err == nil {
+		in.File = file
+		return nil
+	}
+	type plain Input
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(in))
+}
+
+// Target 一个生成目标的配置
+type Target struct {
+	Out     string            `json:"out"`     // 输出目录
+	Package string            `json:"package"` // go: 包名
+	Subdir  string            `json:"subdir"`  // go: 相对 out 的子目录
+	Import  string            `json:"import"`  // go: 完整导入路径
+	Tags    []string          `json:"tags"`    // go: 附加的 struct tag
+	Options map[string]string `json:"options"` // 其余目标特定选项
+}
+
+// Merge 用 o 中的非空字段覆盖 t
+func (t Target) Merge(o Target) Target {
+	if o.Out != "" {
+		t.Out = o.Out
+	}
+	if o.Package != "" {
+		t.Package = o.Package
+	}
+	if o.Subdir != "" {
+		t.Subdir = o.Subdir
+	}
+	if o.Import != "" {
+		t.Import = o.Import
+	}
+	if o.Tags != nil {
+		t.Tags = o.Tags
+	}
+	if len(o.Options) > 0 {
+		opts := maps.Clone(t.Options)
+		if opts == nil {
+			opts = make(map[string]string)
+		}
+		maps.Copy(opts, o.Options)
+		t.Options = opts
+	}
+	return t
+}
+
+// Config 转换为生成器使用的目标配置
+func (t Target) Config() generator.TargetConfig {
+	return generator.TargetConfig{
+		Out:     t.Out,
+		Package: t.Package,
+		Subdir:  t.Subdir,
+		Import:  t.Import,
+		Tags:    t.Tags,
+		Options: t.Options,
+	}
+}
+
+// Find 在 dir 中查找默认配置文件, 不存在时返回空字符串
+func Find(dir string) (string, error) {
+	path := filepath.Join(dir, DefaultFile)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// Load 读取并校验配置文件
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.Dir = filepath.Dir(path)
+	return cfg, nil
+}
+
+// Parse 解析并校验 YAML 配置内容
+func Parse(data []byte) (*Config, error) {
+	v, err := decodeYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	// 通用结构经 JSON 转换为 Config, 复用 json 标签与类型检查
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if v != nil {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 校验版本与目标名称; 未填写的版本按当前版本处理
+func (c *Config) Validate() error {
+	if c.Version == 0 {
+		c.Version = Version
+	}
+	if c.Version != Version {
+		return fmt.Errorf("unsupported version %d (supported: %d)", c.Version, Version)
+	}
+	if c.Wire == 0 {
+		c.Wire = WireVersion
+	}
+	if c.Wire != WireVersion {
+		return fmt.Errorf("unsupported wire version %d (supported: %d)", c.Wire, WireVersion)
+	}
+	if c.Lang != "" && c.Lang != "zh" && c.Lang != "en" {
+		return fmt.Errorf("unsupported lang %q (zh|en)", c.Lang)
+	}
+	if err := checkTargets(c.Targets); err != nil {
+		return err
+	}
+	for i, in := range c.Inputs {
+		if in.File == "" {
+			return fmt.Errorf("inputs[%d]: missing file", i)
+		}
+		for name := range in.Targets {
+			if _, ok := c.Targets[name]; !ok {
+				return fmt.Errorf("inputs[%d]: target %q is not enabled in targets", i, name)
+			}
+		}
+		if err := checkTargets(in.Targets); err != nil {
+			return fmt.Errorf("inputs[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func checkTargets(targets map[string]Target) error {
+	return CheckTargetNames(slices.Sorted(maps.Keys(targets)))
+}
+
+// CheckTargetNames 校验目标名称均已在 generator 中注册或有对应的插件
+func CheckTargetNames(names []string) error {
+	_, err := generator.OrderTargets(names)
+	return err
+}
+
+// Resolve 输入文件的最终目标配置: 顶层配置合并输入级覆盖, 相对路径转换为基于配置目录
+func (c *Config) Resolve(in Input) map[string]Target {
+	result := make(map[string]Target, len(c.Targets))
+	for name, t := range c.Targets {
+		t = t.Merge(in.Targets[name])
+		if t.Out == "" {
+			target, _ := generator.LookupTarget(name)
+			t.Out = target.DefaultOut
+		}
+		if t.Out != "" {
+			t.Out = c.path(t.Out)
+		}
+		result[name] = t
+	}
+	return result
+}
+
+// TemplatesPath 模板目录相对配置目录的路径, 未配置时为空
+func (c *Config) TemplatesPath() string {
+	if c.Templates == "" {
+		return ""
+	}
+	return c.path(c.Templates)
+}
+
+// InputPath 输入文件相对配置目录的路径
+func (c *Config) InputPath(in Input) string {
+	return c.path(in.File)
+}
+
+func (c *Config) path(p string) string {
+	if filepath.IsAbs(p) || c.Dir == "" {
+		return p
+	}
+	return filepath.Join(c.Dir, p)
+}
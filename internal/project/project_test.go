@@ -0,0 +1,205 @@
+package project
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	input := `
+# 注释
+name: "a # b"   # 行尾注释
+count: 3
+enabled: true
+none: ~
+list:
+  - x
+  - 'it''s'
+same_indent:
+- 1
+- 2
+flow: [a, "b, c", 1]
+map: {out: ./web, num: 2}
+empty: {}
+items:
+  - file: a.sb
+    targets:
+      go:
+        out: .
+  -
+    nested: "yes"
+`
+	got, err := decodeYAML([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":        "a # b",
+		"count":       int64(3),
+		"enabled":     true,
+		"none":        nil,
+		"list":        []any{"x", "it's"},
+		"same_indent": []any{int64(1), int64(2)},
+		"flow":        []any{"a", "b, c", int64(1)},
+		"map":         map[string]any{"out": "./web", "num": int64(2)},
+		"empty":       map[string]any{},
+		"items": []any{
+			map[string]any{"file": "a.sb", "targets": map[string]any{"go": map[string]any{"out": "."}}},
+			map[string]any{"nested": "yes"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+// TestDecodeYAML_Plain 普通标量中的引号与 # 按完整 YAML 的规则处理
+func TestDecodeYAML_Plain(t *testing.T) {
+	input := "a: it's # 注释\nb: x#y\nc: [it's, 'd''e',]\nd: {e: }\n"
+	got, err := decodeYAML([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"a": "it's", "b": "x#y", "c": []any{"it's", "d'e"}, "d": map[string]any{"e": nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestDecodeYAML_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"tab", "a:\n\tb: 1", "line 2: tabs"},
+		{"indent", "a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"duplicate", "a: 1\na: 2", `line 2: duplicate key "a"`},
+		{"not a mapping", "a: 1\nb", `line 2: expected "key: value"`},
+		{"nested flow", "a: [[1]]", "line 1: nested flow"},
+		{"anchor", "a: &x 1", "line 1: anchors"},
+		{"documents", "a: 1\n---\nb: 2", "line 2: multiple documents"},
+		// 完整 YAML 解析为其他含义的写法
+		{"bool alias", "a: 1\nb: yes", "line 2: yes is not a plain string"},
+		{"bool alias key", "on: 1", "line 1: on is not a plain string"},
+		{"float", "a: 1.5", "line 1: 1.5 is not a plain string"},
+		{"hex", "a: 0x10", "line 1: 0x10 is not a plain string"},
+		{"leading zero", "a: 010", "line 1: 010 is not a plain string"},
+		{"go escape", `a: "\x41"`, `line 1: unsupported escape \x`},
+		{"text after quote", `a: "b" c`, `line 1: unexpected " c" after quoted string`},
+		{"single quote", "a: 'b'c'", `line 1: unexpected "c'" after quoted string`},
+		{"unterminated", `a: "b`, "line 1: unterminated quoted string"},
+		{"mapping in value", "a: b: c", `line 1: unexpected ": " in value "b: c"`},
+		{"mapping in flow", "a: [b: c]", "line 1: mappings inside flow sequences"},
+		{"empty flow item", "a: [b, , c]", "line 1: empty item"},
+		{"nested sequence", "a:\n  - - b", "line 2: nested sequences"},
+		{"tag", "a: !!str 1", "line 1: \"!!str 1\" cannot start a plain scalar"},
+		{"alias", "a: 1\nb: *x", "line 2: anchors, aliases"},
+		{"literal block", "a: |\n  b", "line 1: anchors, aliases and block scalars"},
+		{"folded block", "a: >\n  b", "line 1: anchors, aliases and block scalars"},
+		{"multi-line plain", "a: b\n  c", "line 2: unexpected indentation"},
+		{"reserved @", "a: @b", "line 1: \"@b\" cannot start a plain scalar"},
+		{"reserved backtick", "a: `b`", "line 1: \"`b`\" cannot start a plain scalar"},
+		{"directive in value", "a: %b", "line 1: \"%b\" cannot start a plain scalar"},
+		{"complex key", "? a\n: b", `line 1: expected "key: value", found "? a"`},
+		{"flow indicator", "a: ]b", "line 1: \"]b\" cannot start a plain scalar"},
+		{"trailing colon", "a: b:", `line 1: unexpected ": " in value "b:"`},
+		{"short bool", "a: n", "line 1: n is not a plain string"},
+		{"infinity", "a: .inf", "line 1: .inf is not a plain string"},
+		{"not a number", "a: .NaN", "line 1: .NaN is not a plain string"},
+		{"octal", "a: 0o17", "line 1: 0o17 is not a plain string"},
+		{"binary", "a: 0b101", "line 1: 0b101 is not a plain string"},
+		{"exponent", "a: 1e3", "line 1: 1e3 is not a plain string"},
+		{"bool key", "true: 1", "line 1: key true is not a string"},
+		{"number key", "a:\n  1: b", "line 2: key 1 is not a string"},
+		{"empty key", `"": 1`, "line 1: empty key"},
+		{"unterminated single", "a: 'b", "line 1: unterminated quoted string"},
+		{"multi-line flow sequence", "a: [b,\n  c]", "line 1: unterminated flow sequence"},
+		{"multi-line flow mapping", "a: {b: 1,\n  c: 2}", "line 1: unterminated flow mapping"},
+		{"flow mapping item", "a: {b}", `line 1: expected "key: value" in flow mapping, found "b"`},
+		{"flow duplicate", "a: {b: 1, b: 2}", `line 1: duplicate key "b"`},
+		{"nested flow mapping", "a: {b: [c]}", "line 1: nested flow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeYAML([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`
+templates: ./tpl
+inputs:
+  - billing.sb
+  - file: sim.sb
+    targets:
+      go: {package: simpb}
+      ts: {out: ./web/sim}
+targets:
+  go:
+    out: .
+    tags: [bson, json]
+  ts:
+    out: ./web
+  doc:
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Dir = "/repo"
+	if cfg.Version != Version || cfg.Wire != WireVersion {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+
+	billing := cfg.Resolve(cfg.Inputs[0])
+	want := map[string]Target{
+		TargetGo:  {Out: "/repo", Tags: []string{"bson", "json"}},
+		TargetTs:  {Out: "/repo/web"},
+		TargetDoc: {},
+	}
+	if !reflect.DeepEqual(billing, want) {
+		t.Errorf("billing targets = %+v, want %+v", billing, want)
+	}
+
+	sim := cfg.Resolve(cfg.Inputs[1])
+	if sim[TargetGo].Package != "simpb" || sim[TargetGo].Out != "/repo" || sim[TargetTs].Out != "/repo/web/sim" {
+		t.Errorf("sim targets = %+v", sim)
+	}
+	if got := cfg.TemplatesPath(); got != filepath.Join("/repo", "tpl") {
+		t.Errorf("templates path = %s", got)
+	}
+	if got := cfg.InputPath(cfg.Inputs[1]); got != filepath.Join("/repo", "sim.sb") {
+		t.Errorf("input path = %s", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"version", "version: 2", "unsupported version 2"},
+		{"wire", "wire: 3", "unsupported wire version 3"},
+		{"lang", "lang: fr", `unsupported lang "fr"`},
+		{"unknown field", "target: {}", `unknown field "target"`},
+		{"unknown target", "targets:\n  java: {}", `unknown target "java"`},
+		{"disabled target", "targets:\n  go: {}\ninputs:\n  - file: a.sb\n    targets:\n      ts: {}", `target "ts" is not enabled`},
+		{"missing file", "inputs:\n  - targets: {}", "inputs[0]: missing file"},
+		{"type", "targets:\n  go:\n    tags: json", "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package project
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 项目配置只依赖标准库, 这里实现 YAML 的一个子集, 足以表达 sb.yaml:
+//   - 块映射 (key: value) 与块序列 (- item), 以空格缩进
+//   - 序列项可以是映射 (- key: value 后接同级缩进的键)
+//   - 单层的流式序列 [a, "b", c] 与流式映射 {key: value, ...}
+//   - 标量: 双引号字符串 (仅支持 \\ \" \n \t \r 转义), 单引号字符串 ('' 表示 '),
+//     true/false, null/~, 十进制整数, 其余为字符串
+//   - # 注释 (行首或空白之后, 引号内除外)
+//
+// 子集内的输入与完整的 YAML 解析结果一致; 完整 YAML 会解析为其他含义的写法一律报错并给出行号,
+// 包括锚点, 标签, 多文档, 多行字符串, 嵌套的流式集合, 以及 yes/no/on/off, 浮点数,
+// 0x/0o 前缀或前导零的数字等不会按字符串解析, 或在 YAML 1.1 与 1.2 间含义不同的普通标量 (需加引号)。
+// 面向用户的完整列表见 README 的 "YAML 子集" 一节, 修改支持范围时同步更新, 并在 TestDecodeYAML_Errors 中为报错的写法补充用例。
+
+type yamlLine struct {
+	num    int    // 行号 (从 1 开始)
+	indent int    // 缩进空格数
+	text   string // 去掉缩进与注释后的内容
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// decodeYAML 解析为 map[string]any / []any / 标量组成的通用结构
+func decodeYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \r")
+		content := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(content)
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		content = strings.TrimSpace(stripComment(content))
+		if content == "" {
+			continue
+		}
+		if content == "---" || strings.HasPrefix(content, "%") {
+			if len(p.lines) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: indent, text: content})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	v, err := p.parseNode(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+// stripComment 去掉引号之外的 # 注释
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && opensQuote(s, i):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// opensQuote s[i] 处的引号是否开始带引号的标量: 引号须位于标量开头 (行首, "key: ", "- " 或流式集合的 [ { , 之后),
+// 普通标量中间的引号 (如 it's) 只是普通字符
+func opensQuote(s string, i int) bool {
+	j := i - 1
+	for j >= 0 && s[j] == ' ' {
+		j--
+	}
+	if j < 0 {
+		return true
+	}
+	switch s[j] {
+	case '[', '{', ',':
+		return true
+	case ':', '-':
+		return j < i-1
+	}
+	return false
+}
+
+// parseNode 解析缩进为 indent 的块 (序列或映射)
+func (p *yamlParser) parseNode(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseSeq(indent int) ([]any, error) {
+	seq := []any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		// 与键同级缩进的序列在下一个键处结束
+		if line.indent < indent || line.indent == indent && !isSeqItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		switch {
+		case rest == "":
+			p.pos++
+			v, err := p.parseChild(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		case splitKey(rest) >= 0:
+			// "- key: value": 映射从 rest 所在列开始, 后续键与之对齐
+			p.lines[p.pos] = yamlLine{num: line.num, indent: line.indent + len(line.text) - len(rest), text: rest}
+			v, err := p.parseMap(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		default:
+			v, err := parseScalar(rest, line.num)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			p.pos++
+		}
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		i := splitKey(line.text)
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected \"key: value\", found %q", line.num, line.text)
+		}
+		key, err := parseKey(line.text[:i], line.num)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		rest := strings.TrimSpace(line.text[i+1:])
+		p.pos++
+		if rest == "" {
+			// 允许序列与键同级缩进: key:\n- a
+			if m[key], err = p.parseChild(indent, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if m[key], err = parseScalar(rest, line.num); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// parseChild 解析键或序列项之后的嵌套块, 没有嵌套块时值为 null
+func (p *yamlParser) parseChild(indent int, allowSameIndentSeq bool) (any, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || allowSameIndentSeq && next.indent == indent && isSeqItem(next.text) {
+		return p.parseNode(next.indent)
+	}
+	return nil, nil
+}
+
+// splitKey 返回映射键后冒号的位置 (冒号后为空白或行尾, 且不在引号内), 不是映射项时返回 -1
+func splitKey(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == '[' || c == '{':
+			if i == 0 {
+				return -1
+			}
+		case c == ':' && (i+1 == len(s) || s[i+1] == ' '):
+			return i
+		}
+	}
+	return -1
+}
+
+func parseKey(s string, num int) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		v, err := parseQuoted(s, num)
+		if err != nil {
+			return "", err
+		}
+		s = v
+	} else if v, err := parseScalar(s, num); err != nil {
+		return "", err
+	} else if _, ok := v.(string); !ok {
+		return "", fmt.Errorf("line %d: key %s is not a string, quote it", num, s)
+	}
+	if s == "" {
+		return "", fmt.Errorf("line %d: empty key", num)
+	}
+	return s, nil
+}
+
+// parseScalar 解析标量或流式集合
+func parseScalar(s string, num int) (any, error) {
+	switch {
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("line %d: unterminated flow mapping", num)
+		}
+		m := map[string]any{}
+		items, err := flowItems(s[1:len(s)-1], num)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			i := splitKey(item)
+			if i < 0 {
+				return nil, fmt.Errorf("line %d: expected \"key: value\" in flow mapping, found %q", num, item)
+			}
+			key, err := parseKey(item[:i], num)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := m[key]; ok {
+				return nil, fmt.Errorf("line %d: duplicate key %q", num, key)
+			}
+			if m[key], err = parseScalar(strings.TrimSpace(item[i+1:]), num); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("line %d: unterminated flow sequence", num)
+		}
+		seq := []any{}
+		items, err := flowItems(s[1:len(s)-1], num)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if splitKey(item) >= 0 {
+				return nil, fmt.Errorf("line %d: mappings inside flow sequences are not supported, found %q", num, item)
+			}
+			v, err := parseScalar(item, num)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		}
+		return seq, nil
+	case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'"):
+		return parseQuoted(s, num)
+	case strings.HasPrefix(s, "&") || strings.HasPrefix(s, "*") || strings.HasPrefix(s, "|") || strings.HasPrefix(s, ">"):
+		return nil, fmt.Errorf("line %d: anchors, aliases and block scalars are not supported", num)
+	case s != "" && strings.IndexByte("!@`%?,]}", s[0]) >= 0:
+		return nil, fmt.Errorf("line %d: %q cannot start a plain scalar, quote it", num, s)
+	case s == "-" || strings.HasPrefix(s, "- "):
+		return nil, fmt.Errorf("line %d: nested sequences on one line are not supported", num)
+	case splitKey(s) >= 0 || strings.HasSuffix(s, ":"):
+		return nil, fmt.Errorf("line %d: unexpected \": \" in value %q, quote it", num, s)
+	}
+	switch s {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~", "":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && !hasLeadingZero(s) {
+		return n, nil
+	}
+	if ambiguous(s) {
+		return nil, fmt.Errorf("line %d: %s is not a plain string in all YAML versions, quote it", num, s)
+	}
+	return s, nil
+}
+
+// parseQuoted 解析双引号或单引号字符串, 结束引号之后不能再有内容
+func parseQuoted(s string, num int) (string, error) {
+	var b strings.Builder
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == quote:
+			if i+1 != len(s) {
+				return "", fmt.Errorf("line %d: unexpected %q after quoted string", num, s[i+1:])
+			}
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if i+1 == len(s) {
+				return "", fmt.Errorf("line %d: unterminated quoted string %s", num, s)
+			}
+			i++
+			switch s[i] {
+			case '\\', '"':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				return "", fmt.Errorf("line %d: unsupported escape \\%c in quoted string", num, s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("line %d: unterminated quoted string %s", num, s)
+}
+
+// hasLeadingZero 带前导零的整数 (如 010), YAML 1.1 按八进制解析
+func hasLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0'
+}
+
+// ambiguous 完整 YAML (1.1 或 1.2) 不会解析为字符串的普通标量: 布尔别名, 浮点数, 带进制前缀或前导零的整数
+func ambiguous(s string) bool {
+	switch strings.ToLower(s) {
+	case "yes", "no", "on", "off", "y", "n", ".inf", "+.inf", "-.inf", ".nan":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return true
+	}
+	return hasLeadingZero(s) && strings.Trim(s, "+-0123456789") == ""
+}
+
+// flowItems 流式集合的元素 (不支持嵌套)
+func flowItems(inner string, num int) ([]string, error) {
+	inner = strings.TrimSpace(inner)
+	if inner == "" {
+		return nil, nil
+	}
+	items := splitFlow(inner)
+	if len(items) > 1 && strings.TrimSpace(items[len(items)-1]) == "" {
+		items = items[:len(items)-1] // 允许末尾逗号
+	}
+	for i, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, fmt.Errorf("line %d: empty item in flow collection", num)
+		}
+		if strings.HasPrefix(item, "[") || strings.HasPrefix(item, "{") || strings.Contains(item, ": [") || strings.Contains(item, ": {") {
+			return nil, fmt.Errorf("line %d: nested flow collections are not supported", num)
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// splitFlow 按引号外的逗号切分流式集合
+func splitFlow(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && opensQuote(s, i):
+			quote = c
+		case c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
@@ -1,155 +1,316 @@
 package main
 
 import (
-	"bytes"
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"sb/internal/ast"
+	"sb/internal/descriptor"
+	"sb/internal/diag"
+	"sb/internal/diff"
 	"sb/internal/generator"
 	"sb/internal/lexer"
 	"sb/internal/parser"
-	"sb/internal/util"
+	"sb/internal/project"
+	"slices"
 	"strings"
-	"text/template"
 )
 
+// commands 子命令表; 第一个参数不是子命令时执行代码生成
+var commands = map[string]func(args []string) error{
+	"breaking":   runBreaking,
+	"descriptor": runDescriptor,
+	"fmt":        runFmt,
+	"lint":       runLint,
+	"lsp":        runLsp,
+	"templates":  runTemplates,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("代码生成成功。")
 }
 
 func run() error {
 	goDir := flag.String("go", "./go", "Go 代码输出目录")
 	tsDir := flag.String("ts", "./ts", "TypeScript 代码输出目录")
 	tags := flag.String("tag", "", "Go 结构体 Tag (例如 bson,json)")
+	goPackage := flag.String("go-package", "", "Go 包名 (默认取导入路径或子目录的最后一段, 否则为 sb)")
+	goSubdir := flag.String("go-subdir", "", "Go 代码输出子目录, 相对 -go 目录 (默认由导入路径推断, 否则与包名相同)")
+	goImport := flag.String("go-import", "", "生成包的完整导入路径 (覆盖 sb:go-package 指令; 默认由 go.mod 推断)")
+	langFlag := flag.String("lang", "zh", "诊断信息语言 (zh|en)")
+	configPath := flag.String("config", "", "项目配置文件 (默认读取当前目录下的 "+project.DefaultFile+")")
+	templatesDir := flag.String("templates", "", "覆盖内置模板的目录, 其中的同名文件替换内置模板 (见 sb templates)")
+	goServer := flag.String("go-server", "", "Go 服务端业务逻辑的组织方式: interface 生成服务接口 (默认), functions 使用包内的处理函数 api.<name>.go")
+	handlers := flag.String("handlers", "", "处理函数 api.<name>.go 的签名与 Schema 不一致时: rewrite 改写签名并保留函数体 (默认), report 只报告")
+	pruneHandlers := flag.Bool("prune-handlers", false, "同时删除已从 Schema 移除的 API 的 api.<name>.go (其中手写的业务逻辑将丢失)")
+	check := flag.Bool("check", false, "只在内存中生成并与磁盘比较, 输出差异; 有差异时以非 0 状态退出 (用于 CI)")
+	targetList := flag.String("targets", "", "要生成的目标, 逗号分隔 (默认为配置中的全部目标; 可用: "+strings.Join(generator.TargetNames(), ", ")+")")
 
 	flag.Parse()
 
-	args := flag.Args()
-	if len(args) != 1 {
-		flag.Usage()
-		return fmt.Errorf("缺少输入文件")
-	}
-
-	schema, err := parseSchema(args[0])
+	proj, err := loadProject(*configPath)
 	if err != nil {
-		return fmt.Errorf("解析错误: %w", err)
-	}
-
-	cfg := generator.Config{
-		GoDir: *goDir,
-		TsDir: *tsDir,
-		GoTag: *tags,
-		TplFS: generator.TplFS,
-	}
-
-	if err := generateCode(schema, cfg); err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func parseSchema(filename string) (*ast.Schema, error) {
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+	// 显式指定的命令行参数覆盖配置文件; 只有 -go / -ts 会启用配置中未列出的目标, 其余参数只设置已启用目标的选项
+	overrides := make(map[string]project.Target)
+	override := func(target string, t project.Target) {
+		overrides[target] = overrides[target].Merge(t)
 	}
-
-	l := lexer.New(string(content))
-	p := parser.New(l)
-	return p.ParseSchema()
-}
-
-func generateCode(schema *ast.Schema, cfg generator.Config) error {
-	goGen := generator.NewGoGenerator(cfg)
-	if err := goGen.Generate(schema); err != nil {
-		return fmt.Errorf("go generation: %w", err)
+	enable := func(target string) {
+		if _, ok := proj.Targets[target]; !ok {
+			if proj.Targets == nil {
+				proj.Targets = make(map[string]project.Target)
+			}
+			proj.Targets[target] = project.Target{}
+		}
 	}
-
-	tsGen := generator.NewTsGenerator(cfg)
-	if err := tsGen.Generate(schema); err != nil {
-		return fmt.Errorf("ts generation: %w", err)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "go":
+			enable(project.TargetGo)
+			override(project.TargetGo, project.Target{Out: *goDir})
+		case "ts":
+			enable(project.TargetTs)
+			override(project.TargetTs, project.Target{Out: *tsDir})
+		case "tag":
+			override(project.TargetGo, project.Target{Tags: splitList(*tags)})
+		case "go-package":
+			override(project.TargetGo, project.Target{Package: *goPackage})
+		case "go-subdir":
+			override(project.TargetGo, project.Target{Subdir: *goSubdir})
+		case "go-import":
+			override(project.TargetGo, project.Target{Import: *goImport})
+		case "go-server":
+			override(project.TargetGo, project.Target{Options: map[string]string{"server": *goServer}})
+		case "handlers":
+			override(project.TargetGo, project.Target{Options: map[string]string{"handlers": *handlers}})
+		case "lang":
+			proj.Lang = *langFlag
+		case "templates":
+			// 命令行中的路径相对当前目录, 而不是配置目录
+			proj.Templates, _ = filepath.Abs(*templatesDir)
+		}
+	})
+	if *targetList != "" {
+		// 只生成选中的目标, 其余目标的配置与命令行参数一并忽略
+		names := splitList(*targetList)
+		if err := project.CheckTargetNames(names); err != nil {
+			return err
+		}
+		selected := make(map[string]project.Target, len(names))
+		for _, name := range names {
+			selected[name] = proj.Targets[name]
+		}
+		proj.Targets = selected
+		for name := range overrides {
+			if _, ok := selected[name]; !ok {
+				delete(overrides, name)
+			}
+		}
 	}
-
-	if err := generateDoc(schema, cfg); err != nil {
-		return fmt.Errorf("documentation: %w", err)
+	if flag.NArg() > 0 {
+		proj.Inputs = nil
+		for _, arg := range flag.Args() {
+			proj.Inputs = append(proj.Inputs, project.Input{File: arg})
+		}
+		proj.Dir = ""
 	}
-
-	return nil
-}
-
-func generateDoc(schema *ast.Schema, cfg generator.Config) error {
-	tplContent, err := cfg.TplFS.ReadFile("_tpl/doc.md.tpl")
-	if err != nil {
-		return err
+	if len(proj.Inputs) == 0 {
+		flag.Usage()
+		return fmt.Errorf("缺少输入文件")
 	}
 
-	groups := groupApis(schema.Apis)
-	funcMap := createFuncMap(cfg)
-
-	tpl, err := template.New("doc").Funcs(funcMap).Parse(string(tplContent))
+	lang, err := diag.ParseLang(cmp.Or(proj.Lang, "zh"))
 	if err != nil {
 		return err
 	}
 
-	data := map[string]any{
-		"Apis":    schema.Apis,
-		"Enums":   schema.Enums,
-		"Structs": schema.Structs,
-		"Note":    schema.Note,
-		"Groups":  groups,
+	var tplFS fs.FS
+	if dir := proj.TemplatesPath(); dir != "" {
+		if tplFS, err = generator.Overlay(dir); err != nil {
+			return err
+		}
 	}
 
-	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, data); err != nil {
-		return err
+	output := generator.Disk
+	var mem *generator.Memory
+	if *check {
+		mem = generator.NewMemory()
+		output = mem
 	}
 
-	if err := writeDocFile(cfg.GoDir, buf.Bytes()); err != nil {
-		return err
+	owners := make(map[string]string) // 输出目录 -> 输入文件, 检查多个输入之间的冲突
+	for _, in := range proj.Inputs {
+		input := proj.InputPath(in)
+		schema, err := parseSchema(input, lang)
+		if err != nil {
+			return fmt.Errorf("解析错误:\n%w", err)
+		}
+
+		targets := proj.Resolve(in)
+		for name, t := range overrides {
+			if _, ok := targets[name]; ok {
+				targets[name] = targets[name].Merge(t)
+			}
+		}
+		configs := make(map[string]generator.TargetConfig, len(targets))
+		for name, t := range targets {
+			tc := t.Config()
+			tc.TplFS = tplFS
+			tc.File = input
+			tc.Lang = lang
+			configs[name] = tc
+		}
+
+		// 按生成顺序创建生成器, 先检查输出目录冲突再统一生成
+		ordered, err := generator.OrderTargets(slices.Collect(maps.Keys(configs)))
+		if err != nil {
+			return err
+		}
+		var gens []generator.Generator
+		var trackers []*generator.Tracker // 记录各目标写入的文件, 用于删除不再生成的文件
+		for _, target := range ordered {
+			tracker := generator.NewTracker(output, target.Name)
+			tc := configs[target.Name]
+			tc.Output = tracker
+			gen, err := target.New(tc, configs)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", input, target.Name, err)
+			}
+			if d, ok := gen.(generator.OutputDirer); ok {
+				dir, err := d.OutputDir(schema)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", input, target.Name, err)
+				}
+				tracker.Root = dir
+				if dir != "" {
+					if abs, err := filepath.Abs(dir); err == nil {
+						dir = abs
+					}
+					if prev, ok := owners[dir]; ok && prev != input {
+						return fmt.Errorf("输出目录 %s 同时被 %s 与 %s 使用, 请为其指定不同的包或输出目录", dir, prev, input)
+					}
+					owners[dir] = input
+				}
+			}
+			gens = append(gens, gen)
+			trackers = append(trackers, tracker)
+		}
+		for i, gen := range gens {
+			if err := gen.Generate(schema); err != nil {
+				return fmt.Errorf("%s: %s generation: %w", input, trackers[i].Target, err)
+			}
+			if n, ok := gen.(generator.Noticer); ok && mem == nil {
+				for _, notice := range n.Notices() {
+					fmt.Fprintf(os.Stderr, "提示: %s\n", notice)
+				}
+			}
+		}
+		kept, err := generator.Prune(output, trackers, *pruneHandlers)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		for _, path := range kept {
+			fmt.Fprintf(os.Stderr, "警告: %s 不再使用 (对应的 API 已删除, 或 go 目标已改用服务接口), 文件保留; 确认不再需要后手动删除, 或使用 -prune-handlers\n", path)
+		}
 	}
-	if err := writeDocFile(cfg.TsDir, buf.Bytes()); err != nil {
-		return err
+	if mem != nil {
+		return checkOutput(mem)
 	}
+	fmt.Println("代码生成成功。")
 	return nil
 }
 
-func groupApis(apis []ast.Api) map[string][]ast.Api {
-	groups := make(map[string][]ast.Api)
-	for _, api := range apis {
-		module := "api"
-		if parts := strings.Split(api.Name, "."); len(parts) > 1 {
-			module = parts[0]
+// checkOutput 以统一差异格式输出内存中的生成结果与磁盘的不同; 存在差异时返回错误
+func checkOutput(mem *generator.Memory) error {
+	stale := 0
+	for _, name := range mem.Files() {
+		want, _ := mem.ReadFile(name)
+		have, err := os.ReadFile(name)
+		from := name
+		if errors.Is(err, fs.ErrNotExist) {
+			from = "/dev/null"
+		} else if err != nil {
+			return err
 		}
-		groups[module] = append(groups[module], api)
+		if d := diff.Unified(from, name, have, want); d != "" {
+			fmt.Print(d)
+			stale++
+		}
+	}
+	for _, name := range mem.Removed() {
+		have, err := os.ReadFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		fmt.Print(diff.Unified(name, "/dev/null", have, nil))
+		stale++
 	}
-	return groups
+	if stale > 0 {
+		return fmt.Errorf("%d 个生成文件与 Schema 不一致, 请重新生成", stale)
+	}
+	fmt.Println("生成结果已是最新。")
+	return nil
 }
 
-func createFuncMap(cfg generator.Config) template.FuncMap {
-	dummyCfg := generator.Config{TplFS: cfg.TplFS}
-	goGen := generator.NewGoGenerator(dummyCfg)
-	tsGen := generator.NewTsGenerator(dummyCfg)
+// loadProject 读取项目配置: 指定 -config 时必须存在, 否则查找当前目录下的 sb.yaml
+func loadProject(path string) (*project.Config, error) {
+	if path == "" {
+		found, err := project.Find(".")
+		if err != nil || found == "" {
+			return project.Default(), err
+		}
+		path = found
+	}
+	return project.Load(path)
+}
 
-	return template.FuncMap{
-		"SnakeCase":  util.SnakeCase,
-		"PascalCase": util.PascalCase,
-		"CamelCase":  util.CamelCase,
-		"GoValue":    goGen.FuncMap["GoValue"],
-		"TsValue":    tsGen.FuncMap["TsValue"],
+// splitList 逗号分隔的列表; 结果非 nil, 以便 -tag "" 能覆盖配置中的 tags
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
 
-func writeDocFile(baseDir string, data []byte) error {
-	path := filepath.Join(baseDir, "sb", "DOC.md")
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
+// parseSchema 读取 .sb 源文件; .json 文件按描述符加载
+func parseSchema(filename string, lang diag.Lang) (*ast.Schema, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
 	}
-	return os.WriteFile(path, data, 0644)
-}
\ No newline at end of file
+	if filepath.Ext(filename) == ".json" {
+		return descriptor.Load(content)
+	}
+
+	l := lexer.New(string(content))
+	p := parser.New(l)
+	p.File = filename
+	p.Lang = lang
+	return p.ParseSchema()
+}
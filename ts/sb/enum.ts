@@ -51,6 +51,13 @@ export enum SimPickPhone {
     Abcc = 4, 
 }
 
+// sim信息错误
+export enum SimError {
+    NotFound = 1, // 卡不存在
+    Expired = 2, // 已过期
+    Locked = 3, // 已锁定
+}
+
 // 运营商
 export enum SimOperator {
     Zz = 2, 
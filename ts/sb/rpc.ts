@@ -8,26 +8,130 @@ export enum RpcErrCode {
     RespErr = 500,
     NotAuth = 401,
     NotExist = 404,
+    TooLarge = 413, // 请求体超过服务端上限
+    BizErr = 422, // 业务错误, code 为 API 声明的错误枚举值
 }
 
+/**
+ * 结构化的 RPC 错误: 状态码、业务错误码、说明, 以及可选的详情 (Schema 中声明的结构体)。
+ * 非 200 响应的响应体依次为业务错误码 (i32)、说明 (text)、详情 (bin)。
+ * E 为 API 声明的错误枚举 (=> R ! E), 业务错误时可通过 error 字段 switch 各个枚举值。
+ */
+export class RpcError<E extends number = never> extends Error {
+    constructor(
+        public readonly status: RpcErrCode,
+        public readonly code: number = 0,
+        message: string = "",
+        private readonly _details: Uint8Array = new Uint8Array(0),
+    ) {
+        super(message || `rpc status ${status}`);
+        this.name = "RpcError";
+    }
+
+    /** 业务错误 (状态码 BizErr) 对应的错误枚举值, 其他错误为 null */
+    get error(): E | null {
+        return this.status === RpcErrCode.BizErr ? this.code as E : null;
+    }
+
+    public hasDetails = (): boolean => this._details.length > 0;
+
+    /** 以 Schema 中结构体的 get 函数解码详情, 例如 err.details(sb.getSimInfo) */
+    public details = <T>(getter: (buf: _.Buffer) => [T, Error | null]): [T | null, Error | null] => {
+        const [v, err] = getter(new _.Buffer(this._details));
+        return err === null ? [v, null] : [null, err];
+    };
+}
+
+/** 解析非 200 响应; 响应体为空或无法解析时 (如代理返回的错误) 只保留状态码 */
+const decodeError = (status: RpcErrCode, body: Uint8Array): RpcError => {
+    const buf = new _.Buffer(body);
+    const [code, e1] = _.getI32(buf);
+    const [message, e2] = _.getText(buf);
+    const [details, e3] = _.getBin(buf);
+    if (e1 !== null || e2 !== null || e3 !== null) return new RpcError(status);
+    return new RpcError(status, code, message, details);
+};
+
+const rpcError = (status: RpcErrCode, err: Error): RpcError => new RpcError(status, 0, err.message);
+
+/** 拦截器收到的调用信息 */
+export interface UnaryInfo {
+    api: string; // API 名称, 如 user.set_sim_info
+}
+
+/**
+ * 调用下一个拦截器, 最终发送请求。req 为 API 对应的 <Api>Request,
+ * 返回 [API 的结果 (无返回值的 API 为 null), 错误]
+ */
+export type UnaryHandler = (req: any) => Promise<[any, RpcError<any> | null]>;
+
+/**
+ * 一元拦截器, 可用于日志, 鉴权, 指标, 缓存等, 通过 RpcConfig.interceptors 或 RpcClient.use 指定。
+ * 可修改 req (结构须保持不变) 后调用 next, 也可不调用 next 直接返回结果或错误
+ */
+export type UnaryInterceptor = (info: UnaryInfo, req: any, next: UnaryHandler) => Promise<[any, RpcError<any> | null]>;
+
+/** user.get_abc 的参数, 为拦截器收到的 req */
+export interface UserGetAbcRequest {
+}
+
+/** user.get_abcd 的参数, 为拦截器收到的 req */
+export interface UserGetAbcdRequest {
+    page: number;
+    size: number;
+}
+
+/** user.set_sim_info 的参数, 为拦截器收到的 req */
+export interface UserSetSimInfoRequest {
+    info: _.SimInfo;
+}
+
+/** get_count 的参数, 为拦截器收到的 req */
+export interface GetCountRequest {
+    page: number;
+}
+
+/** get_bin 的参数, 为拦截器收到的 req */
+export interface GetBinRequest {
+    page: number;
+}
+
+/** 默认触发重试的状态码: 无法连接, 超时, 以及网关错误与服务暂不可用 (502, 503, 504) */
+export const DEFAULT_RETRY_CODES: number[] = [RpcErrCode.NoConn, RpcErrCode.Timeout, 502, 503, 504];
+
 export interface RpcConfig {
     host: string;
     headers?: Record<string, string>;
     timeout?: number;
-    retries?: number;
+    retries?: number; // 幂等 API (@idempotent) 失败后的最大重试次数, 其他 API 不自动重试
+    retryDelay?: number; // 首次重试前的等待 (毫秒), 之后每次翻倍, 默认 500
+    retryMaxDelay?: number; // 重试等待的上限 (毫秒), 默认 10000
+    retryCodes?: number[]; // 触发重试的状态码, 默认 DEFAULT_RETRY_CODES
+    interceptors?: UnaryInterceptor[]; // 依次包裹每次调用
 }
 
 export class RpcClient {
     private headers: Record<string, string> = {};
     private timeout: number;
     private retries: number;
+    private retryDelay: number;
+    private retryMaxDelay: number;
+    private retryCodes: number[];
+    private interceptors: UnaryInterceptor[];
 
     constructor(private config: RpcConfig) {
         if (config.headers) this.headers = { ...config.headers };
         this.timeout = config.timeout || 5000;
         this.retries = config.retries !== undefined ? config.retries : 3;
+        this.retryDelay = config.retryDelay || 500;
+        this.retryMaxDelay = config.retryMaxDelay || 10000;
+        this.retryCodes = config.retryCodes || DEFAULT_RETRY_CODES;
+        this.interceptors = [...(config.interceptors || [])];
     }
 
+    /** 追加拦截器, 位于已有拦截器之内 */
+    public use = (interceptor: UnaryInterceptor): void => { this.interceptors.push(interceptor); };
+
     public setHeader = (key: string, value: string): void => { this.headers[key] = value; };
     public getHeader = (key: string): string | undefined => this.headers[key];
     public removeHeader = (key: string): void => { delete this.headers[key]; };
@@ -37,93 +141,144 @@ export class RpcClient {
     public removeAuthorization = (): void => { this.removeHeader("Authorization"); };
     public isAuthorized = (): boolean => !!this.getAuthorization();
 
-    private async _fetch(path: string, body: Uint8Array): Promise<[Uint8Array | null, RpcErrCode]> {
-        let lastStatus = RpcErrCode.NoConn;
-        for (let i = 0; i <= this.retries; i++) {
-            if (i > 0) await new Promise(res => setTimeout(res, i * 1000));
-            const controller = new AbortController();
-            const timeoutId = setTimeout(() => controller.abort(), this.timeout);
-            try {
-                const res = await fetch(`${this.config.host}/${path}`, {
-                    method: "POST",
-                    headers: { "Content-Type": "application/octet-stream", ...this.headers },
-                    body: body as any,
-                    signal: controller.signal
-                });
-                if (res.ok) return [new Uint8Array(await res.arrayBuffer()), RpcErrCode.Ok];
-                lastStatus = res.status as RpcErrCode;
-                if (res.status === 408 && i < this.retries) continue;
-                return [null, res.status as RpcErrCode];
-            } catch (e: any) {
-                if (e.name === "AbortError") {
-                    lastStatus = RpcErrCode.Timeout;
-                } else {
-                    lastStatus = RpcErrCode.NoConn;
-                }
-                if (i < this.retries) continue;
-            } finally {
-                clearTimeout(timeoutId);
-            }
+    /** 依次经过拦截器后调用 call */
+    private _intercept(api: string, req: any, call: UnaryHandler): Promise<[any, RpcError<any> | null]> {
+        let next = call;
+        for (let i = this.interceptors.length - 1; i >= 0; i--) {
+            const interceptor = this.interceptors[i], inner = next;
+            next = (req) => interceptor({ api }, req, inner);
         }
-        return [null, lastStatus];
+        return next(req);
     }
 
-    /** 获取用户的id */
-    public userGetAbc = async (): Promise<[_.OrderStatus, RpcErrCode]> => {
-        const buf = new _.Buffer();
+    /** 发送请求; idempotent 为 true 时按重试策略重试可重试的错误 */
+    private async _fetch(path: string, body: Uint8Array, idempotent: boolean): Promise<[Uint8Array, RpcError | null]> {
+        const retries = idempotent ? this.retries : 0;
+        for (let i = 0; ; i++) {
+            if (i > 0) await new Promise(res => setTimeout(res, this._backoff(i)));
+            const [bytes, err] = await this._send(path, body);
+            if (err === null || i >= retries || !this.retryCodes.includes(err.status)) return [bytes, err];
+        }
+    }
+
+    private async _send(path: string, body: Uint8Array): Promise<[Uint8Array, RpcError | null]> {
+        const controller = new AbortController();
+        const timeoutId = setTimeout(() => controller.abort(), this.timeout);
+        try {
+            const res = await fetch(`${this.config.host}/${path}`, {
+                method: "POST",
+                headers: { "Content-Type": "application/octet-stream", ...this.headers },
+                body: body as any,
+                signal: controller.signal
+            });
+            const bytes = new Uint8Array(await res.arrayBuffer());
+            if (res.ok) return [bytes, null];
+            return [new Uint8Array(0), decodeError(res.status as RpcErrCode, bytes)];
+        } catch (e: any) {
+            if (e.name === "AbortError") return [new Uint8Array(0), new RpcError(RpcErrCode.Timeout, 0, "request timeout")];
+            return [new Uint8Array(0), rpcError(RpcErrCode.NoConn, e)];
+        } finally {
+            clearTimeout(timeoutId);
+        }
+    }
 
-        const [bytes, status] = await this._fetch("user.get_abc", buf.bytes);
-        if (status !== RpcErrCode.Ok || bytes === null) return [0 as _.OrderStatus, status];
+    /** 第 n 次重试前的等待 (毫秒): 指数增长, 并在 [d/2, d] 内随机抖动, 避免大量客户端同时重试 */
+    private _backoff(n: number): number {
+        const d = Math.min(this.retryDelay * 2 ** (n - 1), this.retryMaxDelay);
+        return d / 2 + Math.random() * d / 2;
+    }
+
+    /**
+     * 获取用户的id
+     * @deprecated 请使用 user.get_abcd
+     */
+    public userGetAbc = async (): Promise<[_.OrderStatus, RpcError | null]> => {
+        const req: UserGetAbcRequest = { };
+        const [result, callErr] = await this._intercept("user.get_abc", req, async (): Promise<[any, RpcError<any> | null]> => {
+            const buf = new _.Buffer();
 
-        const [result, err] = _.getU8(new _.Buffer(bytes));
-        if (err !== null) return [0 as _.OrderStatus, RpcErrCode.RespErr];
-        return [result as any, RpcErrCode.Ok];
+            const [bytes, fetchErr] = await this._fetch("user.get_abc", buf.bytes, false);
+            if (fetchErr !== null) return [null, fetchErr];
+
+            const [result, err] = _.getU8(new _.Buffer(bytes));
+            if (err !== null) return [null, rpcError(RpcErrCode.RespErr, err)];
+            return [result, null];
+        });
+        return callErr !== null ? [0 as _.OrderStatus, callErr] : [result, null];
     };
-    /** 获取abcd */
-    public userGetAbcd = async (page: number, size: number): Promise<[_.OrderStatus, RpcErrCode]> => {
-        const buf = new _.Buffer();
-        if (_.setAll(buf, _.u8(page), _.u8(size)) !== null) return [0 as _.OrderStatus, RpcErrCode.ReqErr];
+    /**
+     * 获取abcd
+     * 幂等 API, 失败时按 RpcConfig 的重试策略自动重试
+     */
+    public userGetAbcd = async (page: number, size: number): Promise<[_.OrderStatus, RpcError | null]> => {
+        const req: UserGetAbcdRequest = { page, size };
+        const [result, callErr] = await this._intercept("user.get_abcd", req, async (req: UserGetAbcdRequest): Promise<[any, RpcError<any> | null]> => {
+            const buf = new _.Buffer();
+            const setErr = _.setAll(buf, _.u8(req.page), _.u8(req.size));
+            if (setErr !== null) return [null, rpcError(RpcErrCode.ReqErr, setErr)];
 
-        const [bytes, status] = await this._fetch("user.get_abcd", buf.bytes);
-        if (status !== RpcErrCode.Ok || bytes === null) return [0 as _.OrderStatus, status];
+            const [bytes, fetchErr] = await this._fetch("user.get_abcd", buf.bytes, true);
+            if (fetchErr !== null) return [null, fetchErr];
 
-        const [result, err] = _.getU8(new _.Buffer(bytes));
-        if (err !== null) return [0 as _.OrderStatus, RpcErrCode.RespErr];
-        return [result as any, RpcErrCode.Ok];
+            const [result, err] = _.getU8(new _.Buffer(bytes));
+            if (err !== null) return [null, rpcError(RpcErrCode.RespErr, err)];
+            return [result, null];
+        });
+        return callErr !== null ? [0 as _.OrderStatus, callErr] : [result, null];
     };
     /** 设置sim信息 */
-    public userSetSimInfo = async (info: _.SimInfo): Promise<RpcErrCode> => {
-        const buf = new _.Buffer();
-        if (_.setAll(buf, info) !== null) return RpcErrCode.ReqErr;
-
-        const [bytes, status] = await this._fetch("user.set_sim_info", buf.bytes);
-        if (status !== RpcErrCode.Ok || bytes === null) return status;
+    public userSetSimInfo = async (info: _.SimInfo): Promise<RpcError<_.SimError> | null> => {
+        const req: UserSetSimInfoRequest = { info };
+        const [, callErr] = await this._intercept("user.set_sim_info", req, async (req: UserSetSimInfoRequest): Promise<[any, RpcError<any> | null]> => {
+            const buf = new _.Buffer();
+            const setErr = _.setAll(buf, req.info);
+            if (setErr !== null) return [null, rpcError(RpcErrCode.ReqErr, setErr)];
 
-        return RpcErrCode.Ok;
+            const [, fetchErr] = await this._fetch("user.set_sim_info", buf.bytes, false);
+            if (fetchErr !== null) return [null, fetchErr];
+            return [null, null];
+        });
+        return callErr;
     };
-    /** 获取数量 */
-    public getCount = async (page: number): Promise<[number, RpcErrCode]> => {
-        const buf = new _.Buffer();
-        if (_.setAll(buf, _.u8(page)) !== null) return [0, RpcErrCode.ReqErr];
+    /**
+     * 获取数量
+     * 幂等 API, 失败时按 RpcConfig 的重试策略自动重试
+     */
+    public getCount = async (page: number): Promise<[number, RpcError | null]> => {
+        const req: GetCountRequest = { page };
+        const [result, callErr] = await this._intercept("get_count", req, async (req: GetCountRequest): Promise<[any, RpcError<any> | null]> => {
+            const buf = new _.Buffer();
+            const setErr = _.setAll(buf, _.u8(req.page));
+            if (setErr !== null) return [null, rpcError(RpcErrCode.ReqErr, setErr)];
 
-        const [bytes, status] = await this._fetch("get_count", buf.bytes);
-        if (status !== RpcErrCode.Ok || bytes === null) return [0, status];
+            const [bytes, fetchErr] = await this._fetch("get_count", buf.bytes, true);
+            if (fetchErr !== null) return [null, fetchErr];
 
-        const [result, err] = _.getU8(new _.Buffer(bytes));
-        if (err !== null) return [0, RpcErrCode.RespErr];
-        return [result as any, RpcErrCode.Ok];
+            const [result, err] = _.getU8(new _.Buffer(bytes));
+            if (err !== null) return [null, rpcError(RpcErrCode.RespErr, err)];
+            return [result, null];
+        });
+        return callErr !== null ? [0, callErr] : [result, null];
     };
-    /** 获取bin */
-    public getBin = async (page: number): Promise<[Uint8Array, RpcErrCode]> => {
-        const buf = new _.Buffer();
-        if (_.setAll(buf, _.u8(page)) !== null) return [new Uint8Array(0), RpcErrCode.ReqErr];
+    /**
+     * 获取bin
+     * 幂等 API, 失败时按 RpcConfig 的重试策略自动重试
+     */
+    public getBin = async (page: number): Promise<[Uint8Array, RpcError | null]> => {
+        const req: GetBinRequest = { page };
+        const [result, callErr] = await this._intercept("get_bin", req, async (req: GetBinRequest): Promise<[any, RpcError<any> | null]> => {
+            const buf = new _.Buffer();
+            const setErr = _.setAll(buf, _.u8(req.page));
+            if (setErr !== null) return [null, rpcError(RpcErrCode.ReqErr, setErr)];
 
-        const [bytes, status] = await this._fetch("get_bin", buf.bytes);
-        if (status !== RpcErrCode.Ok || bytes === null) return [new Uint8Array(0), status];
+            const [bytes, fetchErr] = await this._fetch("get_bin", buf.bytes, true);
+            if (fetchErr !== null) return [null, fetchErr];
 
-        const [result, err] = _.getBin(new _.Buffer(bytes));
-        if (err !== null) return [new Uint8Array(0), RpcErrCode.RespErr];
-        return [result as any, RpcErrCode.Ok];
+            const [result, err] = _.getBin(new _.Buffer(bytes));
+            if (err !== null) return [null, rpcError(RpcErrCode.RespErr, err)];
+            return [result, null];
+        });
+        return callErr !== null ? [new Uint8Array(0), callErr] : [result, null];
     };
     
 }
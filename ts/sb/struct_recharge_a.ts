@@ -1,11 +1,7 @@
 import * as _ from "./_.ts"
 import * as Enum from "./enum"
 
-export interface RechargeA extends _.Serializable, _.Deserializable {
-    id: number;
-    type: Enum.OrderStatus[];
-    phone: string[];
-    si: _.SimInfo;
+export interface RechargeA extends _.Recharge, _.Serializable, _.Deserializable {
     aid: number;
 }
 
@@ -1,11 +1,7 @@
 import * as _ from "./_.ts"
 import * as Enum from "./enum"
 
-export interface RechargeB extends _.Serializable, _.Deserializable {
-    id: number;
-    type: Enum.OrderStatus[];
-    phone: string[];
-    si: _.SimInfo;
+export interface RechargeB extends _.Recharge, _.Serializable, _.Deserializable {
     bid: number;
 }
 